@@ -30,6 +30,9 @@ func NewPage(c *Config, path string, info os.FileInfo) (*Page, error) {
 		return nil, err
 	}
 	date, err := time.Parse("2006-01-02", d.Get("DATE"))
+	if t, ok := d.Date(); ok && err != nil {
+		date, err = t.Time, nil // e.g. #+DATE: [2023-05-01 Mon]
+	}
 	if err != nil {
 		date, _ = time.Parse("2006-01-02", "1970-01-01")
 	}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2"
@@ -20,7 +21,7 @@ import (
 var usage = `Usage: go-org COMMAND [ARGS]...
 Commands:
 - render [FILE] FORMAT
-  FORMAT: org, html, html-chroma
+  FORMAT: org, html, html-chroma, md, txt, ics
   Instead of specifying a file, org mode content can also be passed on stdin
 - blorg
   - blorg init
@@ -110,6 +111,12 @@ func render(args []string) {
 		write(org.NewOrgWriter())
 	case "html":
 		write(org.NewHTMLWriter())
+	case "md":
+		write(org.NewMarkdownWriter())
+	case "txt":
+		write(org.NewPlainTextWriter())
+	case "ics":
+		fmt.Fprint(os.Stdout, d.ICS())
 	case "html-chroma":
 		writer := org.NewHTMLWriter()
 		writer.HighlightCodeBlock = highlightCodeBlock
@@ -128,6 +135,9 @@ func highlightCodeBlock(source, lang string, inline bool, params map[string]stri
 	l = chroma.Coalesce(l)
 	it, _ := l.Tokenise(nil, source)
 	options := []html.Option{}
+	if start, err := strconv.Atoi(params[":number-lines"]); err == nil {
+		options = append(options, html.WithLineNumbers(true), html.BaseLineNumber(start))
+	}
 	if params[":hl_lines"] != "" {
 		ranges := org.ParseRanges(params[":hl_lines"])
 		if ranges != nil {
@@ -2,6 +2,7 @@ package org
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -9,8 +10,11 @@ import (
 type Block struct {
 	Name       string
 	Parameters []string
+	Switches   []string
 	Children   []Node
 	Result     Node
+
+	rawParameters string
 }
 
 type Result struct {
@@ -21,10 +25,19 @@ type Example struct {
 	Children []Node
 }
 
+// LatexBlock is a latex environment on lines of its own, e.g. \begin{equation} ... \end{equation}.
+type LatexBlock struct {
+	Environment string // Environment is the name of the environment, e.g. equation or align*.
+	Content     string // Content is the raw latex between \begin{Environment} and \end{Environment} - including e.g. arguments after \begin{...}.
+	Indent      string // Indent is the indentation of the environment beyond that of its parent (e.g. a list item). Lines of Content are relative to it.
+}
+
 var exampleLineRegexp = regexp.MustCompile(`^(\s*):(\s(.*)|\s*$)`)
 var beginBlockRegexp = regexp.MustCompile(`(?i)^(\s*)#\+BEGIN_(\w+)(.*)`)
 var endBlockRegexp = regexp.MustCompile(`(?i)^(\s*)#\+END_(\w+)`)
 var resultRegexp = regexp.MustCompile(`(?i)^(\s*)#\+RESULTS:`)
+var beginLatexBlockRegexp = regexp.MustCompile(`^(\s*)\\begin\{([a-zA-Z]+\*?)\}(.*)`)
+var endLatexBlockRegexp = regexp.MustCompile(`^\s*\\end\{([a-zA-Z]+\*?)\}\s*$`)
 var exampleBlockEscapeRegexp = regexp.MustCompile(`(^|\n)([ \t]*),([ \t]*)(\*|,\*|#\+|,#\+)`)
 
 func lexBlock(line string) (token, bool) {
@@ -36,6 +49,13 @@ func lexBlock(line string) (token, bool) {
 	return nilToken, false
 }
 
+func lexLatexBlock(line string) (token, bool) {
+	if m := beginLatexBlockRegexp.FindStringSubmatch(line); m != nil && !strings.Contains(m[3], `\end{`+m[2]+`}`) {
+		return token{"beginLatexBlock", len(m[1]), m[2], m}, true
+	}
+	return nilToken, false
+}
+
 func lexResult(line string) (token, bool) {
 	if m := resultRegexp.FindStringSubmatch(line); m != nil {
 		return token{"result", len(m[1]), "", m}, true
@@ -54,20 +74,19 @@ func isRawTextBlock(name string) bool { return name == "SRC" || name == "EXAMPLE
 
 func (d *Document) parseBlock(i int, parentStop stopFn) (int, Node) {
 	t, start := d.tokens[i], i
-	name, parameters := t.content, splitParameters(t.matches[3])
+	name, rawParameters := t.content, t.matches[3]
+	parameters, switches := splitParameters(rawParameters)
 	trim := trimIndentUpTo(d.tokens[i].lvl)
 	stop := func(d *Document, i int) bool {
 		return i >= len(d.tokens) || (d.tokens[i].kind == "endBlock" && d.tokens[i].content == name)
 	}
-	block, i := Block{name, parameters, nil, nil}, i+1
+	block, i := Block{name, parameters, switches, nil, nil, rawParameters}, i+1
 	if isRawTextBlock(name) {
 		rawText := ""
 		for ; !stop(d, i); i++ {
 			rawText += trim(d.tokens[i].matches[0]) + "\n"
 		}
-		if name == "EXAMPLE" || (name == "SRC" && len(parameters) >= 1 && parameters[0] == "org") {
-			rawText = exampleBlockEscapeRegexp.ReplaceAllString(rawText, "$1$2$3$4")
-		}
+		rawText = exampleBlockEscapeRegexp.ReplaceAllString(rawText, "$1$2$3$4") // e.g. ,* and ,#+END_SRC protect lines from being parsed as org
 		block.Children = d.parseRawInline(rawText)
 	} else {
 		consumed, nodes := d.parseMany(i, stop)
@@ -85,6 +104,32 @@ func (d *Document) parseBlock(i int, parentStop stopFn) (int, Node) {
 	return i + 1 - start, block
 }
 
+func (d *Document) parseLatexBlock(i int, parentStop stopFn) (int, Node) {
+	t, start := d.tokens[i], i
+	lvl := t.lvl
+	if d.baseLvl > lvl {
+		lvl = d.baseLvl // e.g. "- \begin{...}" is lexed with the indent of the bullet rather than the content of the list item
+	}
+	trim, lines, indent := trimIndentUpTo(lvl), []string{t.matches[3]}, t.matches[1]
+	if d.baseLvl < len(indent) {
+		indent = indent[d.baseLvl:]
+	} else {
+		indent = ""
+	}
+	for i++; i < len(d.tokens) && !parentStop(d, i); i++ {
+		if m := endLatexBlockRegexp.FindStringSubmatch(d.tokens[i].matches[0]); m != nil && m[1] == t.content {
+			return i + 1 - start, LatexBlock{t.content, strings.Join(lines, "\n"), indent}
+		}
+		lines = append(lines, trim(d.tokens[i].matches[0]))
+	}
+	return 0, nil
+}
+
+// Latex returns the latex environment including its \begin & \end lines.
+func (b LatexBlock) Latex() string {
+	return `\begin{` + b.Environment + `}` + b.Content + "\n" + `\end{` + b.Environment + `}`
+}
+
 func (d *Document) parseSrcBlockResult(i int, parentStop stopFn) (int, Node) {
 	start := i
 	for ; !parentStop(d, i) && d.tokens[i].kind == "text" && d.tokens[i].content == ""; i++ {
@@ -121,30 +166,158 @@ func trimIndentUpTo(max int) func(string) string {
 	}
 }
 
-func splitParameters(s string) []string {
-	parameters, parts := []string{}, strings.Split(s, " :")
-	lang, rest := strings.TrimSpace(parts[0]), parts[1:]
-	if lang != "" {
+// splitParameters splits the parameters of a block into the language, header arguments (:key value)
+// and switches (e.g. -n, +n 10, -r, -l "(ref:%s)"). Switches are recognized before, between and after header arguments -
+// the first word after a :key is always its value, e.g. for :cmdline -n.
+func splitParameters(s string) (parameters, switches []string) {
+	parameters, switches, parts := []string{}, []string{}, strings.Split(s, " :")
+	lang, langSwitches := extractSwitches(parts[0], false)
+	if lang = strings.TrimSpace(lang); lang != "" {
 		parameters = append(parameters, lang)
 	}
-	for _, p := range rest {
+	switches = append(switches, langSwitches...)
+	for _, p := range parts[1:] {
 		kv := strings.SplitN(p+" ", " ", 2)
-		parameters = append(parameters, ":"+kv[0], strings.TrimSpace(kv[1]))
+		value, valueSwitches := extractSwitches(kv[1], true)
+		parameters = append(parameters, ":"+kv[0], strings.TrimSpace(value))
+		switches = append(switches, valueSwitches...)
 	}
-	return parameters
+	return parameters, switches
+}
+
+func extractSwitches(s string, isValue bool) (string, []string) {
+	words, switches, fields, i := []string{}, []string{}, strings.Fields(s), 0
+	if isValue && len(fields) != 0 {
+		words, i = append(words, fields[0]), 1
+	}
+	for ; i < len(fields); i++ {
+		if isSwitch(fields[i]) {
+			if i+1 < len(fields) && isSwitchArgument(fields[i], fields[i+1]) {
+				switches = append(switches, fields[i]+" "+fields[i+1])
+				i++
+			} else {
+				switches = append(switches, fields[i])
+			}
+		} else {
+			words = append(words, fields[i])
+		}
+	}
+	if len(switches) == 0 {
+		return s, nil
+	}
+	return strings.Join(words, " "), switches
+}
+
+func isSwitch(s string) bool {
+	return len(s) == 2 && (s[0] == '-' && strings.ContainsRune("nrilk", rune(s[1])) || s == "+n")
+}
+
+func isSwitchArgument(sw, s string) bool {
+	switch sw[1:] {
+	case "n":
+		_, err := strconv.Atoi(s)
+		return err == nil
+	case "l":
+		return strings.HasPrefix(s, `"`)
+	}
+	return false
+}
+
+// parameterLine returns the original parameters of the block (e.g. to preserve the order of switches and header arguments)
+// if they still match the parsed Parameters and Switches.
+func (b Block) parameterLine() (string, bool) {
+	if b.rawParameters == "" {
+		return "", false
+	}
+	parameters, switches := splitParameters(b.rawParameters)
+	if strings.Join(parameters, "\x00") != strings.Join(b.Parameters, "\x00") || strings.Join(switches, "\x00") != strings.Join(b.Switches, "\x00") {
+		return "", false
+	}
+	return strings.TrimSpace(b.rawParameters), true
+}
+
+// LineNumbers returns the first line number of the block if line numbering was requested via the -n / +n switches.
+// For +n (continued numbering) the returned offset has to be added to the last line number of the previous numbered block.
+func (b Block) LineNumbers() (start int, continued, ok bool) {
+	for _, s := range b.Switches {
+		fields := strings.Fields(s)
+		if fields[0] != "-n" && fields[0] != "+n" {
+			continue
+		}
+		start, continued = 1, fields[0] == "+n"
+		if len(fields) == 2 {
+			start, _ = strconv.Atoi(fields[1])
+		}
+		return start, continued, true
+	}
+	return 0, false, false
 }
 
 func (b Block) ParameterMap() map[string]string {
 	if len(b.Parameters) == 0 {
 		return nil
 	}
-	m := map[string]string{":lang": b.Parameters[0]}
-	for i := 1; i+1 < len(b.Parameters); i += 2 {
+	m, i := map[string]string{}, 0
+	if lang := b.Parameters[0]; !strings.HasPrefix(lang, ":") {
+		m[":lang"], i = lang, 1
+	}
+	for ; i+1 < len(b.Parameters); i += 2 {
 		m[b.Parameters[i]] = b.Parameters[i+1]
 	}
 	return m
 }
 
-func (n Example) String() string { return orgWriter.WriteNodesAsString(n) }
-func (n Block) String() string   { return orgWriter.WriteNodesAsString(n) }
-func (n Result) String() string  { return orgWriter.WriteNodesAsString(n) }
+// Variable is a :var header argument of a src block. Value is not resolved - it is the raw value as written,
+// e.g. a literal ("foo", 42), a reference to a named table or block (data, data[1:2,0]) or a call (block(x=1)).
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Variables returns the :var header arguments of the block in order. Repeated :var arguments and comma separated
+// assignments (:var x=1, y=2) are both supported - commas inside quotes, parentheses and brackets do not separate assignments.
+func (b Block) Variables() []Variable {
+	variables, i := []Variable{}, 0
+	if len(b.Parameters) != 0 && !strings.HasPrefix(b.Parameters[0], ":") {
+		i = 1
+	}
+	for ; i+1 < len(b.Parameters); i += 2 {
+		if b.Parameters[i] != ":var" {
+			continue
+		}
+		for _, assignment := range splitVariableAssignments(b.Parameters[i+1]) {
+			kv := strings.SplitN(assignment, "=", 2)
+			if name := strings.TrimSpace(kv[0]); name != "" {
+				v := Variable{Name: name}
+				if len(kv) == 2 {
+					v.Value = strings.TrimSpace(kv[1])
+				}
+				variables = append(variables, v)
+			}
+		}
+	}
+	return variables
+}
+
+func splitVariableAssignments(s string) []string {
+	assignments, depth, quoted, start := []string{}, 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && (i == 0 || s[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			assignments, start = append(assignments, s[start:i]), i+1
+		}
+	}
+	return append(assignments, s[start:])
+}
+
+func (n LatexBlock) String() string { return orgWriter.WriteNodesAsString(n) }
+func (n Example) String() string    { return orgWriter.WriteNodesAsString(n) }
+func (n Block) String() string      { return orgWriter.WriteNodesAsString(n) }
+func (n Result) String() string     { return orgWriter.WriteNodesAsString(n) }
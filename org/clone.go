@@ -0,0 +1,243 @@
+package org
+
+// Clone returns a deep copy of d that can be modified without affecting d: nodes, settings, footnotes,
+// the outline and the index are copied (pointers between them, e.g. FootnoteLink.Definition or
+// IndexEntry.Section, point into the copy). The Configuration is shared.
+// Custom nodes (e.g. from InlineParsers) are not known to go-org and are copied as is.
+func (d *Document) Clone() *Document {
+	c := &cloner{map[*FootnoteDefinition]*FootnoteDefinition{}, map[*Section]*Section{}}
+	clone := &Document{
+		Configuration:  d.Configuration,
+		Path:           d.Path,
+		lines:          d.lines,
+		inlineParsers:  append([]InlineParser(nil), d.inlineParsers...),
+		baseLvl:        d.baseLvl,
+		Macros:         cloneStringMap(d.Macros),
+		Links:          cloneStringMap(d.Links),
+		Nodes:          c.nodes(d.Nodes),
+		RadioTargets:   cloneStrings(d.RadioTargets),
+		Targets:        cloneStrings(d.Targets),
+		Abbreviations:  cloneStringMap(d.Abbreviations),
+		keywordCases:   cloneStringMap(d.keywordCases),
+		BufferSettings: cloneStringMap(d.BufferSettings),
+		Events:         append([]ParseEvent(nil), d.Events...),
+		Errors:         append([]ParseError(nil), d.Errors...),
+		Error:          d.Error,
+	}
+	if d.NamedNodes != nil {
+		clone.NamedNodes = make(map[string]Node, len(d.NamedNodes))
+		for name, n := range d.NamedNodes {
+			clone.NamedNodes[name] = c.node(n)
+		}
+	}
+	if d.Footnotes != nil {
+		clone.Footnotes = &Footnotes{
+			references:      make([]FootnoteLink, len(d.Footnotes.references)),
+			definitionNames: cloneStrings(d.Footnotes.definitionNames),
+		}
+		if d.Footnotes.Definitions != nil {
+			clone.Footnotes.Definitions = make(map[string]*FootnoteDefinition, len(d.Footnotes.Definitions))
+			for name, definition := range d.Footnotes.Definitions {
+				clone.Footnotes.Definitions[name] = c.definition(definition)
+			}
+		}
+		for i, l := range d.Footnotes.references {
+			clone.Footnotes.references[i] = c.node(l).(FootnoteLink)
+		}
+	}
+	clone.Outline = Outline{c.section(d.Outline.Section, nil), c.sections[d.Outline.last], d.Outline.count}
+	clone.indexOutline()
+	if d.Index != nil {
+		clone.Index = make([]IndexEntry, len(d.Index))
+		for i, e := range d.Index {
+			clone.Index[i] = IndexEntry{e.Term, e.ID, c.sections[e.Section]}
+		}
+	}
+	return clone
+}
+
+// cloner keeps track of the already copied pointers so that shared pointers stay shared in the copy.
+type cloner struct {
+	definitions map[*FootnoteDefinition]*FootnoteDefinition
+	sections    map[*Section]*Section
+}
+
+func (c *cloner) nodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	clone := make([]Node, len(nodes))
+	for i, n := range nodes {
+		clone[i] = c.node(n)
+	}
+	return clone
+}
+
+func (c *cloner) node(n Node) Node {
+	switch n := n.(type) {
+	case Keyword, Comment, HorizontalRule, Text, StatisticToken, ExplicitLineBreak, LineBreak, RadioTarget, Target, Entity, InlineBabelCall, LatexBlock, nil:
+		return n
+	case Include:
+		n.Children = c.nodes(n.Children) // Resolve reads the included file on every call
+		return n
+	case NodeWithMeta:
+		return NodeWithMeta{c.node(n.Node), Metadata{c.captions(n.Meta.Caption), cloneStringSlices(n.Meta.HTMLAttributes)}}
+	case NodeWithName:
+		return NodeWithName{n.Name, c.node(n.Node)}
+	case Headline:
+		return c.headline(n)
+	case Block:
+		n.Parameters, n.Switches, n.Children, n.Result = cloneStrings(n.Parameters), cloneStrings(n.Switches), c.nodes(n.Children), c.node(n.Result)
+		return n
+	case Result:
+		return Result{c.node(n.Node)}
+	case InlineBlock:
+		return InlineBlock{n.Name, cloneStrings(n.Parameters), c.nodes(n.Children)}
+	case Example:
+		return Example{c.nodes(n.Children)}
+	case Drawer:
+		return Drawer{n.Name, c.nodes(n.Children)}
+	case PropertyDrawer:
+		return PropertyDrawer{cloneStringSlices(n.Properties), cloneStringMap(n.separators)}
+	case List:
+		return List{n.Kind, c.nodes(n.Items)}
+	case ListItem:
+		n.Children = c.nodes(n.Children)
+		return n
+	case DescriptiveListItem:
+		n.Term, n.Details = c.nodes(n.Term), c.nodes(n.Details)
+		return n
+	case Table:
+		return c.table(n)
+	case Paragraph:
+		return Paragraph{c.nodes(n.Children)}
+	case Emphasis:
+		return Emphasis{n.Kind, c.nodes(n.Content)}
+	case LatexFragment:
+		return LatexFragment{n.OpeningPair, n.ClosingPair, c.nodes(n.Content)}
+	case RegularLink:
+		n.Description = c.nodes(n.Description)
+		return n
+	case Macro:
+		return Macro{n.Name, cloneStrings(n.Parameters)}
+	case Timestamp:
+		return cloneTimestamp(n)
+	case FootnoteLink:
+		return FootnoteLink{n.Name, c.definition(n.Definition)}
+	case FootnoteDefinition:
+		return FootnoteDefinition{n.Name, c.nodes(n.Children), n.Inline}
+	default:
+		return n
+	}
+}
+
+func (c *cloner) headline(h Headline) Headline {
+	if h.Properties != nil {
+		properties := c.node(*h.Properties).(PropertyDrawer)
+		h.Properties = &properties
+	}
+	for _, t := range []**Timestamp{&h.Scheduled, &h.Deadline, &h.Closed} {
+		if *t != nil {
+			timestamp := cloneTimestamp(**t)
+			*t = &timestamp
+		}
+	}
+	h.Title, h.Tags, h.Children = c.nodes(h.Title), cloneStrings(h.Tags), c.nodes(h.Children)
+	return h
+}
+
+func (c *cloner) table(t Table) Table {
+	clone := Table{nil, append([]ColumnInfo(nil), t.ColumnInfos...), append([]int(nil), t.SeparatorIndices...)}
+	for _, row := range t.Rows {
+		columns := []Column(nil)
+		for i, column := range row.Columns {
+			column.Children = c.nodes(column.Children)
+			if i < len(clone.ColumnInfos) && column.ColumnInfo == &t.ColumnInfos[i] {
+				column.ColumnInfo = &clone.ColumnInfos[i]
+			} else if column.ColumnInfo != nil {
+				info := *column.ColumnInfo
+				column.ColumnInfo = &info
+			}
+			columns = append(columns, column)
+		}
+		clone.Rows = append(clone.Rows, Row{columns, row.IsSpecial})
+	}
+	return clone
+}
+
+func (c *cloner) definition(f *FootnoteDefinition) *FootnoteDefinition {
+	if f == nil {
+		return nil
+	}
+	if clone, ok := c.definitions[f]; ok {
+		return clone
+	}
+	clone := &FootnoteDefinition{}
+	c.definitions[f] = clone
+	*clone = c.node(*f).(FootnoteDefinition)
+	return clone
+}
+
+func (c *cloner) section(s *Section, parent *Section) *Section {
+	if s == nil {
+		return nil
+	}
+	clone := &Section{Parent: parent}
+	c.sections[s] = clone
+	if s.Headline != nil {
+		h := c.headline(*s.Headline)
+		clone.Headline = &h
+	}
+	for _, child := range s.Children {
+		clone.Children = append(clone.Children, c.section(child, clone))
+	}
+	return clone
+}
+
+func (c *cloner) captions(captions [][]Node) [][]Node {
+	if captions == nil {
+		return nil
+	}
+	clone := make([][]Node, len(captions))
+	for i, caption := range captions {
+		clone[i] = c.nodes(caption)
+	}
+	return clone
+}
+
+func cloneTimestamp(t Timestamp) Timestamp {
+	if t.End != nil {
+		end := *t.End
+		t.End = &end
+	}
+	return t
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneStringSlices(s [][]string) [][]string {
+	if s == nil {
+		return nil
+	}
+	clone := make([][]string, len(s))
+	for i := range s {
+		clone[i] = cloneStrings(s[i])
+	}
+	return clone
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
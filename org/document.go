@@ -4,12 +4,13 @@
 // Further export formats can be defined using the Writer interface.
 //
 // You probably want to start with something like this:
-//   input := strings.NewReader("Your Org mode input")
-//   html, err := org.New().Parse(input, "./").Write(org.NewHTMLWriter())
-//   if err != nil {
-//       log.Fatalf("Something went wrong: %s", err)
-//   }
-//   log.Print(html)
+//
+//	input := strings.NewReader("Your Org mode input")
+//	html, err := org.New().Parse(input, "./").Write(org.NewHTMLWriter())
+//	if err != nil {
+//	    log.Fatalf("Something went wrong: %s", err)
+//	}
+//	log.Print(html)
 package org
 
 import (
@@ -19,7 +20,10 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
 )
 
 type Configuration struct {
@@ -28,6 +32,49 @@ type Configuration struct {
 	DefaultSettings     map[string]string                     // Default values for settings that are overriden by setting the same key in BufferSettings.
 	Log                 *log.Logger                           // Log is used to print warnings during parsing.
 	ReadFile            func(filename string) ([]byte, error) // ReadFile is used to read e.g. #+INCLUDE files.
+	InlineParsers       []InlineParser                        // InlineParsers extend the inline syntax (e.g. @mentions or #hashtags).
+	UnknownKeywords     string                                // UnknownKeywords is the policy for unknown #+KEYWORDs: keep (default), drop (omit from the AST) or error (log them).
+	Translations        map[string]map[string]string          // Translations of fixed export strings by #+LANGUAGE - take precedence over DefaultTranslations.
+	Trace               bool                                  // Trace records the decisions of the parser in Document.Events - for debugging documents that parse oddly.
+	StripCharacters     string                                // StripCharacters are removed from the input before parsing, e.g. InvisibleCharacters. Disabled (i.e. preserved) if empty.
+	CollectErrors       bool                                  // CollectErrors records problems like unterminated blocks in Document.Errors - parsing stays lenient either way.
+	EmphasisMarkers     string                                // EmphasisMarkers are the characters parsed as emphasis (see DefaultEmphasisMarkers). Content of = and ~ is verbatim, other (custom) markers parse their content like *.
+}
+
+// DefaultEmphasisMarkers are the EmphasisMarkers of New: *bold*, /italic/, _underline_, +strike-through+, =verbatim= and ~code~.
+const DefaultEmphasisMarkers = "*/_+=~"
+
+// InvisibleCharacters are the soft hyphen, zero width (non-)joiner & space, word joiner and byte order mark - see Configuration.StripCharacters.
+const InvisibleCharacters = "\u00ad\u200b\u200c\u200d\u2060\ufeff"
+
+// ParseEvent records which parser consumed which lines of the input (see Configuration.Trace).
+// Events are in document order, nested events (e.g. the items of a list) follow their parent event.
+type ParseEvent struct {
+	Line     int    // Line is the 1-based line number of the first consumed line - lines spliced in via #+INCLUDE are counted as well.
+	Lines    int    // Lines is the number of consumed lines.
+	Token    string // Token is the kind of line the parser was chosen for, e.g. headline, unorderedList or keyword.
+	Node     string // Node is the type of the parsed node, e.g. Headline or List - empty for lines that produce no node.
+	Depth    int    // Depth is the nesting depth of the event - 0 for top level nodes.
+	Fallback bool   // Fallback is set if the line could not be parsed as Token and was parsed as plain text instead.
+}
+
+// ParseError is a problem found during parsing (see Configuration.CollectErrors), e.g. a block without #+END_*.
+type ParseError struct {
+	Line    int // Line is the 1-based line number - lines spliced in via #+INCLUDE are counted as well (see ParseEvent.Line).
+	Column  int // Column is the 1-based byte offset of the problem in the line.
+	Message string
+}
+
+func (e ParseError) Error() string { return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message) }
+
+// InlineParser parses custom inline syntax into a custom Node.
+// Parsers with a positive Priority are tried before the built-in syntax (and can thus shadow it),
+// all others are only tried where no built-in syntax matched. Parsers with a higher Priority are tried first.
+// Writers render custom nodes via their NodeRenderHook - the OrgWriter falls back to Node.String().
+type InlineParser struct {
+	Chars    string                                                  // Chars contains the characters the custom syntax can start with.
+	Priority int                                                     // Priority determines the order in which parsers are tried.
+	Parse    func(input string, start int) (consumed int, node Node) // Parse returns the number of consumed bytes (0 for no match) and the parsed node.
 }
 
 // Document contains the parsing results and a pointer to the Configuration.
@@ -35,13 +82,29 @@ type Document struct {
 	*Configuration
 	Path           string // Path of the file containing the parse input - used to resolve relative paths during parsing (e.g. INCLUDE).
 	tokens         []token
+	lines          []string          // lines are the input lines - unlike tokens they are not rewritten during parsing (see Validate).
+	ownSettings    map[string]string // ownSettings contains the settings of the buffer's own keywords - they win over setupSettings.
+	setupSettings  map[string]string // setupSettings contains the settings imported from setup files (see loadSetupFile).
+	keywordCases   map[string]string // keywordCases maps the keys of custom keywords to the case of their first occurrence (see OrgWriter.PreserveKeywordCase).
+	inlineParsers  []InlineParser
 	baseLvl        int
 	Macros         map[string]string
 	Links          map[string]string
 	Nodes          []Node
 	NamedNodes     map[string]Node
+	Footnotes      *Footnotes
+	RadioTargets   []string          // RadioTargets contains the names of all <<<radio targets>>> - matching text is linked to them.
+	Targets        []string          // Targets contains the names of all <<targets>> - [[name]] links to them.
+	Index          []IndexEntry      // Index contains all #+INDEX: entries in document order.
+	Abbreviations  map[string]string // Abbreviations maps the abbreviations defined via #+ABBREV: (e.g. WHO) to their expansion.
 	Outline        Outline           // Outline is a Table Of Contents for the document and contains all sections (headline + content).
 	BufferSettings map[string]string // Settings contains all settings that were parsed from keywords.
+	Events         []ParseEvent      // Events contains the decisions of the parser if Configuration.Trace is set.
+	Errors         []ParseError      // Errors contains the problems found during parsing if Configuration.CollectErrors is set.
+	traceDepth     int
+	line           int           // line is the index of the token currently being parsed - used for the position of ParseErrors.
+	outline        *outlineIndex // outline contains lookups of the headlines in the Outline - built after parsing.
+	setupFiles     []string      // setupFiles contains the files whose #+SETUPFILE chain led to this document - used to detect cycles.
 	Error          error
 }
 
@@ -65,6 +128,7 @@ var lexFns = []lexFn{
 	lexHeadline,
 	lexDrawer,
 	lexBlock,
+	lexLatexBlock,
 	lexResult,
 	lexList,
 	lexTable,
@@ -83,10 +147,12 @@ func New() *Configuration {
 	return &Configuration{
 		AutoLink:            true,
 		MaxEmphasisNewLines: 1,
+		EmphasisMarkers:     DefaultEmphasisMarkers,
 		DefaultSettings: map[string]string{
 			"TODO":         "TODO | DONE",
 			"EXCLUDE_TAGS": "noexport",
-			"OPTIONS":      "toc:t <:t e:t f:t pri:t todo:t tags:t title:t ealb:nil",
+			"SELECT_TAGS":  "export",
+			"OPTIONS":      "toc:t <:t date:t e:t f:t pri:t todo:t tags:t title:t ealb:nil rtl:nil ^:{} H:nil num:nil ':nil p:nil",
 		},
 		Log:      log.New(os.Stderr, "go-org: ", 0),
 		ReadFile: ioutil.ReadFile,
@@ -114,18 +180,87 @@ func (d *Document) Write(w Writer) (out string, err error) {
 	return w.String(), err
 }
 
+// streamWriter is implemented by writers embedding a strings.Builder - i.e. all writers of this package.
+type streamWriter interface {
+	Writer
+	Len() int
+	Reset()
+	WriteString(string) (int, error)
+}
+
+// Stream is like Write but flushes the output to out after each top level node rather than buffering the whole document.
+// Writers that do not embed a strings.Builder are buffered and flushed once. Errors of out are returned rather than ignored.
+func (d *Document) Stream(out io.Writer, w Writer) (n int64, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("could not write output: %s", recovered)
+		}
+	}()
+	if d.Error != nil {
+		return 0, d.Error
+	} else if d.Nodes == nil {
+		return 0, fmt.Errorf("could not write output: parse was not called")
+	}
+	sw, ok := w.(streamWriter)
+	if !ok {
+		s, err := d.Write(w)
+		if err != nil {
+			return 0, err
+		}
+		m, err := io.WriteString(out, s)
+		return int64(m), err
+	}
+	// the last bytes of the output are kept - writers check them to separate blocks (e.g. MarkdownWriter.startBlock).
+	start := sw.Len()
+	flush := func() error {
+		s := sw.String()[start:]
+		m, err := io.WriteString(out, s)
+		n += int64(m)
+		if err != nil {
+			return err
+		}
+		tail := sw.String()
+		if len(tail) > 2 {
+			tail = tail[len(tail)-2:]
+		}
+		sw.Reset()
+		sw.WriteString(tail)
+		start = len(tail)
+		return nil
+	}
+	w.Before(d)
+	for _, node := range d.Nodes {
+		WriteNodes(w, node)
+		if err := flush(); err != nil {
+			return n, err
+		}
+	}
+	w.After(d)
+	return n, flush()
+}
+
 // Parse parses the input into an AST (and some other helpful fields like Outline).
 // To allow method chaining, errors are stored in document.Error rather than being returned.
 func (c *Configuration) Parse(input io.Reader, path string) (d *Document) {
+	return c.parse(input, path, nil)
+}
+
+func (c *Configuration) parse(input io.Reader, path string, setupFiles []string) (d *Document) {
 	outlineSection := &Section{}
 	d = &Document{
 		Configuration:  c,
 		Outline:        Outline{outlineSection, outlineSection, 0},
 		BufferSettings: map[string]string{},
 		NamedNodes:     map[string]Node{},
+		Footnotes:      &Footnotes{Definitions: map[string]*FootnoteDefinition{}},
 		Links:          map[string]string{},
 		Macros:         map[string]string{},
+		Abbreviations:  map[string]string{},
+		ownSettings:    map[string]string{},
+		setupSettings:  map[string]string{},
+		keywordCases:   map[string]string{},
 		Path:           path,
+		setupFiles:     setupFiles,
 	}
 	defer func() {
 		if recovered := recover(); recovered != nil {
@@ -135,9 +270,13 @@ func (c *Configuration) Parse(input io.Reader, path string) (d *Document) {
 	if d.tokens != nil {
 		d.Error = fmt.Errorf("parse was called multiple times")
 	}
+	d.inlineParsers = append([]InlineParser{}, c.InlineParsers...)
+	sort.SliceStable(d.inlineParsers, func(i, j int) bool { return d.inlineParsers[i].Priority > d.inlineParsers[j].Priority })
 	d.tokenize(input)
 	_, nodes := d.parseMany(0, func(d *Document, i int) bool { return i >= len(d.tokens) })
+	sort.SliceStable(d.Errors, func(i, j int) bool { return d.Errors[i].Line < d.Errors[j].Line })
 	d.Nodes = nodes
+	d.indexOutline()
 	return d
 }
 
@@ -147,19 +286,142 @@ func (c *Configuration) Silent() *Configuration {
 	return c
 }
 
+// Normalize merges adjacent Text nodes (e.g. as emitted by custom InlineParsers) into a single Text node.
+// Text is never merged across other nodes (e.g. emphasis or links). Headlines in the Outline are updated accordingly.
+// To allow method chaining, the document is returned.
+func (d *Document) Normalize() *Document {
+	d.Nodes = normalizeNodes(d.Nodes)
+	d.updateOutline()
+	return d
+}
+
+// updateOutline points the sections of the outline to the current version of their headlines (e.g. after Normalize).
+func (d *Document) updateOutline() {
+	headlines := map[int]Headline{}
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Headline); ok {
+			headlines[h.Index] = h
+		}
+	})
+	var updateSections func(*Section)
+	updateSections = func(s *Section) {
+		if s.Headline != nil {
+			if h, ok := headlines[s.Headline.Index]; ok {
+				*s.Headline = h
+			}
+		}
+		for _, c := range s.Children {
+			updateSections(c)
+		}
+	}
+	updateSections(d.Outline.Section)
+	d.indexOutline()
+}
+
+func normalizeNodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	normalized := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case Text:
+			if last := len(normalized) - 1; last >= 0 {
+				if t, ok := normalized[last].(Text); ok && t.IsRaw == n.IsRaw {
+					normalized[last] = Text{t.Content + n.Content, n.IsRaw}
+					continue
+				}
+			}
+		case Headline:
+			n.Title, n.Children = normalizeNodes(n.Title), normalizeNodes(n.Children)
+			node = n
+		case Block:
+			n.Children = normalizeNodes(n.Children)
+			if n.Result != nil {
+				n.Result = normalizeNodes([]Node{n.Result})[0]
+			}
+			node = n
+		case Result:
+			n.Node = normalizeNodes([]Node{n.Node})[0]
+			node = n
+		case Drawer:
+			n.Children = normalizeNodes(n.Children)
+			node = n
+		case Include:
+			n.Children = normalizeNodes(n.Children)
+			node = n
+		case List:
+			n.Items = normalizeNodes(n.Items)
+			node = n
+		case ListItem:
+			n.Children = normalizeNodes(n.Children)
+			node = n
+		case DescriptiveListItem:
+			n.Term, n.Details = normalizeNodes(n.Term), normalizeNodes(n.Details)
+			node = n
+		case Table:
+			rows := make([]Row, len(n.Rows))
+			for i, row := range n.Rows {
+				rows[i] = Row{make([]Column, len(row.Columns)), row.IsSpecial}
+				for j, column := range row.Columns {
+					rows[i].Columns[j] = Column{normalizeNodes(column.Children), column.ColumnInfo}
+				}
+			}
+			n.Rows = rows
+			node = n
+		case Paragraph:
+			n.Children = normalizeNodes(n.Children)
+			node = n
+		case Emphasis:
+			n.Content = normalizeNodes(n.Content)
+			node = n
+		case NodeWithMeta:
+			n.Node = normalizeNodes([]Node{n.Node})[0]
+			node = n
+		case NodeWithName:
+			n.Node = normalizeNodes([]Node{n.Node})[0]
+			node = n
+		case FootnoteDefinition:
+			n.Children = normalizeNodes(n.Children)
+			node = n
+		case RegularLink:
+			n.Description = normalizeNodes(n.Description)
+			node = n
+		}
+		normalized = append(normalized, node)
+	}
+	return normalized
+}
+
 func (d *Document) tokenize(input io.Reader) {
 	d.tokens = []token{}
 	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
-		d.tokens = append(d.tokens, tokenize(scanner.Text()))
+		line := d.stripCharacters(scanner.Text())
+		d.lines, d.tokens = append(d.lines, line), append(d.tokens, tokenize(line))
 	}
 	if err := scanner.Err(); err != nil {
 		d.Error = fmt.Errorf("could not tokenize input: %s", err)
 	}
 }
 
-// Get returns the value for key in BufferSettings or DefaultSettings if key does not exist in the former
+// stripCharacters removes the StripCharacters from line.
+func (d *Document) stripCharacters(line string) string {
+	if d.StripCharacters == "" || !strings.ContainsAny(line, d.StripCharacters) {
+		return line
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(d.StripCharacters, r) {
+			return -1
+		}
+		return r
+	}, line)
+}
+
+// Get returns the value for key in BufferSettings or DefaultSettings if key does not exist in the former.
+// Keys are matched case-insensitively, e.g. Get("title") returns the value of #+TITLE:.
 func (d *Document) Get(key string) string {
+	key = strings.ToUpper(key)
 	if v, ok := d.BufferSettings[key]; ok {
 		return v
 	}
@@ -169,17 +431,74 @@ func (d *Document) Get(key string) string {
 	return ""
 }
 
+// Date returns the value of #+DATE: as a timestamp, e.g. for #+DATE: [2023-05-01] - ok is false for free text dates.
+func (d *Document) Date() (t Timestamp, ok bool) {
+	value := strings.TrimSpace(d.Get("DATE"))
+	if value == "" {
+		return Timestamp{}, false
+	}
+	consumed, node := d.parseTimestamp(value, 0)
+	t, ok = node.(Timestamp)
+	return t, ok && consumed == len(value)
+}
+
+// Keywords returns the values of all keywords in BufferSettings.
+// Keywords that occur multiple times (e.g. #+AUTHOR or #+HTML_HEAD) have multiple values in order of appearance.
+func (d *Document) Keywords() map[string][]string {
+	keywords := make(map[string][]string, len(d.BufferSettings))
+	for k, v := range d.BufferSettings {
+		keywords[k] = strings.Split(v, "\n")
+	}
+	return keywords
+}
+
+// FrontMatter returns the keywords of d by lowercase name, e.g. for the front matter of static site generators.
+// Keywords with multiple values are []string, all others string - except for date, which is a time.Time if #+DATE: is
+// a timestamp or a YYYY-MM-DD date, and tags, which contains the #+FILETAGS: (e.g. :a:b:) as a []string.
+func (d *Document) FrontMatter() map[string]interface{} {
+	frontMatter := map[string]interface{}{}
+	for k, vs := range d.Keywords() {
+		if len(vs) == 1 {
+			frontMatter[strings.ToLower(k)] = vs[0]
+		} else {
+			frontMatter[strings.ToLower(k)] = vs
+		}
+	}
+	if t, ok := d.Date(); ok {
+		frontMatter["date"] = t.Time
+	} else if t, err := time.Parse("2006-01-02", strings.TrimSpace(d.Get("DATE"))); err == nil {
+		frontMatter["date"] = t
+	}
+	if _, ok := d.BufferSettings["FILETAGS"]; ok {
+		delete(frontMatter, "filetags")
+		frontMatter["tags"] = d.fileTags()
+	}
+	return frontMatter
+}
+
+// fileTags returns the tags of #+FILETAGS: (e.g. :a:b:).
+func (d *Document) fileTags() []string {
+	return strings.FieldsFunc(d.BufferSettings["FILETAGS"], func(r rune) bool { return r == ':' || unicode.IsSpace(r) })
+}
+
 // GetOption returns the value associated to the export option key
 // Currently supported options:
-// - < (export timestamps)
+// - ' (replace straight quotes with curly ones in html - see HTMLWriter.PrettifyText)
+// - < (export timestamps. active / inactive only exports <active> / [inactive] timestamps)
+// - date (export #+DATE: - see HTMLWriter.TimestampFormat)
 // - e (export org entities)
 // - f (export footnotes)
 // - title (export title)
 // - toc (export table of content. an int limits the included org headline lvl)
 // - todo (export headline todo status)
+// - p (export the planning line of headlines - SCHEDULED, DEADLINE & CLOSED - html only. defaults to nil)
 // - pri (export headline priority)
 // - tags (export headline tags)
+// - H (export headlines up to the given lvl as headings - deeper ones as list items. nil exports all lvls as headings)
+// - num (number sections. an int limits the numbered headline lvl. defaults to #+STARTUP: num / nonum, then nil (non-standard))
+// - ^ (parse sub- & superscripts. {} requires braces (e.g. a_{b}), t also allows a_b, nil disables them. defaults to {} (non-standard))
 // - ealb (non-standard) (export with east asian line breaks / ignore line breaks between multi-byte characters)
+// - rtl (non-standard) (mark paragraphs starting with a right-to-left character as dir="rtl")
 // see https://orgmode.org/manual/Export-Settings.html for more information
 func (d *Document) GetOption(key string) string {
 	get := func(settings map[string]string) string {
@@ -191,6 +510,15 @@ func (d *Document) GetOption(key string) string {
 		return ""
 	}
 	value := get(d.BufferSettings)
+	if value == "" && key == "num" {
+		for _, option := range strings.Fields(d.BufferSettings["STARTUP"]) {
+			if option == "num" {
+				value = "t"
+			} else if option == "nonum" {
+				value = "nil"
+			}
+		}
+	}
 	if value == "" {
 		value = get(d.DefaultSettings)
 	}
@@ -202,6 +530,20 @@ func (d *Document) GetOption(key string) string {
 }
 
 func (d *Document) parseOne(i int, stop stopFn) (consumed int, node Node) {
+	event := -1
+	d.line = i
+	if d.Trace {
+		event = len(d.Events)
+		d.Events = append(d.Events, ParseEvent{Line: i + 1, Token: d.tokens[i].kind, Depth: d.traceDepth})
+		d.traceDepth++
+		defer func() {
+			d.traceDepth--
+			d.Events[event].Lines = consumed
+			if node != nil {
+				d.Events[event].Node = strings.TrimPrefix(fmt.Sprintf("%T", node), "org.")
+			}
+		}()
+	}
 	switch d.tokens[i].kind {
 	case "unorderedList", "orderedList":
 		consumed, node = d.parseList(i, stop)
@@ -209,6 +551,8 @@ func (d *Document) parseOne(i int, stop stopFn) (consumed int, node Node) {
 		consumed, node = d.parseTable(i, stop)
 	case "beginBlock":
 		consumed, node = d.parseBlock(i, stop)
+	case "beginLatexBlock":
+		consumed, node = d.parseLatexBlock(i, stop)
 	case "result":
 		consumed, node = d.parseResult(i, stop)
 	case "beginDrawer":
@@ -233,17 +577,44 @@ func (d *Document) parseOne(i int, stop stopFn) (consumed int, node Node) {
 		return consumed, node
 	}
 	d.Log.Printf("Could not parse token %#v: Falling back to treating it as plain text.", d.tokens[i])
+	d.addError(i, d.tokens[i].lvl+1, unparsedTokenMessage(d.tokens[i]))
+	if event != -1 {
+		d.Events[event].Fallback = true
+	}
 	m := plainTextRegexp.FindStringSubmatch(d.tokens[i].matches[0])
 	d.tokens[i] = token{"text", len(m[1]), m[2], m}
 	return d.parseOne(i, stop)
 }
 
+// addError records a ParseError for the token at index i if Configuration.CollectErrors is set.
+// Errors are only recorded during parsing - writers parse inline markup (e.g. of captions) again.
+func (d *Document) addError(i, column int, message string) {
+	if d.CollectErrors && d.Nodes == nil {
+		d.Errors = append(d.Errors, ParseError{i + 1, column, message})
+	}
+}
+
+func unparsedTokenMessage(t token) string {
+	switch t.kind {
+	case "beginBlock":
+		return fmt.Sprintf("#+BEGIN_%s without #+END_%s", t.content, t.content)
+	case "beginLatexBlock":
+		return fmt.Sprintf("\\begin{%s} without \\end{%s}", t.content, t.content)
+	case "beginDrawer":
+		return fmt.Sprintf(":%s: drawer without :END:", t.content)
+	default:
+		return fmt.Sprintf("could not parse %s - treating it as plain text", t.kind)
+	}
+}
+
 func (d *Document) parseMany(i int, stop stopFn) (int, []Node) {
 	start, nodes := i, []Node{}
 	for i < len(d.tokens) && !stop(d, i) {
 		consumed, node := d.parseOne(i, stop)
 		i += consumed
-		nodes = append(nodes, node)
+		if node != nil {
+			nodes = append(nodes, node)
+		}
 	}
 	return i - start, nodes
 }
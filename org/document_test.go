@@ -0,0 +1,1191 @@
+package org
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	gotoken "go/token"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTangle(t *testing.T) {
+	input := `#+NAME: greeting
+#+BEGIN_SRC sh
+echo hello
+echo world
+#+END_SRC
+
+* a headline
+#+BEGIN_SRC sh :tangle hello.sh :noweb yes
+#!/bin/sh
+  <<greeting>> # indented
+#+END_SRC
+
+#+BEGIN_SRC sh :tangle hello.sh
+<<greeting>>
+#+END_SRC
+
+#+BEGIN_SRC python :tangle no
+print("not tangled")
+#+END_SRC
+
+#+BEGIN_SRC python :tangle yes
+print("tangled")
+#+END_SRC
+`
+	expected := map[string]string{
+		"hello.sh":      "#!/bin/sh\n  echo hello # indented\n  echo world # indented\n<<greeting>>\n",
+		"tangle.python": "print(\"tangled\")\n",
+	}
+	actual := New().Silent().Parse(strings.NewReader(input), "./tangle.org").Tangle()
+	if len(actual) != len(expected) {
+		t.Errorf("expected %d tangled files, got %d: %v", len(expected), len(actual), actual)
+	}
+	for file, content := range expected {
+		if string(actual[file]) != content {
+			t.Errorf("%s:\n%s", file, diff(string(actual[file]), content))
+		}
+	}
+}
+
+func TestBlockParameters(t *testing.T) {
+	tests := map[string]struct {
+		parameters, switches []string
+	}{
+		"#+BEGIN_SRC bash -n -r :results output":                  {[]string{"bash", ":results", "output"}, []string{"-n", "-r"}},
+		"#+BEGIN_SRC bash :results output -n 10 :exports code -r": {[]string{"bash", ":results", "output", ":exports", "code"}, []string{"-n 10", "-r"}},
+		`#+BEGIN_SRC -l "(ref:%s)" :cmdline -foo -bar`:            {[]string{":cmdline", "-foo -bar"}, []string{`-l "(ref:%s)"`}},
+		"#+BEGIN_SRC +n":                    {[]string{}, []string{"+n"}},
+		"#+BEGIN_SRC sh :cmdline -n -r":     {[]string{"sh", ":cmdline", "-n"}, []string{"-r"}},
+		"#+BEGIN_SRC sh -n 5 :cmdline -l 1": {[]string{"sh", ":cmdline", "-l 1"}, []string{"-n 5"}},
+	}
+	for line, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(line+"\nfoo\n#+END_SRC\n"), "./block.org")
+		b, ok := d.Nodes[0].(Block)
+		if !ok {
+			t.Errorf("%s: expected Block, got %#v", line, d.Nodes[0])
+			continue
+		}
+		if fmt.Sprint(b.Parameters) != fmt.Sprint(expected.parameters) || fmt.Sprint(b.Switches) != fmt.Sprint(expected.switches) {
+			t.Errorf("%s\n got: %q %q\n expected: %q %q", line, b.Parameters, b.Switches, expected.parameters, expected.switches)
+		}
+	}
+}
+
+func TestBlockVariables(t *testing.T) {
+	line := `#+BEGIN_SRC python :var x=1 :results output :var data=table[1:2,0], s="a, b" :var call=block(y=2, z=3) :var empty`
+	input := line + "\nprint(x)\n#+END_SRC\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./block.org")
+	b := d.Nodes[0].(Block)
+	expected := []Variable{{"x", "1"}, {"data", "table[1:2,0]"}, {"s", `"a, b"`}, {"call", "block(y=2, z=3)"}, {"empty", ""}}
+	if actual := b.Variables(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("got %q, expected %q", actual, expected)
+	}
+	if actual := String(d.Nodes); actual != input {
+		t.Errorf("parameter line was not preserved:\n%s", diff(actual, input))
+	}
+}
+
+func TestNodeWithMeta(t *testing.T) {
+	input := "#+CAPTION: a *bold*\n#+CAPTION: caption\n#+ATTR_HTML: :class a :width 100\n#+ATTR_HTML: :width 200\n| a table |\n"
+	n, ok := New().Silent().Parse(strings.NewReader(input), "./meta.org").Nodes[0].(NodeWithMeta)
+	if !ok {
+		t.Fatalf("expected NodeWithMeta")
+	}
+	if caption := orgWriter.WriteNodesAsString(n.Caption()...); caption != "a *bold* caption" {
+		t.Errorf("expected caption %q, got %q", "a *bold* caption", caption)
+	}
+	for key, expected := range map[string]string{"class": "a", ":width": "200"} {
+		if actual, ok := n.HTMLAttr(key); !ok || actual != expected {
+			t.Errorf("expected %s to be %q, got %q (%v)", key, expected, actual, ok)
+		}
+	}
+	if _, ok := n.HTMLAttr("id"); ok {
+		t.Errorf("expected id to not be set")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	splitParser := InlineParser{Chars: "%", Parse: func(input string, start int) (int, Node) { return 1, Text{"%", false} }}
+	c := New().Silent()
+	c.InlineParsers = []InlineParser{splitParser}
+	d := c.Parse(strings.NewReader("* a %% b\nfoo % bar *baz % qux*\n"), "./normalize.org")
+	h := d.Nodes[0].(Headline)
+	if len(h.Title) != 4 || len(h.Children[0].(Paragraph).Children) != 4 {
+		t.Fatalf("expected split text nodes, got %#v", h)
+	}
+	h = d.Normalize().Nodes[0].(Headline)
+	if expected := []Node{Text{"a %% b", false}}; fmt.Sprint(h.Title) != fmt.Sprint(expected) {
+		t.Errorf("expected title %#v, got %#v", expected, h.Title)
+	}
+	expected := []Node{Text{"foo % bar ", false}, Emphasis{"*", []Node{Text{"baz % qux", false}}}}
+	if actual := h.Children[0].(Paragraph).Children; fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+		t.Errorf("expected paragraph %#v, got %#v", expected, actual)
+	}
+	if title := d.Outline.Children[0].Headline.Title; len(title) != 1 {
+		t.Errorf("expected outline headline to be normalized, got %#v", title)
+	}
+}
+
+func TestTodoKeywords(t *testing.T) {
+	input := "#+TODO: TODO(t) WAITING(w@/!) | DONE(d!) CANCELED(@)\n#+TODO: REPORT BUG | FIXED\n#+TODO: OPEN CLOSED\n* WAITING for input\n* FIXED bug\n* CLOSED issue\n* WAITING(w@/!) not a status\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./todo.org")
+	expected := []TodoKeyword{
+		{"TODO", "t", "", false, 0}, {"WAITING", "w", "@/!", false, 0}, {"DONE", "d", "!", true, 0}, {"CANCELED", "", "@", true, 0},
+		{"REPORT", "", "", false, 1}, {"BUG", "", "", false, 1}, {"FIXED", "", "", true, 1},
+		{"OPEN", "", "", false, 2}, {"CLOSED", "", "", true, 2},
+	}
+	if actual := d.TodoKeywords(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("expected keywords\n%v\ngot\n%v", expected, actual)
+	}
+	for i, status := range []string{"WAITING", "FIXED", "CLOSED", ""} {
+		if h := d.Nodes[i+3].(Headline); h.Status != status {
+			t.Errorf("expected headline %d to have status %q, got %q", i, status, h.Status)
+		}
+	}
+	if out := d.Nodes[0].String(); out != "#+TODO: TODO(t) WAITING(w@/!) | DONE(d!) CANCELED(@)\n" {
+		t.Errorf("expected #+TODO to round trip, got %q", out)
+	}
+}
+
+func TestHeadlineStatus(t *testing.T) {
+	input := "#+TODO: TODO WAITING | DONE CANCELED\n#+TODO: OPEN CLOSED\n* headline\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./todo.org")
+	h := d.Nodes[2].(Headline)
+	expected := []string{"TODO", "WAITING", "DONE", "CANCELED", "", "TODO"}
+	for _, status := range expected {
+		if err := h.CycleStatus(d); err != nil || h.Status != status {
+			t.Fatalf("expected status %q, got %q (%v)", status, h.Status, err)
+		}
+	}
+	if err := h.SetStatus(d, "OPEN"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := h.CycleStatus(d); err != nil || h.Status != "CLOSED" {
+		t.Errorf("expected status CLOSED, got %q (%v)", h.Status, err)
+	}
+	if err := h.SetStatus(d, "INVALID"); err == nil || h.Status != "CLOSED" {
+		t.Errorf("expected error for unknown keyword, got %v (status %q)", err, h.Status)
+	}
+	d.Nodes[2] = h
+	if out := d.Nodes[2].String(); out != "* CLOSED headline\n" {
+		t.Errorf("expected headline to be written with new status, got %q", out)
+	}
+}
+
+func TestHeadlineSlug(t *testing.T) {
+	for title, expected := range map[string]string{
+		"Hello World":                                "hello-world",
+		"Crème Brûlée & Straße":                      "creme-brulee-strasse",
+		"Überall: Smörgåsbord?!":                     "ueberall-smoergasbord",
+		"*bold* and [[https://example.com][a link]]": "bold-and-a-link",
+		"What's new in v1.2 -- (beta)...":            "what-s-new-in-v1-2-beta",
+		"日本語 タイトル":                                   "日本語-タイトル",
+		"TODO [#A] Write docs :tag:":                 "write-docs",
+	} {
+		d := New().Silent().Parse(strings.NewReader("** "+title), "./slug.org")
+		h := d.Nodes[0].(Headline)
+		if h.Slug() != expected {
+			t.Errorf("%q: expected slug %q, got %q", title, expected, h.Slug())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	input := `Intro [[#custom]] [[#missing]] [[*Second headline]] [[*No such headline]][fn:undefined]
+* First
+:PROPERTIES:
+:CUSTOM_ID: custom
+:ID: 1234
+:END:
+#+NAME: table
+| a |
+
+[[table]] [[id:1234]] [[id:5678]] [[https://example.com]] [[./file.org]] [[radio]] [[nothing]]
+* Second headline
+<<<radio>>> [fn:a] [fn:inline: definition]
+
+[fn:a] referenced
+[fn:b] unreferenced
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./validate.org")
+	expected := []string{
+		"1: broken-link: broken link: [[#missing]] (-)",
+		"1: broken-link: broken link: [[*No such headline]] (-)",
+		"1: missing-footnote-definition: missing footnote definition: [fn:undefined] (-)",
+		"10: broken-link: broken link: [[id:5678]] (First)",
+		"10: broken-link: broken link: [[nothing]] (First)",
+		"15: unreferenced-footnote-definition: unreferenced footnote definition: [fn:b] (Second headline)",
+	}
+	actual := []string{}
+	for _, issue := range d.Validate() {
+		headline := "-"
+		if issue.Headline != nil {
+			headline = String(issue.Headline.Title)
+		}
+		actual = append(actual, fmt.Sprintf("%d: %s: %s (%s)", issue.Line, issue.Kind, issue.Message, headline))
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected issues\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+	if out := String(d.Nodes); out != String(New().Silent().Parse(strings.NewReader(input), "./validate.org").Nodes) {
+		t.Errorf("expected Validate to not modify the document")
+	}
+}
+
+func TestTableCells(t *testing.T) {
+	input := "| a | b |\n|---+---|\n| <l> |\n| c |\n| d | e | f |\n|---|\n"
+	table := New().Silent().Parse(strings.NewReader(input), "./table.org").Nodes[0].(Table)
+	if table.RowCount() != 3 || table.ColCount() != 3 {
+		t.Errorf("expected 3x3 table, got %dx%d", table.RowCount(), table.ColCount())
+	}
+	for _, cell := range []struct {
+		row, col int
+		expected string
+	}{{0, 0, "a"}, {0, 1, "b"}, {0, 2, ""}, {1, 0, "c"}, {1, 1, ""}, {2, 2, "f"}, {3, 0, ""}, {-1, 0, ""}, {0, 3, ""}, {0, -1, ""}} {
+		if actual := String(table.Cell(cell.row, cell.col)); actual != cell.expected {
+			t.Errorf("expected cell (%d, %d) to be %q, got %q", cell.row, cell.col, cell.expected, actual)
+		}
+	}
+}
+
+func TestTableCSV(t *testing.T) {
+	input := "| name | note |\n|------+------|\n| <l> |\n| *a* | [[https://example.com][x, y]] |\n| b | say \"hi\" |\n\n#+NAME: second\n| c |\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./table.org")
+	tables := d.Tables()
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d", len(tables))
+	}
+	expected := [][]string{{"name", "note"}, {"a", "x, y"}, {"b", `say "hi"`}}
+	if actual := tables[0].Records(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got records %#v, expected %#v", actual, expected)
+	}
+	if actual, expected := tables[0].CSV(), "name,note\na,\"x, y\"\nb,\"say \"\"hi\"\"\"\n"; actual != expected {
+		t.Errorf("got csv %q, expected %q", actual, expected)
+	}
+	if table, ok := d.NamedTable("second"); !ok || table.CSV() != "c\n" {
+		t.Errorf("expected named table second, got %#v", table)
+	}
+	if _, ok := d.NamedTable("missing"); ok {
+		t.Errorf("expected no table named missing")
+	}
+}
+
+func TestResultTable(t *testing.T) {
+	input := "#+NAME: square\n#+BEGIN_SRC python :exports both\nreturn [[1, 1], [2, 4]]\n#+END_SRC\n\n#+RESULTS: square\n| 1 | 1 |\n| 2 | 4 |\n\n#+BEGIN_SRC sh\necho a\n#+END_SRC\n\n#+RESULTS:\n: a\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./results.org")
+	table, ok := d.NamedTable("square")
+	if expected := [][]string{{"1", "1"}, {"2", "4"}}; !ok || !reflect.DeepEqual(table.Records(), expected) {
+		t.Errorf("expected results table %#v, got %#v (%v)", expected, table.Records(), ok)
+	}
+	if _, ok := d.Nodes[len(d.Nodes)-1].(Block).ResultTable(); ok {
+		t.Errorf("expected example results not to be a table")
+	}
+	out, err := d.Write(NewHTMLWriter())
+	if err != nil || !strings.Contains(out, "<table>") || !strings.Contains(out, "<pre class=\"example\">\na\n</pre>") {
+		t.Errorf("expected table & example results, got %v:\n%s", err, out)
+	}
+}
+
+func TestStripCharacters(t *testing.T) {
+	input := "hyph\u00adenation and \u200b*bo\u00adld*\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./invisible.org")
+	if actual := String(d.Nodes); actual != input {
+		t.Errorf("expected invisible characters to be preserved, got %q", actual)
+	}
+	c := New().Silent()
+	c.StripCharacters = InvisibleCharacters
+	d = c.Parse(strings.NewReader(input), "./invisible.org")
+	if actual, expected := String(d.Nodes), "hyphenation and *bold*\n"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if p := d.Nodes[0].(Paragraph); len(p.Children) != 2 || p.Children[1].(Emphasis).Kind != "*" {
+		t.Errorf("expected emphasis after stripped zero width space, got %#v", p.Children)
+	}
+}
+
+func TestUnknownKeywords(t *testing.T) {
+	input := "#+TITLE: title\n#+FOO: foo\n#+HTML_HEAD: <style></style>\n"
+	for policy, expected := range map[string]int{"": 3, "keep": 3, "error": 3, "drop": 2} {
+		out := &strings.Builder{}
+		c := New()
+		c.Log, c.UnknownKeywords = log.New(out, "", 0), policy
+		d := c.Parse(strings.NewReader(input), "./keywords.org")
+		if len(d.Nodes) != expected {
+			t.Errorf("%s: expected %d nodes, got %d: %#v", policy, expected, len(d.Nodes), d.Nodes)
+		}
+		if d.Get("FOO") != "foo" {
+			t.Errorf("%s: expected unknown keyword to be available as buffer setting", policy)
+		}
+		if logged := strings.Contains(out.String(), "#+FOO"); logged != (policy == "error") {
+			t.Errorf("%s: unexpected log output: %q", policy, out.String())
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	input := "#+AUTHOR: a\n#+TITLE: title\n#+AUTHOR: b\n#+HTML_HEAD: <style></style>\n#+HTML_HEAD: <script></script>\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./keywords.org")
+	expected := map[string][]string{
+		"AUTHOR":    {"a", "b"},
+		"TITLE":     {"title"},
+		"HTML_HEAD": {"<style></style>", "<script></script>"},
+	}
+	if actual := d.Keywords(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("got %v, expected %v", actual, expected)
+	}
+	if actual, err := d.Write(NewOrgWriter()); err != nil || actual != input {
+		t.Errorf("bad round trip (%v):\n%s", err, diff(actual, input))
+	}
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"dir/a.org":      "* a\n#+INCLUDE: \"sub/b.org\"\n",
+		"dir/sub/b.org":  "b1\n#+INCLUDE: \"c.go\" src go :lines \"2-3\"\n",
+		"dir/sub/c.go":   "package c\nfunc a() {}\nfunc b() {}\nfunc c() {}\n",
+		"dir/lines.org":  "one\n\ntwo\n\nthree\n",
+		"dir/cycle.org":  "#+INCLUDE: \"./cycle2.org\"\n",
+		"dir/cycle2.org": "#+INCLUDE: \"./cycle.org\"\n",
+	}
+	conf := New().Silent()
+	conf.ReadFile = func(filename string) ([]byte, error) {
+		if content, ok := files[filename]; ok {
+			return []byte(content), nil
+		}
+		return nil, fmt.Errorf("%s: no such file", filename)
+	}
+	parse := func(input string) *Document { return conf.Parse(strings.NewReader(input), "dir/test.org") }
+
+	d := parse("#+INCLUDE: \"a.org\"\n")
+	if d.Error != nil {
+		t.Errorf("unexpected error: %s", d.Error)
+	} else if actual, expected := String(d.Nodes), "#+INCLUDE: \"a.org\"\n"; actual != expected {
+		t.Errorf("include round trip:\n%s", diff(actual, expected))
+	} else if actual, expected := String(d.Nodes[0].(Include).Children), "* a\nb1\n#+INCLUDE: \"sub/c.go\" src go :lines \"2-3\"\n"; actual != expected {
+		t.Errorf("nested include:\n%s", diff(actual, expected))
+	} else if h, ok := d.Nodes[0].(Include).Children[0].(Headline); !ok || len(h.Children) != 2 {
+		t.Errorf("expected included nodes to be children of the included headline: %#v", d.Nodes)
+	} else if actual, expected := String(h.Children[1].(Include).Nodes()), "#+BEGIN_SRC go\nfunc a() {}\nfunc b() {}\n#+END_SRC\n"; actual != expected {
+		t.Errorf("nested src include:\n%s", diff(actual, expected))
+	}
+
+	d = parse("- item\n  #+INCLUDE: \"lines.org\"\n- next\n")
+	if l, ok := d.Nodes[0].(List); !ok || len(l.Items) != 2 {
+		t.Errorf("expected include to stay inside the list item: %#v", d.Nodes)
+	} else if actual, expected := String(l.Items[0].(ListItem).Children[1].(Include).Children), "one\n\ntwo\n\nthree\n"; actual != expected {
+		t.Errorf("list item include:\n%s", diff(actual, expected))
+	} else if out, err := d.Write(NewHTMLWriter()); err != nil || !strings.Contains(out, "<li>\n<p>item</p>\n<p>one</p>") || !strings.Contains(out, "three</p>\n</li>\n<li>next</li>") {
+		t.Errorf("list item include html: %q %v", out, err)
+	}
+
+	d = parse("#+NAME: foo\n#+INCLUDE: \"lines.org\" :lines \"1-2\"\n")
+	if n, ok := d.Nodes[0].(NodeWithName); len(d.Nodes) != 1 || !ok || n.Name != "foo" {
+		t.Errorf("expected named include: %#v", d.Nodes)
+	} else if actual, expected := String(d.Nodes), "#+NAME: foo\n#+INCLUDE: \"lines.org\" :lines \"1-2\"\n"; actual != expected {
+		t.Errorf("named include:\n%s", diff(actual, expected))
+	}
+
+	expected := "two\n\nthree\n"
+	if actual := String(parse("#+INCLUDE: \"lines.org\" :lines \"3-\"\n").Nodes[0].(Include).Children); actual != expected {
+		t.Errorf(":lines include:\n%s", diff(actual, expected))
+	}
+
+	if d := parse("#+INCLUDE: \"cycle.org\"\n"); d.Error == nil || !strings.Contains(d.Error.Error(), "include cycle") {
+		t.Errorf("expected include cycle error, got %v", d.Error)
+	}
+	if d := parse("#+INCLUDE: \"missing.org\"\n"); d.Error == nil || !strings.Contains(d.Error.Error(), "could not include") {
+		t.Errorf("expected missing file error, got %v", d.Error)
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	files := map[string]string{
+		"dir/setup.org":    "#+SETUPFILE: sub/base.org\n#+MACRO: greet hello $1\n* ignored\nbody\n",
+		"dir/sub/base.org": "#+LINK: gh https://github.com/%s\n#+OPTIONS: toc:nil\n",
+		"dir/cycle.org":    "#+SETUPFILE: cycle2.org\n#+MACRO: m cycle\n",
+		"dir/cycle2.org":   "#+SETUPFILE: cycle.org\n",
+		"dir/defaults.org": "#+TITLE: setup title\n#+AUTHOR: setup author\n#+OPTIONS: toc:nil num:nil\n#+MACRO: m setup\n",
+	}
+	conf := New().Silent()
+	conf.ReadFile = func(filename string) ([]byte, error) {
+		if content, ok := files[filename]; ok {
+			return []byte(content), nil
+		}
+		return nil, fmt.Errorf("%s: no such file", filename)
+	}
+	d := conf.Parse(strings.NewReader("#+SETUPFILE: setup.org\n{{{greet(you)}}} [[gh:niklasfasching]]\n"), "dir/test.org")
+	out, err := d.Write(NewHTMLWriter())
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := `<p>hello you <a href="https://github.com/niklasfasching">https://github.com/niklasfasching</a></p>`; !strings.Contains(out, expected) {
+		t.Errorf("expected macros and links of the setup files to be applied:\n%s", diff(out, expected))
+	} else if strings.Contains(out, "ignored") || d.GetOption("toc") != "nil" {
+		t.Errorf("expected only the settings of the setup files to be imported: %q %s", d.GetOption("toc"), out)
+	}
+	if d := conf.Parse(strings.NewReader("#+SETUPFILE: cycle.org\n"), "dir/test.org"); d.Error != nil || d.Macros["m"] != "cycle" {
+		t.Errorf("expected setup file cycle to be ignored: %v %v", d.Error, d.Macros)
+	}
+	d = conf.Parse(strings.NewReader("#+TITLE: own title\n#+MACRO: m own\n#+SETUPFILE: defaults.org\n#+OPTIONS: num:t\n"), "dir/test.org")
+	if d.Get("TITLE") != "own title" || d.Get("AUTHOR") != "setup author" || d.Macros["m"] != "own" {
+		t.Errorf("expected the buffer's own settings to win: %q %q %q", d.Get("TITLE"), d.Get("AUTHOR"), d.Macros["m"])
+	} else if d.GetOption("num") != "t" || d.GetOption("toc") != "nil" {
+		t.Errorf("expected the buffer's own options to win: num:%s toc:%s", d.GetOption("num"), d.GetOption("toc"))
+	}
+}
+
+func TestClone(t *testing.T) {
+	input := "#+TITLE: clone\n* TODO a :tag:\nSCHEDULED: <2024-01-02 Tue>\n:PROPERTIES:\n:ID: a\n:END:\n#+NAME: t\n| /x/ | y |\n\n- item [fn:1]\n\n[fn:1] definition\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./clone.org")
+	expected := String(d.Nodes)
+	clone := d.Clone()
+	if actual := String(clone.Nodes); actual != expected {
+		t.Fatalf("clone differs:\n%s", diff(actual, expected))
+	}
+
+	h := clone.Nodes[1].(Headline)
+	h.Title[0] = Text{"b", false}
+	h.Tags[0], h.Properties.Properties[0][1] = "other", "b"
+	h.Scheduled.Time = h.Scheduled.Time.AddDate(1, 0, 0)
+	table := h.Children[0].(NodeWithName).Node.(Table)
+	table.Rows[0].Columns[0].Children[0].(Emphasis).Content[0] = Text{"z", false}
+	table.ColumnInfos[0].Align = "right"
+	h.Children[2].(List).Items[0].(ListItem).Children[0].(Paragraph).Children[0] = Text{"changed ", false}
+	clone.Footnotes.Definitions["1"].Children[0] = Paragraph{[]Node{Text{"changed", false}}}
+	clone.Outline.Children[0].Headline.Title[0] = Text{"c", false}
+	clone.BufferSettings["TITLE"] = "changed"
+
+	if actual := String(d.Nodes); actual != expected {
+		t.Errorf("modifying the clone modified the original:\n%s", diff(actual, expected))
+	}
+	if actual := String(d.Footnotes.Definitions["1"].Children); actual != "definition\n" {
+		t.Errorf("modifying the clone modified the original footnotes: %q", actual)
+	}
+	if actual := String(d.Outline.Children[0].Headline.Title); actual != "a" {
+		t.Errorf("modifying the clone modified the original outline: %q", actual)
+	}
+	if d.Get("TITLE") != "clone" || table.Rows[0].Columns[0].Align != "right" {
+		t.Errorf("bad clone settings or column infos: %q %q", d.Get("TITLE"), table.Rows[0].Columns[0].Align)
+	}
+}
+
+// TestCloneAliasing clones the nodes of all testdata files and checks that the clone does not share memory with the original.
+// It fails for node types that are missing from the cloner (and thus copied as is) - see nodeTypes.
+func TestCloneAliasing(t *testing.T) {
+	inputs := map[string]string{"./clone.org": "-----\n* a\n:PROPERTIES:\n:A: b\n:END:\n"}
+	for _, path := range orgTestFiles() {
+		inputs[path] = fileString(t, path)
+	}
+	seen, visited := map[string]bool{}, map[uintptr]bool{}
+	var check func(path string, original, clone reflect.Value)
+	check = func(path string, original, clone reflect.Value) {
+		switch original.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			if original.IsNil() || original.Kind() == reflect.Slice && original.Cap() == 0 {
+				return
+			} else if original.Pointer() == clone.Pointer() {
+				t.Errorf("%s: clone shares %s with the original", path, original.Type())
+			} else if original.Kind() == reflect.Ptr && !visited[original.Pointer()] {
+				visited[original.Pointer()] = true
+				check(path, original.Elem(), clone.Elem())
+			} else if original.Kind() == reflect.Slice {
+				for i := 0; i < original.Len(); i++ {
+					check(path, original.Index(i), clone.Index(i))
+				}
+			} else if original.Kind() == reflect.Map {
+				for _, k := range original.MapKeys() {
+					check(path, original.MapIndex(k), clone.MapIndex(k))
+				}
+			}
+		case reflect.Interface:
+			if !original.IsNil() {
+				check(path, original.Elem(), clone.Elem())
+			}
+		case reflect.Struct:
+			seen[original.Type().Name()] = true
+			for i := 0; i < original.NumField(); i++ {
+				check(path, original.Field(i), clone.Field(i))
+			}
+		}
+	}
+	for path, input := range inputs {
+		d := New().Silent().Parse(strings.NewReader(input), path)
+		check(path, reflect.ValueOf(d.Nodes), reflect.ValueOf(d.Clone().Nodes))
+	}
+	for _, name := range nodeTypes(t) {
+		if !seen[name] {
+			t.Errorf("node type %s does not occur in the inputs - the aliasing of its clones is not checked", name)
+		}
+	}
+}
+
+// nodeTypes returns the names of all types of the package that implement Node, i.e. have a String method with a value receiver.
+func nodeTypes(t *testing.T) (names []string) {
+	packages, err := parser.ParseDir(gotoken.NewFileSet(), ".", func(f fs.FileInfo) bool { return !strings.HasSuffix(f.Name(), "_test.go") }, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range packages["org"].Files {
+		for _, decl := range f.Decls {
+			if f, ok := decl.(*ast.FuncDecl); ok && f.Recv != nil && f.Name.Name == "String" {
+				if ident, ok := f.Recv.List[0].Type.(*ast.Ident); ok {
+					names = append(names, ident.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+func TestICalendar(t *testing.T) {
+	input := `* TODO Meeting, weekly :work:team:
+SCHEDULED: <2024-01-02 Tue 10:00-11:00 +2w>
+:PROPERTIES:
+:ID: meeting
+:END:
+* Trip
+<2024-02-01 Thu>--<2024-02-03 Sat> and [2024-02-04 Sun]
+** Report
+DEADLINE: <2024-03-01 Fri>
+* Excluded :noexport:
+SCHEDULED: <2024-04-01 Mon>
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./calendar.org")
+	ics := d.ICS()
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//go-org//EN\r\nBEGIN:VEVENT\r\nUID:SC-meeting\r\nDTSTAMP:",
+		"DTSTART:20240102T100000\r\nDTEND:20240102T110000\r\nSUMMARY:Meeting\\, weekly\r\nCATEGORIES:work,team\r\nRRULE:FREQ=WEEKLY;INTERVAL=2\r\nEND:VEVENT\r\n",
+		"UID:TS-headline-2\r\n",
+		"DTSTART;VALUE=DATE:20240201\r\nDTEND;VALUE=DATE:20240204\r\nSUMMARY:Trip\r\nEND:VEVENT\r\n",
+		"UID:DL-headline-3\r\n",
+		"DTSTART;VALUE=DATE:20240301\r\nDTEND;VALUE=DATE:20240302\r\nSUMMARY:Report\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("expected ics to contain %q:\n%s", expected, ics)
+		}
+	}
+	if events := d.ICalendar(); len(events) != 3 {
+		t.Errorf("expected 3 events, got %d: %#v", len(events), events)
+	}
+	if actual := foldICalendarLine(strings.Repeat("ä", 40)); actual != strings.Repeat("ä", 37)+"\r\n "+strings.Repeat("ä", 3) {
+		t.Errorf("bad folding: %q", actual)
+	}
+}
+
+func TestFootnotesOrdered(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader(footnoteOrderInput+"x[fn:missing] [fn:b]\n"), "./footnotes.org")
+	names := func(fs []*FootnoteDefinition) (names []string) {
+		for _, f := range fs {
+			if f == nil {
+				names = append(names, "<nil>")
+			} else {
+				names = append(names, f.Name)
+			}
+		}
+		return names
+	}
+	if actual, expected := names(d.Footnotes.Ordered(false)), []string{"b", "a", "", "<nil>"}; fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("reference order: got %v, expected %v", actual, expected)
+	}
+	if actual, expected := names(d.Footnotes.Ordered(true)), []string{"a", "b", "", "<nil>"}; fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("definition order: got %v, expected %v", actual, expected)
+	}
+}
+
+func TestRender(t *testing.T) {
+	input := "* headline :tag:\nsome /text/\n"
+	html, err := RenderHTML(input, WithHTMLWriter(func(w *HTMLWriter) { w.PrettyRelativeLinks = true }), WithConfiguration(func(c *Configuration) { c.Silent() }))
+	if err != nil || !strings.Contains(html, "<p>some <em>text</em></p>") {
+		t.Errorf("unexpected html: %q (%v)", html, err)
+	}
+	org, err := RenderOrg(input, WithOrgWriter(func(w *OrgWriter) { w.TagsColumn = 20 }))
+	if expected := "* headline     :tag:\nsome /text/\n"; err != nil || org != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, org, err)
+	}
+	html, err = RenderHTML("#+INCLUDE: \"setup_file_org\" src org\n", WithPath("./testdata/render.org"), WithSilent())
+	if err != nil || !strings.Contains(html, "#+TODO: TODO DONE CUSTOM") {
+		t.Errorf("expected include relative to path: %q (%v)", html, err)
+	}
+	org, err = RenderOrg(input, WithTagsColumn(18), WithLog(log.New(ioutil.Discard, "", 0)))
+	if expected := "* headline   :tag:\nsome /text/\n"; err != nil || org != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, org, err)
+	}
+	html, err = RenderHTML("* DOING headline\n", WithDefaultSetting("TODO", "DOING | DONE"), WithSilent())
+	if err != nil || !strings.Contains(html, `<span class="todo DOING">DOING</span>`) {
+		t.Errorf("unexpected html: %q (%v)", html, err)
+	}
+	html, err = RenderHTML("a -> b => c\n", WithPrettifyText(), WithSilent())
+	if err != nil || !strings.Contains(html, "<p>a → b ⇒ c</p>") {
+		t.Errorf("unexpected html: %q (%v)", html, err)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("[2024-01-02 Tue] <2024-01-02 Tue 10:00-12:00 +1w> <2024-01-02>--<2024-01-05>"), "./")
+	nodes := d.Nodes[0].(Paragraph).Children
+	inactive, timeRange, dateRange := nodes[0].(Timestamp), nodes[2].(Timestamp), nodes[4].(Timestamp)
+	if inactive.IsActive || !inactive.IsDate || inactive.End != nil {
+		t.Errorf("expected inactive date, got %#v", inactive)
+	}
+	if !timeRange.IsActive || timeRange.Interval != "+1w" || timeRange.End == nil || timeRange.End.Format("15:04") != "12:00" {
+		t.Errorf("expected active time range with repeater, got %#v", timeRange)
+	}
+	if !dateRange.IsDate || !dateRange.EndIsDate || dateRange.End == nil || dateRange.End.Format("2006-01-02") != "2024-01-05" {
+		t.Errorf("expected date range, got %#v", dateRange)
+	}
+	for _, input := range []string{"<2024-01-02 Tue +1w>--<2024-01-05 Fri>", "<2024-01-02 Tue>--<2024-01-05 Fri 10:00 +1d>"} {
+		if actual := String(New().Silent().Parse(strings.NewReader(input), "./").Nodes); actual != input+"\n" {
+			t.Errorf("expected each side of the range to keep its own date & repeater:\n%s", diff(actual, input+"\n"))
+		}
+	}
+}
+
+func TestHeadlinePlanning(t *testing.T) {
+	canonical := "* TODO headline\nDEADLINE: <2024-01-05 Fri> SCHEDULED: <2024-01-02 Tue>\n:PROPERTIES:\n:ID: foo\n:END:\ntext\n"
+	for name, input := range map[string]string{
+		"planning before drawer": "* TODO headline\nSCHEDULED: <2024-01-02 Tue> DEADLINE: <2024-01-05 Fri>\n:PROPERTIES:\n:ID: foo\n:END:\ntext\n",
+		"drawer before planning": "* TODO headline\n:PROPERTIES:\n:ID: foo\n:END:\nSCHEDULED: <2024-01-02 Tue> DEADLINE: <2024-01-05 Fri>\ntext\n",
+		"blank lines in between": "* TODO headline\n\n:PROPERTIES:\n:ID: foo\n:END:\n\nDEADLINE: <2024-01-05 Fri>   SCHEDULED: <2024-01-02 Tue>\ntext\n",
+	} {
+		d := New().Silent().Parse(strings.NewReader(input), "./")
+		h := d.Nodes[0].(Headline)
+		if id, _ := h.Properties.Get("ID"); id != "foo" || h.Scheduled == nil || h.Deadline == nil || h.Closed != nil {
+			t.Errorf("%s: expected planning & properties to be attached: %#v", name, h)
+		} else if actual := String(d.Nodes); actual != canonical {
+			t.Errorf("%s: expected canonical order\n%s", name, diff(actual, canonical))
+		}
+	}
+	d := New().Silent().Parse(strings.NewReader("* headline\ntext\nSCHEDULED: <2024-01-02 Tue>\n"), "./")
+	if h := d.Nodes[0].(Headline); h.Scheduled != nil || len(h.Children) != 1 {
+		t.Errorf("expected planning line after text to be a paragraph: %#v", h)
+	}
+}
+
+func TestConsecutiveEmphasisMarkers(t *testing.T) {
+	tests := map[string][]Node{
+		"a ** b":      {Text{"a ** b", false}},
+		"a ** ** b":   {Text{"a ** ** b", false}},
+		"a * * b":     {Text{"a * * b", false}},
+		"=  = ====":   {Text{"=  = ====", false}},
+		"***bold***":  {Emphasis{"*", []Node{Emphasis{"*", []Node{Emphasis{"*", []Node{Text{"bold", false}}}}}}}},
+		"**bold?** a": {Emphasis{"*", []Node{Emphasis{"*", []Node{Text{"bold?", false}}}}}, Text{" a", false}},
+		"~*~":         {Emphasis{"~", []Node{Text{"*", true}}}},
+	}
+	for input, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(input), "./emphasis.org")
+		if actual := d.Nodes[0].(Paragraph).Children; fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", input, actual, expected)
+		}
+	}
+}
+
+func TestEmphasisMarkers(t *testing.T) {
+	tests := []struct {
+		markers, input string
+		expected       []Node
+	}{
+		{"*/_=~", "a + b + c and a +b+ c", []Node{Text{"a + b + c and a +b+ c", false}}},
+		{"*/_=~", "*bold* +b+", []Node{Emphasis{"*", []Node{Text{"bold", false}}}, Text{" +b+", false}}},
+		{"*", "_a_ =b=", []Node{Text{"_a_ =b=", false}}},
+		{"*!", "!custom! *bold*", []Node{Emphasis{"!", []Node{Text{"custom", false}}}, Text{" ", false}, Emphasis{"*", []Node{Text{"bold", false}}}}},
+	}
+	for _, test := range tests {
+		conf := New().Silent()
+		conf.EmphasisMarkers = test.markers
+		d := conf.Parse(strings.NewReader(test.input), "./emphasis.org")
+		if actual := d.Nodes[0].(Paragraph).Children; fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", test.expected) {
+			t.Errorf("%q (%q):\n got %#v\n expected %#v", test.input, test.markers, actual, test.expected)
+		}
+	}
+	conf := New().Silent()
+	conf.EmphasisMarkers += "!"
+	d := conf.Parse(strings.NewReader("!custom! +strike+"), "./emphasis.org")
+	if out, err := d.Write(NewHTMLWriter()); err != nil || out != "<p>!custom! <del>strike</del></p>\n" {
+		t.Errorf("expected custom emphasis to be written as is: %v %q", err, out)
+	} else if out := String(d.Nodes); out != "!custom! +strike+\n" {
+		t.Errorf("expected custom emphasis to be written as is: %q", out)
+	}
+	w := NewHTMLWriter()
+	w.EmphasisTags = map[string][]string{"!": {"<mark>", "</mark>"}}
+	if out, err := d.Write(w); err != nil || out != "<p><mark>custom</mark> +strike+</p>\n" {
+		t.Errorf("expected EmphasisTags to be used: %v %q", err, out)
+	}
+}
+
+func TestEntities(t *testing.T) {
+	tests := map[string][]Node{
+		`\alpha \rightarrow{}x`: {Entity{"alpha", false}, Text{" ", false}, Entity{"rightarrow", true}, Text{"x", false}},
+		`\alpha2 \frac12`:       {Entity{"alpha", false}, Text{"2 ", false}, Entity{"frac12", false}},
+		`a\_  b`:                {Text{"a", false}, Entity{"_  ", false}, Text{"b", false}},
+		`\alphabet \foo \foo{}`: {Text{`\alphabet \foo \foo{}`, false}},
+		"a\\\\\nb":              {Text{"a", false}, ExplicitLineBreak{}, Text{"b", false}},
+	}
+	for input, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(input), "./entities.org")
+		actual := d.Nodes[0].(Paragraph).Children
+		if fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", input, actual, expected)
+		}
+		if out := String(actual); out != input {
+			t.Errorf("%q: round trip failed, got %q", input, out)
+		}
+	}
+	if e := (Entity{"copy", false}); e.UTF8() != "©" || (Entity{"nbsp", true}).HTML() != "&nbsp;" {
+		t.Errorf("unexpected entity representations: %q %q", e.UTF8(), Entity{"nbsp", true}.HTML())
+	}
+}
+
+func TestZeroWidthSpaceEmphasisBoundary(t *testing.T) {
+	tests := map[string][]Node{
+		"*bold*\u200btext":       {Emphasis{"*", []Node{Text{"bold", false}}}, Text{"\u200btext", false}},
+		"text\u200b/italic/":     {Text{"text\u200b", false}, Emphasis{"/", []Node{Text{"italic", false}}}},
+		"a\u200b=code=\u200bb":   {Text{"a\u200b", false}, Emphasis{"=", []Node{Text{"code", true}}}, Text{"\u200bb", false}},
+		"*bold*text":             {Text{"*bold*text", false}},
+		"*\u200bnot bold\u200b*": {Text{"*\u200bnot bold\u200b*", false}},
+	}
+	for input, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(input), "./emphasis.org")
+		actual := d.Nodes[0].(Paragraph).Children
+		if fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", input, actual, expected)
+		}
+		if out := String(actual); out != input {
+			t.Errorf("%q: expected zero width spaces to be preserved, got %q", input, out)
+		}
+	}
+}
+
+func TestEmphasisBoundaries(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Node
+	}{
+		{"foo_bar", []Node{Text{"foo_bar", false}}},
+		{"a_b_c", []Node{Text{"a_b_c", false}}},
+		{"2*3*4", []Node{Text{"2*3*4", false}}},
+		{"mid*bold*word", []Node{Text{"mid*bold*word", false}}},
+		{"~/home~", []Node{Emphasis{"~", []Node{Text{"/home", true}}}}},
+		{"see ~/path/to/file~.", []Node{Text{"see ", false}, Emphasis{"~", []Node{Text{"/path/to/file", true}}}, Text{".", false}}},
+		{"*bold*", []Node{Emphasis{"*", []Node{Text{"bold", false}}}}},
+		{"(*bold*)", []Node{Text{"(", false}, Emphasis{"*", []Node{Text{"bold", false}}}, Text{")", false}}},
+	}
+	for _, test := range tests {
+		d := New().Silent().Parse(strings.NewReader(test.input), "./emphasis.org")
+		actual := d.Nodes[0].(Paragraph).Children
+		if fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", test.expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", test.input, actual, test.expected)
+		}
+	}
+}
+
+func TestExpandLink(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("#+LINK: gh https://github.com/%s/issues\n#+LINK: q https://example.com?q=%h\n#+LINK: GH https://example.com/\n"), "./links.org")
+	tests := []struct {
+		link     RegularLink
+		expected string
+		ok       bool
+	}{
+		{RegularLink{Protocol: "gh", URL: "gh:niklasfasching/go-org"}, "https://github.com/niklasfasching/go-org/issues", true},
+		{RegularLink{Protocol: "q", URL: "q:a b&c"}, "https://example.com?q=a+b%26c", true},
+		{RegularLink{Protocol: "GH", URL: "GH:foo"}, "https://example.com/foo", true},
+		{RegularLink{URL: "gh"}, "https://github.com//issues", true},
+		{RegularLink{Protocol: "Gh", URL: "Gh:foo"}, "Gh:foo", false},
+		{RegularLink{Protocol: "mailto", URL: "mailto:a@b.c"}, "mailto:a@b.c", false},
+	}
+	for _, test := range tests {
+		if expanded, ok := d.ExpandLink(test.link); expanded != test.expected || ok != test.ok {
+			t.Errorf("%s: got %q, %v expected %q, %v", test.link.URL, expanded, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	input := "* a\n- x\n  - y\n#+CAPTION: c\n| a |\n#+BEGIN_SRC\n"
+	if d := New().Silent().Parse(strings.NewReader(input), "./trace.org"); d.Events != nil {
+		t.Errorf("expected no events without Trace, got %#v", d.Events)
+	}
+	c := New().Silent()
+	c.Trace = true
+	expected := []ParseEvent{
+		{Line: 1, Lines: 6, Token: "headline", Node: "Headline"},
+		{Line: 2, Lines: 2, Token: "unorderedList", Node: "List", Depth: 1},
+		{Line: 2, Lines: 1, Token: "text", Node: "Paragraph", Depth: 2},
+		{Line: 3, Lines: 1, Token: "unorderedList", Node: "List", Depth: 2},
+		{Line: 3, Lines: 1, Token: "text", Node: "Paragraph", Depth: 3},
+		{Line: 4, Lines: 2, Token: "keyword", Node: "NodeWithMeta", Depth: 1},
+		{Line: 5, Lines: 1, Token: "tableRow", Node: "Table", Depth: 2},
+		{Line: 6, Lines: 1, Token: "beginBlock", Node: "Paragraph", Depth: 1, Fallback: true},
+		{Line: 6, Lines: 1, Token: "text", Node: "Paragraph", Depth: 2},
+	}
+	if actual := c.Parse(strings.NewReader(input), "./trace.org").Events; fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+		t.Errorf("got\n%#v\nexpected\n%#v", actual, expected)
+	}
+}
+
+func TestExportTags(t *testing.T) {
+	tests := map[string][]string{
+		"* a\n** b :noexport:\n*** c\n* d\n":                                      {"a", "d"},
+		"* a\n** b :export:\n*** c\n** d\n* e\n":                                  {"a", "b", "c"},
+		"* a :export:\n** b\n** c :noexport:\n* d\n":                              {"a", "b"},
+		"#+SELECT_TAGS: public\n* a :export:\n* b :public:\n":                     {"b"},
+		"#+EXCLUDE_TAGS: private\n* a :noexport:\n* b :private:\n** c :export:\n": {},
+	}
+	for input, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(input), "./tags.org")
+		actual := []string{}
+		var walk func([]Node)
+		walk = func(nodes []Node) {
+			for _, n := range nodes {
+				if h, ok := n.(Headline); ok && !h.IsExcluded(d) {
+					actual = append(actual, String(h.Title))
+					walk(h.Children)
+				}
+			}
+		}
+		walk(d.Nodes)
+		if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("%q: got exported headlines %v, expected %v", input, actual, expected)
+		}
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	input := "#+TITLE: Title\n#+DATE: <2024-03-01 Fri 10:30>\n#+FILETAGS: :a:b:\n#+FILETAGS: :c:\n#+AUTHOR: A\n#+AUTHOR: B\n#+CUSTOM: value\n"
+	expected := map[string]interface{}{
+		"title":  "Title",
+		"date":   time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"tags":   []string{"a", "b", "c"},
+		"author": []string{"A", "B"},
+		"custom": "value",
+	}
+	actual := New().Silent().Parse(strings.NewReader(input), "./front_matter.org").FrontMatter()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %#v\nexpected %#v", actual, expected)
+	}
+	for input, expected := range map[string]interface{}{
+		"#+DATE: 2024-03-01\n": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"#+DATE: March\n":      "March",
+	} {
+		if actual := New().Silent().Parse(strings.NewReader(input), "./").FrontMatter()["date"]; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%q: got date %#v, expected %#v", input, actual, expected)
+		}
+	}
+}
+
+func TestInlineBlocks(t *testing.T) {
+	tests := map[string][]Node{
+		"src_python{print(1)} and src_go{f()}": {
+			InlineBlock{"src", []string{"python"}, []Node{Text{"print(1)", true}}},
+			Text{" and ", false},
+			InlineBlock{"src", []string{"go"}, []Node{Text{"f()", true}}},
+		},
+		"src_go[:exports code]{f({a: {}})}": {InlineBlock{"src", []string{"go", ":exports", "code"}, []Node{Text{"f({a: {}})", true}}}},
+		"src_sh{echo {unclosed}":            {Text{"src_sh{echo {unclosed}", false}},
+	}
+	for input, expected := range tests {
+		d := New().Silent().Parse(strings.NewReader(input), "./inline_blocks.org")
+		actual := d.Nodes[0].(Paragraph).Children
+		if fmt.Sprintf("%#v", actual) != fmt.Sprintf("%#v", expected) {
+			t.Errorf("%q:\n got %#v\n expected %#v", input, actual, expected)
+		}
+		if out := strings.TrimSpace(String(d.Nodes)); out != input {
+			t.Errorf("%q: expected round trip, got %q", input, out)
+		}
+	}
+}
+
+func TestUpdateStatistics(t *testing.T) {
+	input := `* Project [/] [%]
+** DONE a
+** TODO b [/]
+- [X] one
+- [ ] two [%]
+  - [X] nested
+- plain
+** no keyword
+* Checkboxes [1/9]
+- [X] x
+- [-] y
+* Recursive [/]
+:PROPERTIES:
+:COOKIE_DATA: todo recursive
+:END:
+** TODO c
+*** DONE d
+* Empty [/] [%]
+`
+	expected := `* Project [1/2] [50%]
+** DONE a
+** TODO b [1/2]
+- [X] one
+- [ ] two [100%]
+  - [X] nested
+- plain
+** no keyword
+* Checkboxes [1/2]
+- [X] x
+- [-] y
+* Recursive [1/2]
+:PROPERTIES:
+:COOKIE_DATA: todo recursive
+:END:
+** TODO c
+*** DONE d
+* Empty [0/0] [0%]
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./statistics.org").UpdateStatistics()
+	if actual := String(d.Nodes); actual != expected {
+		t.Errorf("bad statistics:\n%s", diff(actual, expected))
+	}
+	if actual := String(d.Outline.Children[0].Headline.Title); actual != "Project [1/2] [50%]" {
+		t.Errorf("outline was not updated: %q", actual)
+	}
+	html, err := RenderHTML("* a [/]\n- [X] b\n", WithUpdateStatistics(), WithSilent())
+	if err != nil || !strings.Contains(html, `<code class="statistic statistic-cookie">[1/1]</code>`) {
+		t.Errorf("unexpected html: %q (%v)", html, err)
+	}
+}
+
+func TestHeadlineProperties(t *testing.T) {
+	input := `* a
+:PROPERTIES:
+:CUSTOM_ID: a
+:Owner: alice
+:CATEGORY: work
+:END:
+** b
+:PROPERTIES:
+:owner: bob
+:END:
+*** c
+* d
+`
+	d := New().Silent().Parse(strings.NewReader(input), "./properties.org")
+	c := d.Outline.Children[0].Children[0].Children[0].Headline
+	properties := d.HeadlineProperties(*c)
+	if v, ok := properties.Get("Owner"); !ok || v != "bob" {
+		t.Errorf("expected closest owner bob, got %q (%v)", v, ok)
+	}
+	if v, ok := properties.Get("category"); !ok || v != "work" {
+		t.Errorf("expected inherited category work, got %q (%v)", v, ok)
+	}
+	if v, ok := properties.Get("CUSTOM_ID"); ok {
+		t.Errorf("expected CUSTOM_ID not to be inherited, got %q", v)
+	}
+	a := d.Nodes[0].(Headline)
+	if v, ok := d.HeadlineProperties(a).Get("custom_id"); !ok || v != "a" {
+		t.Errorf("expected own CUSTOM_ID a, got %q (%v)", v, ok)
+	}
+	if v, ok := a.Properties.Get("owner"); !ok || v != "alice" {
+		t.Errorf("expected case-insensitive drawer lookup, got %q (%v)", v, ok)
+	}
+	if properties := d.HeadlineProperties(*d.Outline.Children[1].Headline); len(properties) != 0 {
+		t.Errorf("expected no properties, got %v", properties)
+	}
+}
+
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n += len(p); w.n > 10 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestStream(t *testing.T) {
+	writers := map[string]func() Writer{
+		"org":  func() Writer { return NewOrgWriter() },
+		"html": func() Writer { return NewHTMLWriter() },
+		"md":   func() Writer { return NewMarkdownWriter() },
+		"txt":  func() Writer { return NewPlainTextWriter() },
+	}
+	for _, path := range orgTestFiles() {
+		for name, newWriter := range writers {
+			d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+			expected, err := d.Write(newWriter())
+			if err != nil {
+				t.Fatalf("%s: %s", path, err)
+			}
+			out := &strings.Builder{}
+			if n, err := d.Stream(out, newWriter()); err != nil || out.String() != expected || n != int64(len(expected)) {
+				t.Errorf("%s (%s): %d %v\n%s", path, name, n, err, diff(out.String(), expected))
+			}
+		}
+	}
+	d := New().Silent().Parse(strings.NewReader("* a\n* b\n* c\n"), "./stream.org")
+	if _, err := d.Stream(&failingWriter{}, NewOrgWriter()); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected write error, got %v", err)
+	}
+}
+
+func BenchmarkStream(b *testing.B) {
+	input := strings.Repeat("* headline\nsome /text/ with a [[https://example.com][link]].\n\n| a | b |\n|---+---|\n| 1 | 2 |\n", 1000)
+	d := New().Silent().Parse(strings.NewReader(input), "./stream.org")
+	b.Run("write", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			out, err := d.Write(NewHTMLWriter())
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.WriteString(ioutil.Discard, out)
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.Stream(ioutil.Discard, NewHTMLWriter()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestRestructureHeadlines(t *testing.T) {
+	input := "intro\n* B\nb\n** [#B] y\n** [#A] x\n* A\n* C\n"
+	parse := func() *Document { return New().Silent().Parse(strings.NewReader(input), "./restructure.org") }
+	roundTrip := func(name string, d *Document, expected string) {
+		if actual := String(d.Nodes); actual != expected {
+			t.Errorf("%s:\n%s", name, diff(actual, expected))
+		} else if reparsed := New().Silent().Parse(strings.NewReader(actual), "./restructure.org"); String(reparsed.Nodes) != actual || !reflect.DeepEqual(outlineTitles(reparsed.Outline.Section), outlineTitles(d.Outline.Section)) {
+			t.Errorf("%s: expected re-parsing to yield the same structure: %v vs %v", name, outlineTitles(reparsed.Outline.Section), outlineTitles(d.Outline.Section))
+		}
+	}
+	d := parse().SortHeadlines(func(a, b Headline) bool {
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return String(a.Title) < String(b.Title)
+	})
+	roundTrip("sort", d, "intro\n* A\n* B\nb\n** [#A] x\n** [#B] y\n* C\n")
+
+	d = parse()
+	if d.PromoteSubtree(*d.Outline.Children[0].Headline) {
+		t.Errorf("expected level 1 headline not to be promoted")
+	}
+	roundTrip("promote level 1", d, input)
+	if !d.PromoteSubtree(*d.Outline.Children[0].Children[0].Headline) {
+		t.Errorf("expected y to be promoted")
+	}
+	roundTrip("promote", d, "intro\n* B\nb\n* [#B] y\n** [#A] x\n* A\n* C\n")
+
+	d = parse()
+	if !d.DemoteSubtree(*d.Outline.Children[0].Headline) || !d.DemoteSubtree(*d.Outline.Children[1].Headline) {
+		t.Errorf("expected B & A to be demoted")
+	}
+	roundTrip("demote", d, "intro\n** B\nb\n*** [#B] y\n*** [#A] x\n** A\n* C\n")
+	if d.DemoteSubtree(Headline{Index: 42}) {
+		t.Errorf("expected unknown headline not to be demoted")
+	}
+}
+
+func outlineTitles(s *Section) []string {
+	titles := []string{}
+	for _, c := range s.Children {
+		titles = append(titles, String(c.Headline.Title)+"("+strings.Join(outlineTitles(c), " ")+")")
+	}
+	return titles
+}
+
+func TestMarshalDocument(t *testing.T) {
+	for _, path := range orgTestFiles() {
+		d := New().Silent().Parse(strings.NewReader(fileString(t, path)), path)
+		bs, err := MarshalDocument(d)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		reloaded, err := UnmarshalDocument(bs)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		for _, w := range []func() Writer{func() Writer { return NewOrgWriter() }, func() Writer { return NewHTMLWriter() }, func() Writer { return NewMarkdownWriter() }} {
+			expected, _ := d.Write(w())
+			actual, err := reloaded.Write(w())
+			if err != nil || actual != expected {
+				t.Errorf("%s: bad JSON round trip (%v):\n%s", path, err, diff(actual, expected))
+			}
+		}
+	}
+	if bs, err := MarshalDocument(New().Silent().Parse(strings.NewReader("* a\n"), "")); err != nil || !strings.Contains(string(bs), `"Title":[{"Content":"a","IsRaw":false,"type":"Text"}]`) {
+		t.Errorf("expected nodes with type discriminator: %v %s", err, bs)
+	}
+	if _, err := UnmarshalDocument([]byte(`{"Nodes":[{"type":"Custom"}]}`)); err == nil {
+		t.Errorf("expected error for unknown node type")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	d := New().Silent().Parse(strings.NewReader("* [[https://a][a]]\n- /[[https://b]]/\n| [[https://c]] |\nsee[fn::[[https://e]]]\n* skipped\n[[https://d]]\n"), "")
+	urls := []string{}
+	d.Walk(func(n Node) bool {
+		if l, ok := n.(RegularLink); ok {
+			urls = append(urls, l.URL)
+		}
+		h, ok := n.(Headline)
+		return !ok || String(h.Title) != "skipped"
+	})
+	if actual, expected := strings.Join(urls, " "), "https://a https://b https://c https://e"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	input := "* a\ntext\n  and an [[unclosed link\n:LOGBOOK:\nno end\n#+BEGIN_SRC go\nfunc main() {}\n"
+	conf := New().Silent()
+	if d := conf.Parse(strings.NewReader(input), "./errors.org"); d.Errors != nil {
+		t.Errorf("expected no errors to be collected by default: %v", d.Errors)
+	}
+	conf.CollectErrors = true
+	d := conf.Parse(strings.NewReader(input), "./errors.org")
+	expected := []ParseError{
+		{3, 10, "[[ without closing ]]"},
+		{4, 1, ":LOGBOOK: drawer without :END:"},
+		{6, 1, "#+BEGIN_SRC without #+END_SRC"},
+	}
+	if !reflect.DeepEqual(d.Errors, expected) {
+		t.Errorf("expected %v, got %v", expected, d.Errors)
+	} else if actual, expected := d.Errors[2].Error(), "6:1: #+BEGIN_SRC without #+END_SRC"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if out, err := d.Write(NewHTMLWriter()); err != nil || !strings.Contains(out, "#+BEGIN_SRC go") || len(d.Errors) != 3 {
+		t.Errorf("expected lenient output and no errors from writing: %v %v %s", err, d.Errors, out)
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	input := strings.Join([]string{
+		"#+TITLE: not counted",
+		"* Über die /schöne/ Welt",
+		":PROPERTIES:",
+		":ID: not-counted",
+		":END:",
+		"Some prose - with [[https://example.com][a link]] and a footnote[fn:: counted once].",
+		"#+BEGIN_SRC go",
+		"func main() { fmt.Println(\"not counted\") }",
+		"#+END_SRC",
+		"| one  | two words |",
+		"|------+-----------|",
+		"| 3    | 日本語    |",
+		"- a list item",
+		"* skipped :noexport:",
+		"not counted",
+	}, "\n")
+	d := New().Silent().Parse(strings.NewReader(input), "")
+	if actual, expected := d.WordCount(), 4+10+5+3; actual != expected {
+		t.Errorf("expected %d words, got %d", expected, actual)
+	}
+	if actual, expected := d.ReadingTime(22), time.Minute; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	} else if actual, expected := d.ReadingTime(0), 22*time.Minute/DefaultWordsPerMinute; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
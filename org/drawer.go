@@ -1,8 +1,10 @@
 package org
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 type Drawer struct {
@@ -12,6 +14,8 @@ type Drawer struct {
 
 type PropertyDrawer struct {
 	Properties [][]string
+
+	separators map[string]string // separators contains the whitespace between the key and value of the parsed properties.
 }
 
 var beginDrawerRegexp = regexp.MustCompile(`^(\s*):(\S+):\s*$`)
@@ -55,12 +59,14 @@ func (d *Document) parseDrawer(i int, parentStop stopFn) (int, Node) {
 	}
 	if i < len(d.tokens) && d.tokens[i].kind == "endDrawer" {
 		i++
+	} else {
+		d.addError(start, d.tokens[start].lvl+1, fmt.Sprintf(":%s: drawer without :END:", d.tokens[start].content))
 	}
 	return i - start, drawer
 }
 
 func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
-	drawer, start := PropertyDrawer{}, i
+	drawer, start := PropertyDrawer{separators: map[string]string{}}, i
 	i++
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) || (d.tokens[i].kind != "text" && d.tokens[i].kind != "beginDrawer")
@@ -72,6 +78,7 @@ func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
 		}
 		k, v := strings.ToUpper(m[2]), strings.TrimSpace(m[4])
 		drawer.Properties = append(drawer.Properties, []string{k, v})
+		drawer.separators[k] = m[3][:len(m[3])-len(strings.TrimLeftFunc(m[3], unicode.IsSpace))]
 	}
 	if i < len(d.tokens) && d.tokens[i].kind == "endDrawer" {
 		i++
@@ -81,17 +88,57 @@ func (d *Document) parsePropertyDrawer(i int, parentStop stopFn) (int, Node) {
 	return i - start, drawer
 }
 
+// Get returns the value of the property key - keys are matched case-insensitively.
 func (d *PropertyDrawer) Get(key string) (string, bool) {
 	if d == nil {
 		return "", false
 	}
 	for _, kvPair := range d.Properties {
-		if kvPair[0] == key {
+		if strings.EqualFold(kvPair[0], key) {
 			return kvPair[1], true
 		}
 	}
 	return "", false
 }
 
+// Properties are the properties of a headline including the ones inherited from its ancestors (see Document.HeadlineProperties).
+// Keys are upper case.
+type Properties map[string]string
+
+// NonInheritedProperties are the properties that only apply to the headline defining them.
+var NonInheritedProperties = map[string]bool{"ID": true, "CUSTOM_ID": true, "EXPORT_FILE_NAME": true, "COOKIE_DATA": true}
+
+// Get returns the value of the property key - keys are matched case-insensitively.
+func (p Properties) Get(key string) (string, bool) {
+	v, ok := p[strings.ToUpper(key)]
+	return v, ok
+}
+
+// HeadlineProperties returns the properties of h merged with the properties inherited from its ancestors.
+// The value of the closest headline wins - NonInheritedProperties are only taken from h itself.
+func (d *Document) HeadlineProperties(h Headline) Properties {
+	properties := Properties{}
+	add := func(drawer *PropertyDrawer, inherited bool) {
+		if drawer == nil {
+			return
+		}
+		for _, kvPair := range drawer.Properties {
+			key := strings.ToUpper(kvPair[0])
+			if _, ok := properties[key]; !ok && !(inherited && NonInheritedProperties[key]) {
+				properties[key] = kvPair[1]
+			}
+		}
+	}
+	add(h.Properties, false)
+	if s := d.outlineIndex().sections[h.Index]; s != nil {
+		for s = s.Parent; s != nil; s = s.Parent {
+			if s.Headline != nil {
+				add(s.Headline.Properties, true)
+			}
+		}
+	}
+	return properties
+}
+
 func (n Drawer) String() string         { return orgWriter.WriteNodesAsString(n) }
 func (n PropertyDrawer) String() string { return orgWriter.WriteNodesAsString(n) }
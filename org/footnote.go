@@ -10,6 +10,13 @@ type FootnoteDefinition struct {
 	Inline   bool
 }
 
+// Footnotes contains the footnotes of a document - see Ordered.
+type Footnotes struct {
+	Definitions     map[string]*FootnoteDefinition
+	references      []FootnoteLink
+	definitionNames []string
+}
+
 var footnoteDefinitionRegexp = regexp.MustCompile(`^\[fn:([\w-]+)\](\s+(.+)|\s*$)`)
 
 func lexFootnoteDefinition(line string) (token, bool) {
@@ -29,7 +36,52 @@ func (d *Document) parseFootnoteDefinition(i int, parentStop stopFn) (int, Node)
 	}
 	consumed, nodes := d.parseMany(i, stop)
 	definition := FootnoteDefinition{name, nodes, false}
+	d.Footnotes.addDefinition(&definition)
 	return consumed, definition
 }
 
+// Ordered returns the definitions of all referenced footnotes in order of their first reference (like Emacs).
+// If inDefinitionOrder is true, footnotes are returned in the order they are defined in instead.
+// Referenced footnotes without a definition are returned as nil to keep the numbering of the other footnotes stable.
+func (fs *Footnotes) Ordered(inDefinitionOrder bool) []*FootnoteDefinition {
+	definitions, added := []*FootnoteDefinition{}, map[string]bool{}
+	if inDefinitionOrder {
+		referenced := map[string]bool{}
+		for _, l := range fs.references {
+			referenced[l.Name] = true
+		}
+		for _, name := range fs.definitionNames {
+			if referenced[name] && !added[name] {
+				definitions, added[name] = append(definitions, fs.Definitions[name]), true
+			}
+		}
+	}
+	for _, l := range fs.references {
+		if l.Name == "" {
+			definitions = append(definitions, l.Definition)
+		} else if !added[l.Name] {
+			definition := fs.Definitions[l.Name]
+			if definition == nil {
+				definition = l.Definition
+			}
+			definitions, added[l.Name] = append(definitions, definition), true
+		}
+	}
+	return definitions
+}
+
+func (fs *Footnotes) addReference(l FootnoteLink) {
+	fs.references = append(fs.references, l)
+	if l.Name != "" && l.Definition != nil {
+		fs.addDefinition(l.Definition)
+	}
+}
+
+func (fs *Footnotes) addDefinition(f *FootnoteDefinition) {
+	if _, ok := fs.Definitions[f.Name]; !ok {
+		fs.definitionNames = append(fs.definitionNames, f.Name)
+	}
+	fs.Definitions[f.Name] = f
+}
+
 func (n FootnoteDefinition) String() string { return orgWriter.WriteNodesAsString(n) }
@@ -28,10 +28,24 @@ type Headline struct {
 	Title      []Node
 	Tags       []string
 	Children   []Node
+	Scheduled  *Timestamp // Scheduled, Deadline & Closed are parsed from the planning line (e.g. SCHEDULED: <2024-01-02 Tue>) of the headline.
+	Deadline   *Timestamp
+	Closed     *Timestamp
+}
+
+// TodoKeyword is a keyword defined via #+TODO, e.g. WAITING(w@).
+type TodoKeyword struct {
+	Name     string // Name is the keyword as used in headlines, e.g. WAITING.
+	Key      string // Key is the fast access key, e.g. w.
+	Logging  string // Logging is the logging annotation, e.g. @ or @/!.
+	Done     bool   // Done is true for keywords after the | of a sequence.
+	Sequence int    // Sequence is the index of the #+TODO line that defined the keyword.
 }
 
 var headlineRegexp = regexp.MustCompile(`^([*]+)\s+(.*)`)
+var todoKeywordRegexp = regexp.MustCompile(`^(.+?)\(([^!@/])?([!@/]*)\)$`)
 var tagRegexp = regexp.MustCompile(`(.*?)\s+(:[A-Za-z0-9_@#%:]+:\s*$)`)
+var planningKeywordRegexp = regexp.MustCompile(`^(SCHEDULED|DEADLINE|CLOSED):\s*`)
 
 func lexHeadline(line string) (token, bool) {
 	if m := headlineRegexp.FindStringSubmatch(line); m != nil {
@@ -47,13 +61,10 @@ func (d *Document) parseHeadline(i int, parentStop stopFn) (int, Node) {
 	headline.Index = d.addHeadline(&headline)
 
 	text := t.content
-	todoKeywords := trimFastTags(
-		strings.FieldsFunc(d.Get("TODO"), func(r rune) bool { return unicode.IsSpace(r) || r == '|' }),
-	)
-	for _, k := range todoKeywords {
-		if strings.HasPrefix(text, k) && len(text) > len(k) && unicode.IsSpace(rune(text[len(k)])) {
-			headline.Status = k
-			text = text[len(k)+1:]
+	for _, k := range d.TodoKeywords() {
+		if strings.HasPrefix(text, k.Name) && len(text) > len(k.Name) && unicode.IsSpace(rune(text[len(k.Name)])) {
+			headline.Status = k.Name
+			text = text[len(k.Name)+1:]
 			break
 		}
 	}
@@ -73,30 +84,161 @@ func (d *Document) parseHeadline(i int, parentStop stopFn) (int, Node) {
 	stop := func(d *Document, i int) bool {
 		return parentStop(d, i) || d.tokens[i].kind == "headline" && len(d.tokens[i].matches[1]) <= headline.Lvl
 	}
-	consumed, nodes := d.parseMany(i+1, stop)
-	if len(nodes) > 0 {
-		if d, ok := nodes[0].(PropertyDrawer); ok {
-			headline.Properties = &d
-			nodes = nodes[1:]
+	start := i
+	i = d.parsePlanningAndProperties(&headline, i+1, stop)
+	consumed, nodes := d.parseMany(i, stop)
+	headline.Children = nodes
+	return i - start + consumed, headline
+}
+
+// parsePlanningAndProperties attaches the planning line and property drawer following a headline (in any order,
+// optionally separated from the headline and each other by blank lines) to h and returns the index of the first child token.
+func (d *Document) parsePlanningAndProperties(h *Headline, i int, stop stopFn) int {
+	for j := i; j < len(d.tokens) && !stop(d, j); j++ {
+		t := d.tokens[j]
+		switch {
+		case t.kind == "text" && t.content == "":
+			continue
+		case t.kind == "text" && h.Scheduled == nil && h.Deadline == nil && h.Closed == nil && d.parsePlanning(h, t.content):
+			i = j + 1
+		case t.kind == "beginDrawer" && t.content == "PROPERTIES" && h.Properties == nil:
+			consumed, node := d.parsePropertyDrawer(j, stop)
+			if consumed == 0 {
+				return i
+			}
+			drawer := node.(PropertyDrawer)
+			h.Properties, i, j = &drawer, j+consumed, j+consumed-1
+		default:
+			return i
 		}
 	}
-	headline.Children = nodes
-	return consumed + 1, headline
+	return i
+}
+
+// parsePlanning sets the planning timestamps of h if line is a planning line, e.g. DEADLINE: <2024-01-05 Fri> SCHEDULED: <2024-01-02 Tue>.
+func (d *Document) parsePlanning(h *Headline, line string) bool {
+	planning := map[string]*Timestamp{}
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " \t") {
+		m := planningKeywordRegexp.FindStringSubmatch(line)
+		if m == nil || planning[m[1]] != nil {
+			return false
+		}
+		consumed, node := d.parseTimestamp(line, len(m[0]))
+		if consumed == 0 {
+			return false
+		}
+		timestamp := node.(Timestamp)
+		planning[m[1]], line = &timestamp, line[len(m[0])+consumed:]
+	}
+	if len(planning) == 0 {
+		return false
+	}
+	h.Scheduled, h.Deadline, h.Closed = planning["SCHEDULED"], planning["DEADLINE"], planning["CLOSED"]
+	return true
+}
+
+// planning returns the keywords & timestamps of the planning line of h in canonical order (like org-element).
+func (h Headline) planning() (keywords []string, timestamps []Timestamp) {
+	for _, p := range []struct {
+		keyword   string
+		timestamp *Timestamp
+	}{{"DEADLINE", h.Deadline}, {"SCHEDULED", h.Scheduled}, {"CLOSED", h.Closed}} {
+		if p.timestamp != nil {
+			keywords, timestamps = append(keywords, p.keyword), append(timestamps, *p.timestamp)
+		}
+	}
+	return keywords, timestamps
+}
+
+// planningLine returns the planning line of h - or an empty string if h has no planning.
+func (h Headline) planningLine() string {
+	keywords, timestamps := h.planning()
+	parts := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		parts[i] = keyword + ": " + timestamps[i].orgString()
+	}
+	return strings.Join(parts, " ")
+}
+
+// TodoKeywords returns the keywords of all #+TODO (and #+SEQ_TODO / #+TYP_TODO) sequences of the document.
+// Keywords after the | of a sequence (or the last keyword if there is no |) are done states.
+func (d *Document) TodoKeywords() []TodoKeyword {
+	keywords, sequences := []TodoKeyword{}, strings.Split(d.Get("TODO"), "\n")
+	for _, k := range []string{"SEQ_TODO", "TYP_TODO"} {
+		if v := d.BufferSettings[k]; v != "" {
+			sequences = append(sequences, strings.Split(v, "\n")...)
+		}
+	}
+	for i, sequence := range sequences {
+		fields, done := strings.Fields(sequence), false
+		if !strings.Contains(sequence, "|") && len(fields) != 0 {
+			fields = append(fields[:len(fields)-1:len(fields)-1], "|", fields[len(fields)-1])
+		}
+		for _, field := range fields {
+			for j, name := range strings.Split(field, "|") {
+				if j != 0 {
+					done = true
+				}
+				if name != "" {
+					keywords = append(keywords, parseTodoKeyword(name, done, i))
+				}
+			}
+		}
+	}
+	return keywords
+}
+
+// parseTodoKeyword parses keywords like WAITING(w@/!) into the name, fast access key and logging annotation.
+func parseTodoKeyword(s string, done bool, sequence int) TodoKeyword {
+	k := TodoKeyword{Name: s, Done: done, Sequence: sequence}
+	if m := todoKeywordRegexp.FindStringSubmatch(s); m != nil {
+		k.Name, k.Key, k.Logging = m[1], m[2], m[3]
+	}
+	return k
+}
+
+// SetStatus sets the todo keyword of h to status, which has to be one of the TodoKeywords of d.
+// An empty status removes the keyword. Headlines are values - callers have to put h back into the tree
+// (e.g. d.Nodes[i] = h) for writers to pick up the change.
+func (h *Headline) SetStatus(d *Document, status string) error {
+	if status != "" {
+		if _, ok := findTodoKeyword(d.TodoKeywords(), status); !ok {
+			return fmt.Errorf("unknown todo keyword: %q", status)
+		}
+	}
+	h.Status = status
+	return nil
+}
+
+// CycleStatus advances the todo keyword of h to the next keyword of its sequence like org-todo does:
+// no keyword -> first keyword of the first sequence and last keyword of a sequence -> no keyword.
+func (h *Headline) CycleStatus(d *Document) error {
+	keywords := d.TodoKeywords()
+	if h.Status == "" {
+		if len(keywords) != 0 {
+			h.Status = keywords[0].Name
+		}
+		return nil
+	}
+	i, ok := findTodoKeyword(keywords, h.Status)
+	if !ok {
+		return fmt.Errorf("unknown todo keyword: %q", h.Status)
+	}
+	if i+1 < len(keywords) && keywords[i+1].Sequence == keywords[i].Sequence {
+		h.Status = keywords[i+1].Name
+	} else {
+		h.Status = ""
+	}
+	return nil
 }
 
-func trimFastTags(tags []string) []string {
-	trimmedTags := make([]string, len(tags))
-	for i, t := range tags {
-		lParen := strings.LastIndex(t, "(")
-		rParen := strings.LastIndex(t, ")")
-		end := len(t) - 1
-		if lParen == end-2 && rParen == end {
-			trimmedTags[i] = t[:end-2]
-		} else {
-			trimmedTags[i] = t
+func findTodoKeyword(keywords []TodoKeyword, name string) (int, bool) {
+	for i, k := range keywords {
+		if k.Name == name {
+			return i, true
 		}
 	}
-	return trimmedTags
+	return -1, false
 }
 
 func (h Headline) ID() string {
@@ -106,10 +248,40 @@ func (h Headline) ID() string {
 	return fmt.Sprintf("headline-%d", h.Index)
 }
 
+// Slug returns a url safe representation of the title of h: lowercase, with accented latin letters
+// transliterated and all other runs of non word characters replaced by a single hyphen.
+// Slugs are deterministic but not unique - handling collisions is up to the caller.
+func (h Headline) Slug() string { return slugify(plainText(h.Title)) }
+
+// IsExcluded returns true if h is excluded from export: if it is commented, carries one of the EXCLUDE_TAGS or - in case any
+// headline of d carries one of the SELECT_TAGS - if neither h, its parents nor its children carry one of them.
+// Writers skip the subtrees of excluded headlines, i.e. the children of excluded headlines are excluded as well.
 func (h Headline) IsExcluded(d *Document) bool {
-	for _, excludedTag := range strings.Fields(d.Get("EXCLUDE_TAGS")) {
+	if h.IsCommented() || h.hasTag(strings.Fields(d.Get("EXCLUDE_TAGS"))) {
+		return true
+	}
+	o := d.outlineIndex()
+	if o.selected == nil || h.hasTag(strings.Fields(o.selectTags)) {
+		return false
+	} else if _, ok := o.sections[h.Index]; !ok {
+		return false
+	}
+	return !o.selected[h.Index]
+}
+
+// IsCommented returns true if the title of h starts with the COMMENT keyword - commented subtrees are not exported.
+func (h Headline) IsCommented() bool {
+	if len(h.Title) == 0 {
+		return false
+	}
+	t, ok := h.Title[0].(Text)
+	return ok && (t.Content == "COMMENT" || strings.HasPrefix(t.Content, "COMMENT "))
+}
+
+func (h Headline) hasTag(tags []string) bool {
+	for _, t := range tags {
 		for _, tag := range h.Tags {
-			if tag == excludedTag {
+			if tag == t {
 				return true
 			}
 		}
@@ -117,6 +289,58 @@ func (h Headline) IsExcluded(d *Document) bool {
 	return false
 }
 
+// isExcluded returns true if the headline of s or of any of its parents is excluded from export.
+func (s *Section) isExcluded(d *Document) bool {
+	for ; s != nil; s = s.Parent {
+		if s.Headline != nil && s.Headline.IsExcluded(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// outlineIndex contains lookups of the headlines in the Outline by their Index (see Document.indexOutline).
+type outlineIndex struct {
+	sections   map[int]*Section
+	selectTags string
+	selected   map[int]bool // selected contains the headlines that carry one of the select tags, or whose parents or children do - nil if no headline does.
+}
+
+// indexOutline builds the outlineIndex of the current Outline - it has to be called whenever the outline changes.
+func (d *Document) indexOutline() { d.outline = newOutlineIndex(d) }
+
+// outlineIndex returns the outlineIndex of d. During parsing (or if SELECT_TAGS changed since) it is built on the fly.
+func (d *Document) outlineIndex() *outlineIndex {
+	if d.outline != nil && d.outline.selectTags == d.Get("SELECT_TAGS") {
+		return d.outline
+	}
+	return newOutlineIndex(d)
+}
+
+func newOutlineIndex(d *Document) *outlineIndex {
+	o := &outlineIndex{sections: map[int]*Section{}, selectTags: d.Get("SELECT_TAGS")}
+	selectTags, selected := strings.Fields(o.selectTags), map[int]bool{}
+	var index func(s *Section, parentSelected bool) bool
+	index = func(s *Section, parentSelected bool) bool {
+		isSelected := s.Headline != nil && s.Headline.hasTag(selectTags)
+		childSelected := false
+		for _, c := range s.Children {
+			childSelected = index(c, parentSelected || isSelected) || childSelected
+		}
+		if s.Headline != nil {
+			o.sections[s.Headline.Index] = s
+			if parentSelected || isSelected || childSelected {
+				selected[s.Headline.Index] = true
+			}
+		}
+		return isSelected || childSelected
+	}
+	if d.Outline.Section != nil && index(d.Outline.Section, false) && len(selectTags) != 0 {
+		o.selected = selected
+	}
+	return o
+}
+
 func (parent *Section) add(current *Section) {
 	if parent.Headline == nil || parent.Headline.Lvl < current.Headline.Lvl {
 		parent.Children = append(parent.Children, current)
@@ -127,3 +351,76 @@ func (parent *Section) add(current *Section) {
 }
 
 func (n Headline) String() string { return orgWriter.WriteNodesAsString(n) }
+
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// slugify lowercases s, transliterates accented latin letters and joins the remaining words with hyphens.
+// Letters without transliteration (e.g. CJK) are kept as is.
+func slugify(s string) string {
+	words, word := []string{}, strings.Builder{}
+	for _, r := range strings.ToLower(s) {
+		if t, ok := transliterations[r]; ok {
+			word.WriteString(t)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+		} else if word.Len() != 0 {
+			words, word = append(words, word.String()), strings.Builder{}
+		}
+	}
+	if word.Len() != 0 {
+		words = append(words, word.String())
+	}
+	return strings.Join(words, "-")
+}
+
+// githubSlugify implements the anchor algorithm of GitHub (& GitLab) markdown headings: s is lowercased,
+// everything but letters, numbers, underscores, hyphens & spaces is removed and each space is replaced by a hyphen.
+func githubSlugify(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r) || unicode.In(r, unicode.Pc):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// plainText returns the text of nodes without markup - e.g. the description (or url) of links.
+func plainText(nodes []Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case Text:
+			b.WriteString(n.Content)
+		case Emphasis:
+			b.WriteString(plainText(n.Content))
+		case RegularLink:
+			if n.Description != nil {
+				b.WriteString(plainText(n.Description))
+			} else {
+				b.WriteString(n.URL)
+			}
+		case RadioTarget:
+			b.WriteString(n.Name)
+		case Target:
+		case LineBreak, ExplicitLineBreak:
+			b.WriteString(" ")
+		default:
+			b.WriteString(n.String())
+		}
+	}
+	return b.String()
+}
@@ -1,30 +1,29 @@
 package org
 
 import (
-	"sort"
+	"html"
 	"strings"
 )
 
-var htmlEntityReplacer *strings.Replacer
+// specialStringReplacer replaces the special strings ---, -- and ... with their typographic counterparts.
+// Entities (e.g. \alpha) are parsed into Entity nodes instead (see Document.parseEntity).
+var specialStringReplacer = strings.NewReplacer("---", "—", "--", "–", "...", "…")
+
+// entities maps the names of entities (e.g. alpha for \alpha) to their utf8 representation.
+var entities = map[string]string{}
 
 func init() {
-	htmlEntities = append(htmlEntities,
-		[]string{"---", "—"},
-		[]string{"--", "–"},
-		[]string{"...", "…"},
-	)
-	// replace longer entities first to prevent partial replacements (e.g. \angle as \ang + le)
-	sort.Slice(htmlEntities, func(i, j int) bool {
-		return len(htmlEntities[i][0]) > len(htmlEntities[j][0])
-	})
-	xs := make([]string, len(htmlEntities)*2)
 	for _, kv := range htmlEntities {
-		// replace both \<entity> and \<entity>{}
-		xs = append(xs, kv[0]+"{}", kv[1], kv[0], kv[1])
+		entities[kv[0][1:]] = kv[1]
 	}
-	htmlEntityReplacer = strings.NewReplacer(xs...)
 }
 
+// UTF8 returns the utf8 representation of the entity, e.g. α for \alpha.
+func (e Entity) UTF8() string { return entities[e.Name] }
+
+// HTML returns the html representation of the entity - the escaped utf8 representation and &nbsp; for \nbsp.
+func (e Entity) HTML() string { return nbspReplacer.Replace(html.EscapeString(e.UTF8())) }
+
 /*
 Generated & copied over using the following elisp
 (Setting up go generate seems like a waste for now - I call YAGNI on that one)
@@ -1,16 +1,23 @@
 package org
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"html"
+	"io/fs"
 	"log"
+	"mime"
+	"net/url"
+	"path"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode"
-
-	u "net/url"
+	"unicode/utf8"
 
 	h "golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -18,23 +25,93 @@ import (
 
 // HTMLWriter exports an org document into a html document.
 type HTMLWriter struct {
-	ExtendingWriter     Writer
-	HighlightCodeBlock  func(source, lang string, inline bool, params map[string]string) string
-	PrettyRelativeLinks bool
+	ExtendingWriter            Writer
+	HighlightCodeBlock         func(source, lang string, inline bool, params map[string]string) string // HighlightCodeBlock renders the source of src blocks, e.g. via chroma. Defaults to DefaultHighlightCodeBlock.
+	PrettyRelativeLinks        bool
+	BaseURL                    string                                // Resolve relative links (e.g. ./notes.org or img/pic.png) against this url, e.g. to publish to a subpath - #fragments & ::search options are kept. Disabled if empty.
+	LineNumberAllCode          bool                                  // Number the lines of all src & example blocks - unless disabled via the header argument :number-lines nil.
+	NodeRenderHook             func(w Writer, n Node) (string, bool) // NodeRenderHook renders nodes unknown to the writer (e.g. from custom InlineParsers).
+	TableFigureCaptions        bool                                  // Render table captions as a <figcaption> below the table rather than a <caption> inside it.
+	PrettifyText               bool                                  // Replace ASCII arrows (->, =>, <-) and straight quotes (see #+OPTIONS: ':t) with their typographic counterparts in plain text.
+	FootnotesInDefinitionOrder bool                                  // Number footnotes in order of their definitions rather than their first reference.
+	AttachmentResolver         func(headlineID, path string) string  // Map [[attachment:path]] links to urls. headlineID is the ID (or DIR) property of the closest headline defining one.
+	RenderCache                RenderCache                           // Memoize the html of headline subtrees (e.g. for live previews that re-render on every change).
+	LanguageAliases            map[string]string                     // Map src block languages to the language passed to HighlightCodeBlock (e.g. elisp -> lisp). The css class keeps the original language.
+	TableColSpanMarker         string                                // Merge table cells consisting only of this marker (e.g. <) into the cell to their left (see Table.Spans). Disabled if empty.
+	TableRowSpanMarker         string                                // Merge table cells consisting only of this marker (e.g. ^) into the cell above them (see Table.Spans). Disabled if empty.
+	FoldSrcBlockLines          int                                   // Wrap src blocks with more lines in a collapsible <details> - :hidden t / :hidden nil fold / unfold individual blocks. Disabled if 0.
+	StatisticProgress          bool                                  // Render a <progress> after statistics cookies ([/] or [%]) of list items & headlines with checkbox children - the cookie shows the actual counts.
+	QuoteAttribution           string                                // Render a trailing attribution line of quote blocks (starting with -- or —) in this tag, e.g. footer or cite. Disabled if empty.
+	NumberCaptions             bool                                  // Prefix captions with Figure N: / Table N: (numbered separately) and render [[name]] links to named, captioned nodes as Figure N.
+	TabReplacement             string                                // Replace tabs in prose with this (unescaped) html, e.g. 4 spaces or &#9;. Code (src & example blocks, ~code~, =verbatim=) keeps its tabs. Disabled if empty.
+	DiagramLanguages           []string                              // Render src blocks of these languages (e.g. mermaid) as <pre class="lang"> containing the raw source for client side rendering.
+	SlugHeadlineIDs            bool                                  // Use the slug of the title (suffixed with -1, -2, ... if taken) rather than headline-N as id of headlines without a CUSTOM_ID.
+	SlugStyle                  string                                // SlugStyle is the slug algorithm of SlugHeadlineIDs: default (Headline.Slug) or github (the heading anchors of GitHub & GitLab markdown).
+	HeadlineIDPrecedence       []string                              // HeadlineIDPrecedence are the sources tried in order for the id of headlines: ID & CUSTOM_ID (properties) and slug (see SlugStyle) - headline-N if none apply. Defaults to CUSTOM_ID followed by slug if SlugHeadlineIDs.
+	CheckboxInputs             bool                                  // Render a disabled <input type="checkbox"> at the start of checkbox list items - [-] is rendered with aria-checked="mixed".
+	TOCMaxLvl                  int                                   // Limit the table of contents to headlines up to this level unless toc:N or #+TOC: headlines N specify one. Disabled if 0.
+	TOCMinLvl                  int                                   // Leave out headlines below this level (e.g. a single level 1 document title) from the table of contents - their children are listed in their place. Disabled if 0.
+	VerseLineBreaks            bool                                  // Render verse blocks like Org mode as <p class="verse"> with a <br> per line and &#xa0; indentation rather than as paragraphs in a <div class="verse-block"> (to be styled with white-space: pre).
+	XHTML                      bool                                  // Self-close void elements (<br />, <hr />, <input />) and render boolean attributes as attr="attr" for XHTML embedding contexts.
+	TagLinkFormat              string                                // Render headline tags as links to this url format (e.g. /tags/%s - the tag is url escaped) and #+FILETAGS: below the title as <p class="filetags">. Disabled if empty.
+	HeadlineOffset             int                                   // Shift the <hN> (& outline-N classes) of headlines down by this many levels, e.g. to embed fragments in an existing page. Clamped at <h6>.
+	InlineImages               fs.FS                                 // Inline local images (file: & relative links, paths relative to the root of the fs) as base64 data: uris for self-contained html. Disabled if nil.
+	InlineImageMaxSize         int64                                 // Link rather than inline images larger than this many bytes (see InlineImages). Disabled if 0.
+	HorizontalRuleClass        string                                // Render horizontal rules as <hr class="..." data-dashes="N"> with this class and their number of dashes, e.g. to style rules of different lengths. Disabled if empty.
+	BlockRenderers             map[string]func(Block) string         // Render blocks by name (e.g. ALERT for #+BEGIN_ALERT, case-insensitive) - the returned html replaces the default rendering of the block including its results.
+	TimestampFormat            string                                // Render #+DATE: below the title as <p class="date"> - timestamp values (e.g. [2023-05-01]) are formatted with this Go time layout, free text as is. Disabled if empty.
+	TimestampClasses           bool                                  // Add a timestamp-active or timestamp-inactive class to the <span class="timestamp"> of <active> and [inactive] timestamps.
+	HideInactiveTimestamps     bool                                  // Do not export [inactive] timestamps - like #+OPTIONS: <:active.
+	AriaLabels                 bool                                  // Label the table of contents <nav> and the footnotes <div> with aria-labels translated for the #+LANGUAGE of the document (see Document.Translate).
+	EmphasisTags               map[string][]string                   // Map emphasis kinds (e.g. * or custom Configuration.EmphasisMarkers) to their opening & closing tags. Kinds without tags are written as is. Defaults to DefaultEmphasisTags.
 
 	strings.Builder
-	document   *Document
-	htmlEscape bool
-	log        *log.Logger
-	footnotes  *footnotes
+	document            *Document
+	htmlEscape          bool
+	log                 *log.Logger
+	footnotes           *footnotes
+	lastLineNumber      int
+	inLink              bool
+	headlines           []Headline
+	cachePrefix         string
+	radioTargets        []radioTarget
+	statistics          [][]Node
+	sectionNumbers      map[int]string
+	abbreviations       *regexp.Regexp
+	captionLabels       map[string]string
+	captionCounts       map[string]int
+	headlineIDs         map[int]string
+	links               *linkResolver
+	writtenIndexEntries map[string]bool
+	blockRenderers      map[string]func(Block) string
+}
+
+type radioTarget struct {
+	name   string
+	regexp *regexp.Regexp
 }
 
+// RenderCache stores the rendered html of headline subtrees by a hash of their content (see HTMLWriter.RenderCache).
+// Keys change whenever the subtree or the document settings change - there is no explicit invalidation.
+// Function fields of the writer (e.g. HighlightCodeBlock, BlockRenderers, NodeRenderHook) are not part of the keys:
+// use a new cache when changing them.
+type RenderCache interface {
+	Get(key string) (html string, ok bool)
+	Set(key, html string)
+}
+
+// footnotes numbers footnotes by their position in list - names[i] is the name of the i-th footnote (empty for anonymous ones).
+// mapping is only used for lookups, never iterated, so the numbering does not depend on map order.
 type footnotes struct {
 	mapping map[string]int
 	list    []*FootnoteDefinition
+	names   []string
+	parents map[int]int // parents[i] is the footnote whose definition first referenced footnote i
+	current int         // current is the footnote whose definition is being written plus one - 0 outside of definitions
 }
 
-var emphasisTags = map[string][]string{
+// DefaultEmphasisTags are the EmphasisTags of NewHTMLWriter.
+var DefaultEmphasisTags = map[string][]string{
 	"/":   []string{"<em>", "</em>"},
 	"*":   []string{"<strong>", "</strong>"},
 	"+":   []string{"<del>", "</del>"},
@@ -43,6 +120,8 @@ var emphasisTags = map[string][]string{
 	"_":   []string{`<span style="text-decoration: underline;">`, "</span>"},
 	"_{}": []string{"<sub>", "</sub>"},
 	"^{}": []string{"<sup>", "</sup>"},
+	"_x":  []string{"<sub>", "</sub>"},
+	"^x":  []string{"<sup>", "</sup>"},
 }
 
 var listTags = map[string][]string{
@@ -57,21 +136,46 @@ var listItemStatuses = map[string]string{
 	"X": "checked",
 }
 
+// DefaultLanguageAliases maps common org src block languages to the names used by highlighters (e.g. chroma lexers).
+var DefaultLanguageAliases = map[string]string{
+	"emacs-lisp": "lisp",
+	"elisp":      "lisp",
+	"sh":         "bash",
+	"shell":      "bash",
+	"zsh":        "bash",
+	"js":         "javascript",
+	"ts":         "typescript",
+	"py":         "python",
+}
+
+var nbspReplacer = strings.NewReplacer("\u00a0", "&nbsp;")
+var typographyReplacer = strings.NewReplacer("<->", "↔", "->", "→", "<-", "←", "=>", "⇒")
+
 var cleanHeadlineTitleForHTMLAnchorRegexp = regexp.MustCompile(`</?a[^>]*>`) // nested a tags are not valid HTML
+// DefaultHighlightCodeBlock is the HighlightCodeBlock of NewHTMLWriter. It does not highlight - source is html escaped
+// and wrapped in a <pre> (numbered according to :number-lines). It is also used if HighlightCodeBlock is nil.
+func DefaultHighlightCodeBlock(source, lang string, inline bool, params map[string]string) string {
+	if inline {
+		return fmt.Sprintf("<div class=\"highlight-inline\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
+	}
+	content := html.EscapeString(source)
+	if start, err := strconv.Atoi(params[":number-lines"]); err == nil {
+		content = numberLines(content, start)
+	}
+	return fmt.Sprintf("<div class=\"highlight\">\n<pre>\n%s\n</pre>\n</div>", content)
+}
+
 var tocHeadlineMaxLvlRegexp = regexp.MustCompile(`headlines\s+(\d+)`)
 
 func NewHTMLWriter() *HTMLWriter {
 	defaultConfig := New()
 	return &HTMLWriter{
-		document:   &Document{Configuration: defaultConfig},
-		log:        defaultConfig.Log,
-		htmlEscape: true,
-		HighlightCodeBlock: func(source, lang string, inline bool, params map[string]string) string {
-			if inline {
-				return fmt.Sprintf("<div class=\"highlight-inline\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
-			}
-			return fmt.Sprintf("<div class=\"highlight\">\n<pre>\n%s\n</pre>\n</div>", html.EscapeString(source))
-		},
+		document:           &Document{Configuration: defaultConfig},
+		log:                defaultConfig.Log,
+		htmlEscape:         true,
+		LanguageAliases:    DefaultLanguageAliases,
+		EmphasisTags:       DefaultEmphasisTags,
+		HighlightCodeBlock: DefaultHighlightCodeBlock,
 		footnotes: &footnotes{
 			mapping: map[string]int{},
 		},
@@ -81,10 +185,9 @@ func NewHTMLWriter() *HTMLWriter {
 func (w *HTMLWriter) WriteNodesAsString(nodes ...Node) string {
 	original := w.Builder
 	w.Builder = strings.Builder{}
+	defer func() { w.Builder = original }()
 	WriteNodes(w, nodes...)
-	out := w.String()
-	w.Builder = original
-	return out
+	return w.String()
 }
 
 func (w *HTMLWriter) WriterWithExtensions() Writer {
@@ -97,6 +200,49 @@ func (w *HTMLWriter) WriterWithExtensions() Writer {
 func (w *HTMLWriter) Before(d *Document) {
 	w.document = d
 	w.log = d.Log
+	w.cachePrefix = ""
+	w.radioTargets = nil
+	for _, name := range d.RadioTargets {
+		pattern := strings.Join(strings.Fields(regexp.QuoteMeta(name)), `\s+`)
+		w.radioTargets = append(w.radioTargets, radioTarget{name, regexp.MustCompile(`^(?i)` + pattern)})
+	}
+	sort.SliceStable(w.radioTargets, func(i, j int) bool { return len(w.radioTargets[i].name) > len(w.radioTargets[j].name) })
+	w.abbreviations = nil
+	if len(d.Abbreviations) != 0 {
+		abbreviations := []string{}
+		for abbreviation := range d.Abbreviations {
+			abbreviations = append(abbreviations, regexp.QuoteMeta(abbreviation))
+		}
+		sort.Slice(abbreviations, func(i, j int) bool {
+			if len(abbreviations[i]) != len(abbreviations[j]) {
+				return len(abbreviations[i]) > len(abbreviations[j])
+			}
+			return abbreviations[i] < abbreviations[j]
+		})
+		w.abbreviations = regexp.MustCompile(strings.Join(abbreviations, "|"))
+	}
+	w.sectionNumbers = w.numberSections(d)
+	w.links, w.writtenIndexEntries = newLinkResolver(d), map[string]bool{}
+	w.blockRenderers = w.normalizeBlockRenderers()
+	w.headlineIDs = nil
+	if precedence := w.HeadlineIDPrecedence; precedence != nil {
+		w.headlineIDs = headlineIDs(d, precedence, w.SlugStyle)
+	} else if w.SlugHeadlineIDs {
+		w.headlineIDs = headlineIDs(d, []string{"CUSTOM_ID", "slug"}, w.SlugStyle)
+	}
+	w.captionLabels, w.captionCounts = nil, map[string]int{}
+	if w.NumberCaptions {
+		w.captionLabels = w.labelCaptions(d, d.Nodes, map[string]int{}, map[string]string{})
+	}
+	if w.FootnotesInDefinitionOrder && d.Footnotes != nil {
+		for _, f := range d.Footnotes.Ordered(true) {
+			if f == nil || f.Name == "" {
+				break // undefined and anonymous footnotes follow the defined ones in order of reference
+			}
+			w.footnotes.mapping[f.Name] = len(w.footnotes.list)
+			w.footnotes.list, w.footnotes.names = append(w.footnotes.list, f), append(w.footnotes.names, f.Name)
+		}
+	}
 	if title := d.Get("TITLE"); title != "" && w.document.GetOption("title") != "nil" {
 		titleDocument := d.Parse(strings.NewReader(title), d.Path)
 		if titleDocument.Error == nil {
@@ -114,8 +260,20 @@ func (w *HTMLWriter) Before(d *Document) {
 		}
 		w.WriteString(fmt.Sprintf(`<h1 class="title">%s</h1>`+"\n", title))
 	}
+	if date := strings.TrimSpace(d.Get("DATE")); date != "" && w.TimestampFormat != "" && w.document.GetOption("date") != "nil" {
+		if t, ok := d.Date(); ok {
+			date = t.Time.Format(w.TimestampFormat)
+		}
+		w.WriteString(fmt.Sprintf(`<p class="date">%s</p>`+"\n", html.EscapeString(date)))
+	}
+	if tags := d.fileTags(); len(tags) != 0 && w.TagLinkFormat != "" && w.document.GetOption("tags") != "nil" {
+		w.WriteString(`<p class="filetags">` + w.tags(tags) + "</p>\n")
+	}
 	if w.document.GetOption("toc") != "nil" {
-		maxLvl, _ := strconv.Atoi(w.document.GetOption("toc"))
+		maxLvl, err := strconv.Atoi(w.document.GetOption("toc"))
+		if err != nil {
+			maxLvl = w.TOCMaxLvl
+		}
 		w.WriteOutline(d, maxLvl)
 	}
 }
@@ -124,31 +282,87 @@ func (w *HTMLWriter) After(d *Document) {
 	w.WriteFootnotes(d)
 }
 
+func (w *HTMLWriter) WriteCustomNode(n Node) bool {
+	if w.NodeRenderHook == nil {
+		return false
+	}
+	out, ok := w.NodeRenderHook(w.WriterWithExtensions(), n)
+	if ok {
+		w.WriteString(out)
+	}
+	return ok
+}
+
 func (w *HTMLWriter) WriteComment(Comment)               {}
 func (w *HTMLWriter) WritePropertyDrawer(PropertyDrawer) {}
 
 func (w *HTMLWriter) WriteBlock(b Block) {
-	content, params := w.blockContent(b.Name, b.Children), b.ParameterMap()
+	if render := w.blockRenderer(b.Name); render != nil {
+		w.WriteString(render(b))
+		return
+	}
+	children, attribution := b.Children, []Node(nil)
+	if b.Name == "QUOTE" && w.QuoteAttribution != "" {
+		if c, a, ok := splitQuoteAttribution(b.Children); ok {
+			children, attribution = c, a
+		}
+	}
+	content, params := "", b.ParameterMap()
+	if b.Name != "VERSE" || !w.VerseLineBreaks {
+		content = w.blockContent(b.Name, children)
+	}
 
 	switch b.Name {
 	case "SRC":
 		if params[":exports"] == "results" || params[":exports"] == "none" {
 			break
 		}
-		lang := "text"
-		if len(b.Parameters) >= 1 {
-			lang = strings.ToLower(b.Parameters[0])
+		lang := strings.ToLower(params[":lang"])
+		if w.isDiagramLanguage(lang) {
+			w.WriteString(fmt.Sprintf("<pre class=\"%s\">\n%s\n</pre>\n", html.EscapeString(lang), html.EscapeString(content)))
+			break
+		}
+		if start, ok := w.lineNumbers(b, content); ok {
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[":number-lines"] = strconv.Itoa(start)
+		}
+		summary, folded := w.srcBlockFolding(lang, content, params)
+		content = w.highlightCodeBlock(content, w.highlightLanguage(lang), false, params)
+		if folded {
+			w.WriteString(`<details class="src-details">` + "\n" + fmt.Sprintf("<summary>%s</summary>", html.EscapeString(summary)) + "\n")
+		}
+		if lang == "" {
+			w.WriteString(fmt.Sprintf("<div class=\"src\">\n%s\n</div>\n", content))
+		} else {
+			w.WriteString(fmt.Sprintf("<div class=\"src src-%s\">\n%s\n</div>\n", lang, content))
+		}
+		if folded {
+			w.WriteString("</details>\n")
 		}
-		content = w.HighlightCodeBlock(content, lang, false, params)
-		w.WriteString(fmt.Sprintf("<div class=\"src src-%s\">\n%s\n</div>\n", lang, content))
 	case "EXAMPLE":
-		w.WriteString(`<pre class="example">` + "\n" + html.EscapeString(content) + "\n</pre>\n")
+		content = html.EscapeString(content)
+		if start, ok := w.lineNumbers(b, content); ok {
+			content = numberLines(content, start)
+		}
+		w.WriteString(`<pre class="example">` + "\n" + content + "\n</pre>\n")
 	case "EXPORT":
 		if len(b.Parameters) >= 1 && strings.ToLower(b.Parameters[0]) == "html" {
 			w.WriteString(content + "\n")
 		}
+	case "COMMENT": // comment blocks are not exported
 	case "QUOTE":
+		if attribution != nil {
+			content += fmt.Sprintf("<%s>%s</%s>\n", w.QuoteAttribution, w.WriteNodesAsString(attribution...), w.QuoteAttribution)
+		}
 		w.WriteString("<blockquote>\n" + content + "</blockquote>\n")
+	case "VERSE":
+		if !w.VerseLineBreaks {
+			w.WriteString(`<div class="verse-block">` + "\n" + content + "</div>\n")
+			break
+		}
+		w.WriteString(`<p class="verse">` + "\n" + w.verseLines(b.Children) + "</p>\n")
 	case "CENTER":
 		w.WriteString(`<div class="center-block" style="text-align: center; margin-left: auto; margin-right: auto;">` + "\n")
 		w.WriteString(content + "</div>\n")
@@ -157,19 +371,174 @@ func (w *HTMLWriter) WriteBlock(b Block) {
 		w.WriteString(content + "</div>\n")
 	}
 
-	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" {
-		WriteNodes(w, b.Result)
+	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" && !hasResultsParameter(params, "silent") {
+		if file, ok := resultFile(params); ok {
+			WriteNodes(w, Paragraph{[]Node{RegularLink{"file", nil, "file:" + file, false}}})
+		} else if hasResultsParameter(params, "html") || hasResultsParameter(params, "raw") {
+			w.writeRawResult(b.Result)
+		} else {
+			WriteNodes(w, b.Result)
+		}
 	}
 }
 
+// verseLines returns the lines of a verse block - inline markup is rendered, lines end with a <br> and leading spaces are kept as &#xa0;.
+func (w *HTMLWriter) verseLines(children []Node) string {
+	source := strings.TrimRight(orgWriter.WriteNodesAsString(children...), "\n")
+	out := ""
+	for _, line := range strings.Split(w.WriteNodesAsString(w.document.parseInline(source)...), "\n") {
+		content := strings.TrimLeft(line, " ")
+		out += strings.Repeat("&#xa0;", len(line)-len(content)) + content + w.voidElement("<br>") + "\n"
+	}
+	return out
+}
+
+// blockRenderer returns the BlockRenderers entry for name - names are matched case-insensitively, e.g. alert matches #+BEGIN_ALERT.
+func (w *HTMLWriter) blockRenderer(name string) func(Block) string {
+	return w.blockRenderers[strings.ToUpper(name)]
+}
+
+// normalizeBlockRenderers returns the BlockRenderers by upper case name. Keys that only differ in case are resolved
+// deterministically: upper case keys win, others are applied in sorted order.
+func (w *HTMLWriter) normalizeBlockRenderers() map[string]func(Block) string {
+	keys, renderers := []string{}, map[string]func(Block) string{}
+	for key := range w.BlockRenderers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := w.BlockRenderers[strings.ToUpper(key)]; !ok || key == strings.ToUpper(key) {
+			renderers[strings.ToUpper(key)] = w.BlockRenderers[key]
+		}
+	}
+	return renderers
+}
+
+// srcBlockFolding returns whether a src block is wrapped in a collapsible <details> and the text of its <summary>.
+// Blocks are folded via :hidden (e.g. :hidden t or :hidden "Show solution") or if they are longer than FoldSrcBlockLines.
+func (w *HTMLWriter) srcBlockFolding(lang, content string, params map[string]string) (string, bool) {
+	summary := lang
+	if summary == "" {
+		summary = "src"
+	}
+	switch hidden, ok := params[":hidden"]; {
+	case hidden == "nil":
+		return "", false
+	case ok && hidden != "" && hidden != "t":
+		return strings.Trim(hidden, `"`), true
+	case ok:
+		return summary, true
+	}
+	return summary, w.FoldSrcBlockLines > 0 && strings.Count(content, "\n")+1 > w.FoldSrcBlockLines
+}
+
+// splitQuoteAttribution splits the trailing attribution line (e.g. -- Author) from the last paragraph of a quote.
+// The returned attribution does not contain the leading dash.
+func splitQuoteAttribution(children []Node) ([]Node, []Node, bool) {
+	if len(children) == 0 {
+		return nil, nil, false
+	}
+	p, ok := children[len(children)-1].(Paragraph)
+	if !ok {
+		return nil, nil, false
+	}
+	i := len(p.Children) - 1
+	for ; i >= 0; i-- {
+		if _, ok := p.Children[i].(LineBreak); ok {
+			break
+		}
+	}
+	line := p.Children[i+1:]
+	if len(line) == 0 {
+		return nil, nil, false
+	}
+	t, ok := line[0].(Text)
+	if !ok || t.IsRaw {
+		return nil, nil, false
+	}
+	content := strings.TrimLeft(t.Content, " ")
+	for _, dash := range []string{"--", "—"} {
+		if strings.HasPrefix(content, dash+" ") {
+			text := strings.TrimLeft(content[len(dash):], " ")
+			if len(line) == 1 {
+				text = strings.TrimSpace(text)
+			}
+			attribution := append([]Node{Text{text, false}}, line[1:]...)
+			quote := append([]Node{}, children[:len(children)-1]...)
+			if i > 0 {
+				quote = append(quote, Paragraph{p.Children[:i]})
+			}
+			return quote, attribution, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (w *HTMLWriter) isDiagramLanguage(lang string) bool {
+	for _, l := range w.DiagramLanguages {
+		if strings.ToLower(l) == lang {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *HTMLWriter) highlightCodeBlock(source, lang string, inline bool, params map[string]string) string {
+	if w.HighlightCodeBlock == nil {
+		return DefaultHighlightCodeBlock(source, lang, inline, params)
+	}
+	return w.HighlightCodeBlock(source, lang, inline, params)
+}
+
+// highlightLanguage returns the language passed to HighlightCodeBlock for lang - unknown languages pass through unchanged.
+func (w *HTMLWriter) highlightLanguage(lang string) string {
+	if alias, ok := w.LanguageAliases[lang]; ok {
+		return alias
+	}
+	return lang
+}
+
+// resultFile returns the path of the file (e.g. an image) a src block writes its results to as set via :file (and :dir).
+func resultFile(params map[string]string) (string, bool) {
+	file := params[":file"]
+	if file == "" {
+		return "", false
+	}
+	if dir := params[":dir"]; dir != "" && !path.IsAbs(file) {
+		file = path.Join(dir, file)
+	}
+	return file, true
+}
+
 func (w *HTMLWriter) WriteResult(r Result) { WriteNodes(w, r.Node) }
 
+// writeRawResult writes fixed width & example block results unescaped (:results html & raw) - other results are written as usual.
+func (w *HTMLWriter) writeRawResult(n Node) {
+	if r, ok := n.(Result); ok {
+		switch r := r.Node.(type) {
+		case Example:
+			lines := make([]string, len(r.Children))
+			for i, c := range r.Children {
+				lines[i] = w.blockContent("EXAMPLE", []Node{c})
+			}
+			w.WriteString(strings.Join(lines, "\n") + "\n")
+			return
+		case Block:
+			if r.Name == "EXAMPLE" {
+				w.WriteString(w.blockContent(r.Name, r.Children) + "\n")
+				return
+			}
+		}
+	}
+	WriteNodes(w, n)
+}
+
 func (w *HTMLWriter) WriteInlineBlock(b InlineBlock) {
 	content := w.blockContent(strings.ToUpper(b.Name), b.Children)
 	switch b.Name {
 	case "src":
 		lang := strings.ToLower(b.Parameters[0])
-		content = w.HighlightCodeBlock(content, lang, true, nil)
+		content = w.highlightCodeBlock(content, w.highlightLanguage(lang), true, nil)
 		w.WriteString(fmt.Sprintf("<div class=\"src src-inline src-%s\">\n%s\n</div>", lang, content))
 	case "export":
 		if strings.ToLower(b.Parameters[0]) == "html" {
@@ -178,6 +547,15 @@ func (w *HTMLWriter) WriteInlineBlock(b InlineBlock) {
 	}
 }
 
+// WriteInlineBabelCall writes the call itself for :exports code & both. go-org does not evaluate src blocks -
+// results are only exported if they are part of the document (i.e. the {{{results(...)}}} macro following the call).
+func (w *HTMLWriter) WriteInlineBabelCall(c InlineBabelCall) {
+	switch c.ParameterMap()[":exports"] {
+	case "code", "both":
+		w.WriteString(`<code class="inline-babel-call">` + html.EscapeString(c.String()) + "</code>")
+	}
+}
+
 func (w *HTMLWriter) WriteDrawer(d Drawer) {
 	WriteNodes(w, d.Children...)
 }
@@ -185,6 +563,10 @@ func (w *HTMLWriter) WriteDrawer(d Drawer) {
 func (w *HTMLWriter) WriteKeyword(k Keyword) {
 	if k.Key == "HTML" {
 		w.WriteString(k.Value + "\n")
+	} else if e, ok := w.indexEntry(k); ok {
+		w.WriteString(fmt.Sprintf(`<a id="%s"></a>`, e.ID) + "\n")
+	} else if k.Key == "PRINT_INDEX" {
+		w.writeIndex()
 	} else if k.Key == "TOC" {
 		if m := tocHeadlineMaxLvlRegexp.FindStringSubmatch(k.Value); m != nil {
 			maxLvl, _ := strconv.Atoi(m[1])
@@ -193,8 +575,57 @@ func (w *HTMLWriter) WriteKeyword(k Keyword) {
 	}
 }
 
+// indexEntry returns the first entry of the document for the #+INDEX: keyword k that has not been written yet.
+// Keywords do not know their entry so they are matched by term and section - or just the term for keywords
+// written outside of their section, e.g. in footnote definitions.
+func (w *HTMLWriter) indexEntry(k Keyword) (IndexEntry, bool) {
+	if k.Key != "INDEX" {
+		return IndexEntry{}, false
+	}
+	section := w.document.Outline.Section
+	if len(w.headlines) != 0 {
+		section = w.document.outlineIndex().sections[w.headlines[len(w.headlines)-1].Index]
+	}
+	for _, matchSection := range []bool{true, false} {
+		for _, e := range w.document.Index {
+			if e.Term == k.Value && (e.Section == section || !matchSection) && !w.writtenIndexEntries[e.ID] {
+				w.writtenIndexEntries[e.ID] = true
+				return e, true
+			}
+		}
+	}
+	return IndexEntry{}, false
+}
+
+// writeIndex writes the alphabetized #+INDEX: entries of the document. Repeated terms are listed once, linking to each occurrence.
+func (w *HTMLWriter) writeIndex() {
+	terms, entries := []string{}, map[string][]IndexEntry{}
+	for _, e := range w.document.Index {
+		if e.Section.isExcluded(w.document) {
+			continue
+		}
+		if _, ok := entries[e.Term]; !ok {
+			terms = append(terms, e.Term)
+		}
+		entries[e.Term] = append(entries[e.Term], e)
+	}
+	if len(terms) == 0 {
+		return
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return strings.ToLower(terms[i]) < strings.ToLower(terms[j]) })
+	w.WriteString(fmt.Sprintf(`<ul class="index" aria-label="%s">`, html.EscapeString(w.document.Translate("Index"))) + "\n")
+	for _, term := range terms {
+		w.WriteString(fmt.Sprintf(`<li><a href="#%s">%s</a>`, entries[term][0].ID, html.EscapeString(term)))
+		for i, e := range entries[term][1:] {
+			w.WriteString(fmt.Sprintf(`, <a href="#%s">%d</a>`, e.ID, i+2))
+		}
+		w.WriteString("</li>\n")
+	}
+	w.WriteString("</ul>\n")
+}
+
 func (w *HTMLWriter) WriteInclude(i Include) {
-	WriteNodes(w, i.Resolve())
+	WriteNodes(w, i.Nodes()...)
 }
 
 func (w *HTMLWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
@@ -205,33 +636,41 @@ func (w *HTMLWriter) WriteFootnotes(d *Document) {
 	if w.document.GetOption("f") == "nil" || len(w.footnotes.list) == 0 {
 		return
 	}
-	w.WriteString(`<div class="footnotes">` + "\n")
-	w.WriteString(`<hr class="footnotes-separatator">` + "\n")
+	w.WriteString(`<div class="footnotes"` + w.ariaLabel("Footnotes") + ">\n")
+	w.WriteString(w.voidElement(`<hr class="footnotes-separatator">`) + "\n")
 	w.WriteString(`<div class="footnote-definitions">` + "\n")
-	for i, definition := range w.footnotes.list {
-		id := i + 1
+	// definitions can reference further footnotes - these are appended to the list while writing it
+	for i := 0; i < len(w.footnotes.list); i++ {
+		definition, id := w.footnotes.list[i], i+1
+		if definition == nil && d.Footnotes != nil {
+			definition = d.Footnotes.Definitions[w.footnotes.names[i]] // e.g. footnotes first referenced by other definitions
+		}
 		if definition == nil {
-			name := ""
-			for k, v := range w.footnotes.mapping {
-				if v == i {
-					name = k
-				}
-			}
-			w.log.Printf("Missing footnote definition for [fn:%s] (#%d)", name, id)
+			w.log.Printf("Missing footnote definition for [fn:%s] (#%d)", w.footnotes.names[i], id)
 			continue
 		}
 		w.WriteString(`<div class="footnote-definition">` + "\n")
 		w.WriteString(fmt.Sprintf(`<sup id="footnote-%d"><a href="#footnote-reference-%d">%d</a></sup>`, id, id, id) + "\n")
 		w.WriteString(`<div class="footnote-body">` + "\n")
+		w.footnotes.current = id
 		WriteNodes(w, definition.Children...)
+		w.footnotes.current = 0
 		w.WriteString("</div>\n</div>\n")
 	}
 	w.WriteString("</div>\n</div>\n")
 }
 
+// ariaLabel returns the aria-label attribute (with a leading space) for the translation of label if AriaLabels is set.
+func (w *HTMLWriter) ariaLabel(label string) string {
+	if !w.AriaLabels {
+		return ""
+	}
+	return fmt.Sprintf(` aria-label="%s"`, html.EscapeString(w.document.Translate(label)))
+}
+
 func (w *HTMLWriter) WriteOutline(d *Document, maxLvl int) {
 	if len(d.Outline.Children) != 0 {
-		w.WriteString("<nav>\n<ul>\n")
+		w.WriteString("<nav" + w.ariaLabel("Table of Contents") + ">\n<ul>\n")
 		for _, section := range d.Outline.Children {
 			w.writeSection(section, maxLvl)
 		}
@@ -239,15 +678,57 @@ func (w *HTMLWriter) WriteOutline(d *Document, maxLvl int) {
 	}
 }
 
+// numberSections returns the section numbers (e.g. 1.2) of the headlines of d by index as configured via the num option.
+// Excluded headlines, headlines with a todo keyword and headlines with the UNNUMBERED property (and their children) are not numbered.
+func (w *HTMLWriter) numberSections(d *Document) map[int]string {
+	option := d.GetOption("num")
+	if option == "nil" {
+		return nil
+	}
+	maxLvl, _ := strconv.Atoi(option)
+	numbers := map[int]string{}
+	var number func(sections []*Section, prefix string)
+	number = func(sections []*Section, prefix string) {
+		i := 0
+		for _, s := range sections {
+			h := s.Headline
+			if _, unnumbered := h.Properties.Get("UNNUMBERED"); unnumbered || h.Status != "" || h.IsExcluded(d) || (maxLvl != 0 && h.Lvl > maxLvl) {
+				continue
+			}
+			i++
+			numbers[h.Index] = prefix + strconv.Itoa(i)
+			number(s.Children, numbers[h.Index]+".")
+		}
+	}
+	number(d.Outline.Children, "")
+	return numbers
+}
+
+func (w *HTMLWriter) sectionNumber(h Headline, number string) string {
+	return fmt.Sprintf(`<span class="section-number section-number-%d">%s</span>`, w.headingLvl(h), number)
+}
+
 func (w *HTMLWriter) writeSection(section *Section, maxLvl int) {
+	if h := w.maxHeadlineLvl(); h != 0 && (maxLvl == 0 || h < maxLvl) {
+		maxLvl = h
+	}
 	if (maxLvl != 0 && section.Headline.Lvl > maxLvl) || section.Headline.IsExcluded(w.document) {
 		return
 	}
+	if section.Headline.Lvl < w.TOCMinLvl {
+		for _, section := range section.Children {
+			w.writeSection(section, maxLvl)
+		}
+		return
+	}
 	// NOTE: To satisfy hugo ExtractTOC() check we cannot use `<li>\n` here. Doesn't really matter, just a note.
 	w.WriteString("<li>")
 	h := section.Headline
 	title := cleanHeadlineTitleForHTMLAnchorRegexp.ReplaceAllString(w.WriteNodesAsString(h.Title...), "")
-	w.WriteString(fmt.Sprintf("<a href=\"#%s\">%s</a>\n", h.ID(), title))
+	if number, ok := w.sectionNumbers[h.Index]; ok {
+		title = w.sectionNumber(*h, number) + " " + title
+	}
+	w.WriteString(fmt.Sprintf("<a href=\"#%s\">%s</a>\n", w.headlineID(*h), title))
 	hasChildren := false
 	for _, section := range section.Children {
 		hasChildren = hasChildren || maxLvl == 0 || section.Headline.Lvl <= maxLvl
@@ -266,60 +747,444 @@ func (w *HTMLWriter) WriteHeadline(h Headline) {
 	if h.IsExcluded(w.document) {
 		return
 	}
+	if w.RenderCache == nil || !w.isCacheable(h) {
+		w.writeHeadline(h)
+		return
+	}
+	key := w.renderCacheKey(h)
+	if out, ok := w.RenderCache.Get(key); ok {
+		w.WriteString(out)
+		return
+	}
+	original := w.Builder
+	w.Builder = strings.Builder{}
+	w.writeHeadline(h)
+	out := w.String()
+	w.Builder = original
+	w.RenderCache.Set(key, out)
+	w.WriteString(out)
+}
+
+// isCacheable returns false for headlines whose html depends on the writer state (footnote, line, index & caption numbering)
+// or on nodes outside of the subtree (internal links & radio targets).
+func (w *HTMLWriter) isCacheable(h Headline) bool {
+	cacheable := true
+	walkNodes([]Node{h}, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			cacheable = false
+		case RegularLink:
+			if _, internal := w.internalLinkAnchor(n); internal {
+				cacheable = false
+			}
+		case Text:
+			for _, t := range w.radioTargets {
+				cacheable = cacheable && !t.regexp.MatchString(n.Content)
+			}
+		case Keyword:
+			cacheable = cacheable && n.Key != "INDEX"
+		case NodeWithMeta:
+			cacheable = cacheable && !(w.NumberCaptions && len(n.Meta.Caption) != 0)
+		case Block:
+			if _, _, ok := n.LineNumbers(); ok || w.LineNumberAllCode {
+				cacheable = false
+			}
+		case Example:
+			cacheable = cacheable && !w.LineNumberAllCode
+		}
+	})
+	return cacheable
+}
+
+func (w *HTMLWriter) renderCacheKey(h Headline) string {
+	if w.cachePrefix == "" {
+		d := w.document
+		w.cachePrefix = fmt.Sprint(d.BufferSettings, d.Links, d.Macros, d.RadioTargets, d.Abbreviations, d.Path, w.configurationFingerprint())
+	}
+	headlines := []string{}
+	walkNodes([]Node{h}, func(n Node) {
+		if h, ok := n.(Headline); ok {
+			headlines = append(headlines, fmt.Sprintf("%s %s %t", w.headlineID(h), w.sectionNumbers[h.Index], h.IsExcluded(w.document)))
+		}
+	})
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s\x00%q\x00%s", w.cachePrefix, h.Index, w.attachmentID(), headlines, h.String())))
+	return hex.EncodeToString(hash[:])
+}
+
+// configurationFingerprint returns a string representation of the exported fields of w for renderCacheKey -
+// functions (e.g. HighlightCodeBlock & BlockRenderers) and interfaces (e.g. InlineImages) are not part of it.
+func (w *HTMLWriter) configurationFingerprint() string {
+	v, fingerprint := reflect.ValueOf(w).Elem(), strings.Builder{}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if t := f.Type; f.PkgPath != "" || f.Anonymous || t.Kind() == reflect.Func || t.Kind() == reflect.Interface ||
+			(t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Func) {
+			continue
+		}
+		fmt.Fprintf(&fingerprint, "%s:%#v\x00", f.Name, v.Field(i).Interface())
+	}
+	return fingerprint.String()
+}
+
+func (w *HTMLWriter) writeHeadline(h Headline) {
+	id := w.headlineID(h)
+	if maxLvl := w.maxHeadlineLvl(); maxLvl != 0 && h.Lvl > maxLvl {
+		w.WriteString(fmt.Sprintf(`<li id="%s">`, id) + "\n")
+		w.writeHeadlineTitle(h)
+		w.WriteString("\n" + w.writeHeadlineChildren(h) + "</li>\n")
+		return
+	}
+	lvl := w.headingLvl(h)
+	w.WriteString(fmt.Sprintf(`<div id="outline-container-%s" class="outline-%d">`, id, lvl) + "\n")
+	w.WriteString(fmt.Sprintf(`<h%d id="%s">`, lvl, id) + "\n")
+	w.writeHeadlineTitle(h)
+	w.WriteString(fmt.Sprintf("\n</h%d>\n", lvl))
+	if content := w.writeHeadlineChildren(h); content != "" {
+		w.WriteString(fmt.Sprintf(`<div id="outline-text-%s" class="outline-text-%d">`, id, lvl) + "\n" + content + "</div>\n")
+	}
+	w.WriteString("</div>\n")
+}
 
-	w.WriteString(fmt.Sprintf(`<div id="outline-container-%s" class="outline-%d">`, h.ID(), h.Lvl+1) + "\n")
-	w.WriteString(fmt.Sprintf(`<h%d id="%s">`, h.Lvl+1, h.ID()) + "\n")
+// headingLvl returns the N of the <hN> of h - level 1 headlines are <h2> (the title is the <h1>) shifted by HeadlineOffset, at most <h6>.
+func (w *HTMLWriter) headingLvl(h Headline) int {
+	if lvl := h.Lvl + 1 + w.HeadlineOffset; lvl < 6 {
+		return lvl
+	}
+	return 6
+}
+
+// writeHeadlineChildren returns the html of the children of h. Headlines deeper than the H option are exported as list items.
+// planning returns the planning line of h as <div class="planning"> if planning is exported (#+OPTIONS: p:t).
+func (w *HTMLWriter) planning(h Headline) string {
+	keywords, timestamps := h.planning()
+	if w.document.GetOption("p") != "t" || len(keywords) == 0 {
+		return ""
+	}
+	parts := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		parts[i] = fmt.Sprintf(`<span class="planning-keyword">%s:</span> `, keyword) + w.WriteNodesAsString(timestamps[i])
+	}
+	return `<div class="planning">` + "\n" + strings.Join(parts, " ") + "\n</div>\n"
+}
+
+func (w *HTMLWriter) writeHeadlineChildren(h Headline) string {
+	w.headlines = append(w.headlines, h)
+	content, maxLvl := w.planning(h), w.maxHeadlineLvl()
+	for i := 0; i < len(h.Children); i++ {
+		if c, ok := h.Children[i].(Headline); !ok || maxLvl == 0 || c.Lvl <= maxLvl {
+			content += w.WriteNodesAsString(h.Children[i])
+			continue
+		}
+		j := i
+		for ; j < len(h.Children); j++ {
+			if _, ok := h.Children[j].(Headline); !ok {
+				break
+			}
+		}
+		if items := w.WriteNodesAsString(h.Children[i:j]...); items != "" {
+			content += "<ul>\n" + items + "</ul>\n"
+		}
+		i = j - 1
+	}
+	w.headlines = w.headlines[:len(w.headlines)-1]
+	return content
+}
+
+// headlineID returns the id of h - Headline.ID unless overridden via HeadlineIDPrecedence or SlugHeadlineIDs.
+func (w *HTMLWriter) headlineID(h Headline) string {
+	if id, ok := w.headlineIDs[h.Index]; ok {
+		return id
+	} else if w.headlineIDs != nil {
+		return fmt.Sprintf("headline-%d", h.Index)
+	}
+	return h.ID()
+}
+
+// headlineIDs returns the ids of the headlines of d by index - taken from the first source of precedence that applies to a headline:
+// the ID or CUSTOM_ID property or the slug of the title. Slugs are made unique in document order by appending -1, -2, ...
+// - ids taken from properties are never reused.
+func headlineIDs(d *Document, precedence []string, style string) map[int]string {
+	ids, taken, slugged := map[int]string{}, map[string]bool{}, []Headline{}
+	walkNodes(d.Nodes, func(n Node) {
+		h, ok := n.(Headline)
+		if !ok {
+			return
+		}
+		for _, source := range precedence {
+			if source == "slug" {
+				slugged = append(slugged, h)
+				return
+			} else if id, ok := h.Properties.Get(source); ok && id != "" {
+				ids[h.Index], taken[id] = id, true
+				return
+			}
+		}
+	})
+	for _, h := range slugged {
+		slug := h.Slug()
+		if style == "github" {
+			slug = githubSlugify(plainText(h.Title))
+		}
+		if slug == "" {
+			slug = fmt.Sprintf("headline-%d", h.Index)
+		}
+		id := slug
+		for i := 1; taken[id]; i++ {
+			id = fmt.Sprintf("%s-%d", slug, i)
+		}
+		ids[h.Index], taken[id] = id, true
+	}
+	return ids
+}
+
+func (w *HTMLWriter) maxHeadlineLvl() int {
+	maxLvl, _ := strconv.Atoi(w.document.GetOption("H"))
+	return maxLvl
+}
+
+func (w *HTMLWriter) writeHeadlineTitle(h Headline) {
+	if number, ok := w.sectionNumbers[h.Index]; ok {
+		w.WriteString(w.sectionNumber(h, number) + "\n")
+	}
 	if w.document.GetOption("todo") != "nil" && h.Status != "" {
-		w.WriteString(fmt.Sprintf(`<span class="todo">%s</span>`, h.Status) + "\n")
+		// like ox-html: done keywords (after the | of their #+TODO sequence) get the class done instead of todo
+		class, keywords := "todo", w.document.TodoKeywords()
+		if i, ok := findTodoKeyword(keywords, h.Status); ok && keywords[i].Done {
+			class = "done"
+		}
+		w.WriteString(fmt.Sprintf(`<span class="%s %s">%s</span>`, class, html.EscapeString(h.Status), h.Status) + "\n")
 	}
 	if w.document.GetOption("pri") != "nil" && h.Priority != "" {
 		w.WriteString(fmt.Sprintf(`<span class="priority">[%s]</span>`, h.Priority) + "\n")
 	}
 
+	w.statistics = append(w.statistics, h.Children)
 	WriteNodes(w, h.Title...)
+	w.statistics = w.statistics[:len(w.statistics)-1]
 	if w.document.GetOption("tags") != "nil" && len(h.Tags) != 0 {
-		tags := make([]string, len(h.Tags))
-		for i, tag := range h.Tags {
-			tags[i] = fmt.Sprintf(`<span>%s</span>`, tag)
-		}
 		w.WriteString("&#xa0;&#xa0;&#xa0;")
-		w.WriteString(fmt.Sprintf(`<span class="tags">%s</span>`, strings.Join(tags, "&#xa0;")))
+		w.WriteString(w.tags(h.Tags))
 	}
-	w.WriteString(fmt.Sprintf("\n</h%d>\n", h.Lvl+1))
-	if content := w.WriteNodesAsString(h.Children...); content != "" {
-		w.WriteString(fmt.Sprintf(`<div id="outline-text-%s" class="outline-text-%d">`, h.ID(), h.Lvl+1) + "\n" + content + "</div>\n")
+}
+
+// tags returns the <span class="tags"> of tags - each tag is a link if TagLinkFormat is set.
+func (w *HTMLWriter) tags(tags []string) string {
+	spans := make([]string, len(tags))
+	for i, tag := range tags {
+		if w.TagLinkFormat != "" {
+			href := fmt.Sprintf(w.TagLinkFormat, url.PathEscape(tag))
+			spans[i] = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), html.EscapeString(tag))
+		} else {
+			spans[i] = fmt.Sprintf(`<span>%s</span>`, tag)
+		}
 	}
-	w.WriteString("</div>\n")
+	return fmt.Sprintf(`<span class="tags">%s</span>`, strings.Join(spans, "&#xa0;"))
 }
 
 func (w *HTMLWriter) WriteText(t Text) {
+	content := t.Content
+	if w.PrettifyText && !t.IsRaw && !w.inLink {
+		content = typographyReplacer.Replace(content)
+	}
+	if (w.PrettifyText || w.document.GetOption("'") == "t") && !t.IsRaw && !w.inLink {
+		content = smartQuotes(content, w.quoteOpens())
+	}
+	if len(w.radioTargets) != 0 && w.htmlEscape && !t.IsRaw && !w.inLink {
+		for start, end, name := w.matchRadioTarget(content); name != ""; start, end, name = w.matchRadioTarget(content) {
+			w.writeText(content[:start], false)
+			w.WriteString(fmt.Sprintf(`<a href="#%s">`, radioTargetID(name)))
+			w.writeText(content[start:end], false)
+			w.WriteString("</a>")
+			content = content[end:]
+		}
+	}
+	w.writeText(content, t.IsRaw)
+}
+
+// quoteOpens returns whether a quote at the start of the text about to be written opens, i.e. it follows whitespace,
+// an opening bracket or an opening tag (e.g. <p>) rather than the closing tag of an inline element (e.g. </em>).
+func (w *HTMLWriter) quoteOpens() bool {
+	out := w.String()
+	if strings.HasSuffix(out, ">") {
+		i := strings.LastIndex(out, "<")
+		return i == -1 || !strings.HasPrefix(out[i:], "</")
+	}
+	r, _ := utf8.DecodeLastRuneInString(out)
+	return out == "" || quoteOpensAfter(r)
+}
+
+func quoteOpensAfter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("([{-–—“‘", r)
+}
+
+// smartQuotes replaces straight quotes with curly ones - quotes open after whitespace & opening brackets
+// (or at the start of content if opens) and close otherwise. Closing single quotes double as apostrophes.
+func smartQuotes(content string, opens bool) string {
+	if !strings.ContainsAny(content, `"'`) {
+		return content
+	}
+	out := strings.Builder{}
+	for _, r := range content {
+		switch {
+		case r == '"' && opens:
+			out.WriteRune('“')
+		case r == '"':
+			out.WriteRune('”')
+		case r == '\'' && opens:
+			out.WriteRune('‘')
+		case r == '\'':
+			out.WriteRune('’')
+		default:
+			out.WriteRune(r)
+		}
+		opens = quoteOpensAfter(r)
+	}
+	return out.String()
+}
+
+// writeText writes the escaped content. Whole word occurrences of #+ABBREV: abbreviations in prose are wrapped in <abbr>.
+func (w *HTMLWriter) writeText(content string, isRaw bool) {
+	if w.abbreviations != nil && w.htmlEscape && !isRaw {
+		for start, end := w.matchAbbreviation(content); end != 0; start, end = w.matchAbbreviation(content) {
+			w.writeEscapedText(content[:start], false)
+			w.WriteString(fmt.Sprintf(`<abbr title="%s">`, html.EscapeString(w.document.Abbreviations[content[start:end]])))
+			w.writeEscapedText(content[start:end], false)
+			w.WriteString("</abbr>")
+			content = content[end:]
+		}
+	}
+	w.writeEscapedText(content, isRaw)
+}
+
+// matchAbbreviation returns the position of the first whole word (case-sensitive) match of an abbreviation in s.
+func (w *HTMLWriter) matchAbbreviation(s string) (int, int) {
+	for offset := 0; offset < len(s); {
+		m := w.abbreviations.FindStringIndex(s[offset:])
+		if m == nil {
+			break
+		}
+		start, end := offset+m[0], offset+m[1]
+		if !isWordRune(prevRune(s, start)) && !isWordRune(firstRune(s[end:])) {
+			return start, end
+		}
+		_, size := utf8.DecodeRuneInString(s[start:])
+		offset = start + size
+	}
+	return 0, 0
+}
+
+func (w *HTMLWriter) writeEscapedText(content string, isRaw bool) {
 	if !w.htmlEscape {
-		w.WriteString(t.Content)
-	} else if w.document.GetOption("e") == "nil" || t.IsRaw {
-		w.WriteString(html.EscapeString(t.Content))
+		w.WriteString(content)
+	} else if isRaw {
+		w.WriteString(nbspReplacer.Replace(html.EscapeString(content)))
+	} else {
+		if w.document.GetOption("e") != "nil" {
+			content = specialStringReplacer.Replace(content)
+		}
+		content = nbspReplacer.Replace(html.EscapeString(content))
+		if w.TabReplacement != "" {
+			content = strings.ReplaceAll(content, "\t", w.TabReplacement)
+		}
+		w.WriteString(content)
+	}
+}
+
+func (w *HTMLWriter) WriteRadioTarget(t RadioTarget) {
+	w.WriteString(fmt.Sprintf(`<a id="%s">`, radioTargetID(t.Name)))
+	w.writeText(t.Name, false)
+	w.WriteString("</a>")
+}
+
+// WriteEntity writes the html representation of e - or e as is if entities are disabled (#+OPTIONS: e:nil).
+func (w *HTMLWriter) WriteEntity(e Entity) {
+	if w.document.GetOption("e") == "nil" {
+		w.writeEscapedText(e.String(), true)
+	} else if w.htmlEscape {
+		w.WriteString(e.HTML())
 	} else {
-		w.WriteString(html.EscapeString(htmlEntityReplacer.Replace(t.Content)))
+		w.WriteString(e.UTF8())
 	}
 }
 
+func (w *HTMLWriter) WriteTarget(t Target) {
+	w.WriteString(fmt.Sprintf(`<a id="%s"></a>`, targetID(t.Name)))
+}
+
+// targetID returns the id of the anchor of the <<target>> name - the slug of the name, e.g. my-target for <<My Target>>.
+func targetID(name string) string { return slugify(name) }
+
+// matchRadioTarget returns the position of the first (and at that position longest) case-insensitive
+// match of a radio target in s. Radio targets only match whole words - e.g. cat does not match category.
+func (w *HTMLWriter) matchRadioTarget(s string) (int, int, string) {
+	for start := 0; start < len(s); {
+		r, size := utf8.DecodeRuneInString(s[start:])
+		if !isWordRune(r) || !isWordRune(prevRune(s, start)) {
+			for _, t := range w.radioTargets {
+				if m := t.regexp.FindStringIndex(s[start:]); m != nil && !isWordRune(firstRune(s[start+m[1]:])) {
+					return start, start + m[1], t.name
+				}
+			}
+		}
+		start += size
+	}
+	return 0, 0, ""
+}
+
+func radioTargetID(name string) string {
+	return "radio-target-" + slugify(name)
+}
+
+func isWordRune(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
 func (w *HTMLWriter) WriteEmphasis(e Emphasis) {
-	tags, ok := emphasisTags[e.Kind]
+	tags, ok := w.EmphasisTags[e.Kind]
 	if !ok {
-		panic(fmt.Sprintf("bad emphasis %#v", e))
+		tags = orgEmphasisBorders(e.Kind)
+		tags = []string{html.EscapeString(tags[0]), html.EscapeString(tags[1])}
 	}
 	w.WriteString(tags[0])
 	WriteNodes(w, e.Content...)
 	w.WriteString(tags[1])
 }
 
+// WriteLatexFragment wraps latex fragments in a <span class="math inline"> (\(...\) & $...$) or <span class="math display">
+// (\[...\], $$...$$ & \begin{...}...\end{...}) for MathJax / KaTeX - dollar delimiters are written as \(...\) & \[...\].
 func (w *HTMLWriter) WriteLatexFragment(l LatexFragment) {
-	w.WriteString(l.OpeningPair)
+	openingPair, closingPair, class := l.OpeningPair, l.ClosingPair, "display"
+	switch openingPair {
+	case `\(`, "$":
+		openingPair, closingPair, class = `\(`, `\)`, "inline"
+	case "$$":
+		openingPair, closingPair = `\[`, `\]`
+	}
+	w.WriteString(`<span class="math ` + class + `">` + html.EscapeString(openingPair))
 	WriteNodes(w, l.Content...)
-	w.WriteString(l.ClosingPair)
+	w.WriteString(html.EscapeString(closingPair) + "</span>")
+}
+
+func (w *HTMLWriter) WriteLatexBlock(b LatexBlock) {
+	w.WriteString(`<div class="math display">` + "\n" + html.EscapeString(b.Latex()) + "\n</div>\n")
 }
 
 func (w *HTMLWriter) WriteStatisticToken(s StatisticToken) {
-	w.WriteString(fmt.Sprintf(`<code class="statistic">[%s]</code>`, s.Content))
+	if w.StatisticProgress && len(w.statistics) != 0 {
+		if done, total := countCheckboxes(w.statistics[len(w.statistics)-1]); total != 0 {
+			content := fmt.Sprintf("%d/%d", done, total)
+			if strings.HasSuffix(s.Content, "%") {
+				content = fmt.Sprintf("%d%%", done*100/total)
+			}
+			w.WriteString(fmt.Sprintf(`<code class="statistic statistic-cookie">[%s]</code>`, content))
+			w.WriteString(fmt.Sprintf(`<progress max="%d" value="%d">%s</progress>`, total, done, content))
+			return
+		}
+	}
+	w.WriteString(fmt.Sprintf(`<code class="statistic statistic-cookie">[%s]</code>`, s.Content))
 }
 
 func (w *HTMLWriter) WriteLineBreak(l LineBreak) {
@@ -329,7 +1194,7 @@ func (w *HTMLWriter) WriteLineBreak(l LineBreak) {
 }
 
 func (w *HTMLWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
-	w.WriteString("<br>\n")
+	w.WriteString(w.voidElement("<br>") + "\n")
 }
 
 func (w *HTMLWriter) WriteFootnoteLink(l FootnoteLink) {
@@ -338,56 +1203,111 @@ func (w *HTMLWriter) WriteFootnoteLink(l FootnoteLink) {
 	}
 	i := w.footnotes.add(l)
 	id := i + 1
+	if w.footnotes.isCyclic(i) {
+		w.log.Printf("Footnote cycle: the definition of [fn:%s] (#%d) references itself", w.footnotes.names[i], id)
+		w.WriteString(fmt.Sprintf(`<sup class="footnote-reference"><a href="#footnote-%d">%d</a></sup>`, id, id))
+		return
+	}
 	w.WriteString(fmt.Sprintf(`<sup class="footnote-reference"><a id="footnote-reference-%d" href="#footnote-%d">%d</a></sup>`, id, id, id))
 }
 
 func (w *HTMLWriter) WriteTimestamp(t Timestamp) {
-	if w.document.GetOption("<") == "nil" {
+	switch option := w.document.GetOption("<"); {
+	case option == "nil", (option == "active" || w.HideInactiveTimestamps) && !t.IsActive, option == "inactive" && t.IsActive:
 		return
 	}
-	w.WriteString(`<span class="timestamp">&lt;`)
-	if t.IsDate {
-		w.WriteString(t.Time.Format(datestampFormat))
-	} else {
-		w.WriteString(t.Time.Format(timestampFormat))
-	}
-	if t.Interval != "" {
-		w.WriteString(" " + t.Interval)
+	class := "timestamp"
+	if w.TimestampClasses && t.IsActive {
+		class += " timestamp-active"
+	} else if w.TimestampClasses {
+		class += " timestamp-inactive"
 	}
-	w.WriteString(`&gt;</span>`)
+	w.WriteString(`<span class="` + class + `">` + html.EscapeString(t.orgString()) + `</span>`)
 }
 
 func (w *HTMLWriter) WriteRegularLink(l RegularLink) {
+	if l.IsExecutable() {
+		w.log.Printf("Not linking executable link: %s", l.URL)
+		if l.Description != nil {
+			WriteNodes(w, l.Description...)
+		} else {
+			w.WriteString(html.EscapeString(l.URL))
+		}
+		return
+	}
 	url := html.EscapeString(l.URL)
 	if l.Protocol == "file" {
 		url = url[len("file:"):]
 	}
-	if isRelative := l.Protocol == "file" || l.Protocol == ""; isRelative && w.PrettyRelativeLinks {
-		if !strings.HasPrefix(url, "/") {
-			url = "../" + url
+	if l.Protocol == "attachment" {
+		path, resolved := l.URL[len("attachment:"):], ""
+		if w.AttachmentResolver != nil {
+			resolved = w.AttachmentResolver(w.attachmentID(), path)
 		}
-		if strings.HasSuffix(url, ".org") {
-			url = strings.TrimSuffix(url, ".org") + "/"
+		if resolved == "" {
+			w.log.Printf("Could not resolve attachment: %s", l.URL)
+			if l.Description != nil {
+				WriteNodes(w, l.Description...)
+			} else {
+				w.WriteString(html.EscapeString(path))
+			}
+			return
 		}
-	} else if isRelative && strings.HasSuffix(url, ".org") {
-		url = strings.TrimSuffix(url, ".org") + ".html"
+		l.URL, url = resolved, html.EscapeString(resolved)
 	}
-	if prefix := w.document.Links[l.Protocol]; prefix != "" {
-		if tag := strings.TrimPrefix(l.URL, l.Protocol+":"); strings.Contains(prefix, "%s") || strings.Contains(prefix, "%h") {
-			url = html.EscapeString(strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", tag), "%h", u.QueryEscape(tag)))
+	if l.Protocol == "file" || l.Protocol == "" {
+		path, suffix := splitLinkFragment(url)
+		if w.PrettyRelativeLinks {
+			if !strings.HasPrefix(path, "/") {
+				path = "../" + path
+			}
+			if strings.HasSuffix(path, ".org") {
+				path = strings.TrimSuffix(path, ".org") + "/"
+			}
+		} else if strings.HasSuffix(path, ".org") {
+			path = strings.TrimSuffix(path, ".org") + ".html"
+		}
+		url = w.resolveRelativeURL(path) + suffix
+	}
+	if expanded, ok := w.document.ExpandLink(l); ok {
+		url = html.EscapeString(expanded)
+	}
+	if label, ok := w.captionLabels[l.URL]; ok && l.Protocol == "" {
+		description := html.EscapeString(label)
+		if l.Description != nil {
+			description = w.WriteNodesAsString(l.Description...)
+		}
+		w.WriteString(fmt.Sprintf(`<a href="#%s">%s</a>`, html.EscapeString(l.URL), description))
+		return
+	}
+	if anchor, internal := w.internalLinkAnchor(l); internal {
+		description := html.EscapeString(strings.TrimPrefix(l.URL, "*"))
+		if l.Description != nil {
+			description = w.WriteNodesAsString(l.Description...)
+		}
+		if anchor == "" {
+			w.log.Printf("Broken internal link: %s", l)
+			w.WriteString(`<span class="broken-link">` + description + `</span>`)
 		} else {
-			url = html.EscapeString(prefix) + tag
+			w.WriteString(fmt.Sprintf(`<a href="#%s">%s</a>`, html.EscapeString(anchor), description))
 		}
-	} else if prefix := w.document.Links[l.URL]; prefix != "" {
-		url = html.EscapeString(strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", ""), "%h", ""))
+		return
 	}
 	switch l.Kind() {
 	case "image":
 		if l.Description == nil {
-			w.WriteString(fmt.Sprintf(`<img src="%s" alt="%s" title="%s" />`, url, url, url))
+			src := url
+			if data, ok := w.inlineImage(l.URL); ok {
+				src = data
+			}
+			w.WriteString(fmt.Sprintf(`<img src="%s" alt="%s" title="%s" />`, src, url, url))
 		} else {
 			description := strings.TrimPrefix(String(l.Description), "file:")
-			w.WriteString(fmt.Sprintf(`<a href="%s"><img src="%s" alt="%s" /></a>`, url, description, description))
+			src := description
+			if data, ok := w.inlineImage(description); ok {
+				src = data
+			}
+			w.WriteString(fmt.Sprintf(`<a href="%s"><img src="%s" alt="%s" /></a>`, url, src, description))
 		}
 	case "video":
 		if l.Description == nil {
@@ -399,22 +1319,110 @@ func (w *HTMLWriter) WriteRegularLink(l RegularLink) {
 	default:
 		description := url
 		if l.Description != nil {
+			inLink := w.inLink
+			w.inLink = true
 			description = w.WriteNodesAsString(l.Description...)
+			w.inLink = inLink
 		}
 		w.WriteString(fmt.Sprintf(`<a href="%s">%s</a>`, url, description))
 	}
 }
 
-func (w *HTMLWriter) WriteMacro(m Macro) {
-	if macro := w.document.Macros[m.Name]; macro != "" {
-		for i, param := range m.Parameters {
-			macro = strings.Replace(macro, fmt.Sprintf("$%d", i+1), param, -1)
-		}
-		macroDocument := w.document.Parse(strings.NewReader(macro), w.document.Path)
-		if macroDocument.Error != nil {
-			w.log.Printf("bad macro: %s -> %s: %v", m.Name, macro, macroDocument.Error)
+// internalLinkAnchor returns the id of the element an internal link points to (see linkResolver.resolve) - empty if nothing matches.
+// internal is false for external links (incl. [[id:...]] links, which may point to other files), links to files and link abbreviations.
+func (w *HTMLWriter) internalLinkAnchor(l RegularLink) (id string, internal bool) {
+	if l.Protocol == "id" {
+		return "", false
+	}
+	switch t, internal := w.links.resolve(l); {
+	case !internal:
+		return "", false
+	case t.headline != nil:
+		return w.headlineID(*t.headline), true
+	case t.radioTarget != "":
+		return radioTargetID(t.radioTarget), true
+	case t.target != "":
+		return targetID(t.target), true
+	default:
+		return t.name, true
+	}
+}
+
+// splitLinkFragment splits the #fragment or ::search option (e.g. notes.org::*Heading) off the path of a link.
+func splitLinkFragment(link string) (path, fragment string) {
+	i := strings.Index(link, "::")
+	if i == -1 {
+		i = strings.Index(link, "#")
+	}
+	if i == -1 {
+		return link, ""
+	}
+	return link[:i], link[i:]
+}
+
+// resolveRelativeURL resolves the (html escaped) relative path against BaseURL - internal links (#... & *...) are returned as is.
+func (w *HTMLWriter) resolveRelativeURL(path string) string {
+	if w.BaseURL == "" || path == "" || strings.HasPrefix(path, "*") {
+		return path
+	}
+	base, err := url.Parse(w.BaseURL)
+	if err != nil {
+		w.log.Printf("Could not parse BaseURL %s: %s", w.BaseURL, err)
+		return path
+	}
+	ref, err := url.Parse(html.UnescapeString(path))
+	if err != nil {
+		w.log.Printf("Could not resolve link %s: %s", path, err)
+		return path
+	}
+	return html.EscapeString(base.ResolveReference(ref).String())
+}
+
+// inlineImage returns the local image at src (e.g. file:foo.png or ./foo.png) as a base64 data: uri (see HTMLWriter.InlineImages).
+// Remote images, images that cannot be read and images larger than InlineImageMaxSize are not inlined.
+func (w *HTMLWriter) inlineImage(src string) (string, bool) {
+	if w.InlineImages == nil {
+		return "", false
+	}
+	if parts := strings.SplitN(src, ":", 2); len(parts) == 2 && parts[0] != "file" {
+		return "", false
+	}
+	name := path.Clean(strings.TrimPrefix(strings.TrimPrefix(src, "file:"), "/"))
+	if info, err := fs.Stat(w.InlineImages, name); err != nil {
+		w.log.Printf("Could not inline image %s: %s", src, err)
+		return "", false
+	} else if w.InlineImageMaxSize != 0 && info.Size() > w.InlineImageMaxSize {
+		return "", false
+	}
+	data, err := fs.ReadFile(w.InlineImages, name)
+	if err != nil {
+		w.log.Printf("Could not inline image %s: %s", src, err)
+		return "", false
+	}
+	mimeType := mime.TypeByExtension(path.Ext(name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), true
+}
+
+// attachmentID returns the ID (or DIR) property of the innermost headline currently being written that has one.
+func (w *HTMLWriter) attachmentID() string {
+	for i := len(w.headlines) - 1; i >= 0; i-- {
+		if id, ok := w.headlines[i].Properties.Get("ID"); ok {
+			return id
+		} else if dir, ok := w.headlines[i].Properties.Get("DIR"); ok {
+			return dir
 		}
-		WriteNodes(w, macroDocument.Nodes...)
+	}
+	return ""
+}
+
+func (w *HTMLWriter) WriteMacro(m Macro) {
+	if nodes, ok := w.document.ExpandMacro(m); ok {
+		WriteNodes(w, nodes...)
+	} else {
+		w.writeText(m.String(), true)
 	}
 }
 
@@ -424,7 +1432,21 @@ func (w *HTMLWriter) WriteList(l List) {
 		panic(fmt.Sprintf("bad list kind %#v", l))
 	}
 	w.WriteString(tags[0] + "\n")
-	WriteNodes(w, l.Items...)
+	previousTerm := ""
+	for _, item := range l.Items {
+		// consecutive items with the same term (and status) are rendered as one <dt> with multiple <dd> definitions
+		if di, ok := item.(DescriptiveListItem); ok && len(di.Term) != 0 {
+			term := di.Status + String(di.Term)
+			if term == previousTerm {
+				w.writeDescriptiveListItemDetails(di)
+				continue
+			}
+			previousTerm = term
+		} else {
+			previousTerm = ""
+		}
+		WriteNodes(w, item)
+	}
 	w.WriteString(tags[1] + "\n")
 }
 
@@ -436,14 +1458,18 @@ func (w *HTMLWriter) WriteListItem(li ListItem) {
 	if li.Status != "" {
 		attributes += fmt.Sprintf(` class="%s"`, listItemStatuses[li.Status])
 	}
-	w.WriteString(fmt.Sprintf("<li%s>", attributes))
+	w.WriteString(fmt.Sprintf("<li%s>", attributes) + w.checkboxInput(li.Status))
+	w.statistics = append(w.statistics, li.Children)
 	w.writeListItemContent(li.Children)
+	w.statistics = w.statistics[:len(w.statistics)-1]
 	w.WriteString("</li>\n")
 }
 
 func (w *HTMLWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
+	w.statistics = append(w.statistics, di.Details)
+	defer func() { w.statistics = w.statistics[:len(w.statistics)-1] }()
 	if di.Status != "" {
-		w.WriteString(fmt.Sprintf("<dt class=\"%s\">\n", listItemStatuses[di.Status]))
+		w.WriteString(fmt.Sprintf("<dt class=\"%s\">\n", listItemStatuses[di.Status]) + w.checkboxInput(di.Status))
 	} else {
 		w.WriteString("<dt>\n")
 	}
@@ -454,6 +1480,41 @@ func (w *HTMLWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
 		w.WriteString("?")
 	}
 	w.WriteString("\n</dt>\n")
+	w.writeDescriptiveListItemDetails(di)
+}
+
+// checkboxInput returns the <input> of CheckboxInputs for the status of a list item - or nothing.
+func (w *HTMLWriter) checkboxInput(status string) string {
+	if !w.CheckboxInputs || status == "" {
+		return ""
+	}
+	switch status {
+	case "X":
+		return w.voidElement(`<input type="checkbox" `+w.booleanAttribute("checked")+" "+w.booleanAttribute("disabled")+">") + " "
+	case "-":
+		return w.voidElement(`<input type="checkbox" aria-checked="mixed" `+w.booleanAttribute("disabled")+">") + " "
+	default:
+		return w.voidElement(`<input type="checkbox" `+w.booleanAttribute("disabled")+">") + " "
+	}
+}
+
+// voidElement returns the tag of a void element (e.g. <br>) - self-closed (<br />) if XHTML.
+func (w *HTMLWriter) voidElement(tag string) string {
+	if w.XHTML {
+		return strings.TrimSuffix(tag, ">") + " />"
+	}
+	return tag
+}
+
+// booleanAttribute returns the boolean attribute name - as name="name" if XHTML.
+func (w *HTMLWriter) booleanAttribute(name string) string {
+	if w.XHTML {
+		return fmt.Sprintf(`%s="%s"`, name, name)
+	}
+	return name
+}
+
+func (w *HTMLWriter) writeDescriptiveListItemDetails(di DescriptiveListItem) {
 	w.WriteString("<dd>")
 	w.writeListItemContent(di.Details)
 	w.WriteString("</dd>\n")
@@ -478,55 +1539,148 @@ func (w *HTMLWriter) WriteParagraph(p Paragraph) {
 	if len(p.Children) == 0 {
 		return
 	}
-	w.WriteString("<p>")
+	if w.document.GetOption("rtl") != "nil" && isRightToLeft(p.Children) {
+		w.WriteString(`<p dir="rtl">`)
+	} else {
+		w.WriteString("<p>")
+	}
 	WriteNodes(w, p.Children...)
 	w.WriteString("</p>\n")
 }
 
 func (w *HTMLWriter) WriteExample(e Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = w.WriteNodesAsString(n)
+	}
+	content := strings.Join(lines, "\n")
+	if start, ok := w.nextLineNumbers(1, false, w.LineNumberAllCode, content); ok {
+		content = numberLines(content, start)
+	}
 	w.WriteString(`<pre class="example">` + "\n")
 	if len(e.Children) != 0 {
-		for _, n := range e.Children {
-			WriteNodes(w, n)
-			w.WriteString("\n")
-		}
+		w.WriteString(content + "\n")
 	}
 	w.WriteString("</pre>\n")
 }
 
 func (w *HTMLWriter) WriteHorizontalRule(h HorizontalRule) {
-	w.WriteString("<hr>\n")
+	if w.HorizontalRuleClass != "" {
+		w.WriteString(w.voidElement(fmt.Sprintf(`<hr class="%s" data-dashes="%d">`, html.EscapeString(w.HorizontalRuleClass), h.Dashes)) + "\n")
+	} else {
+		w.WriteString(w.voidElement("<hr>") + "\n")
+	}
 }
 
-func (w *HTMLWriter) WriteNodeWithMeta(n NodeWithMeta) {
+func (w *HTMLWriter) WriteNodeWithMeta(n NodeWithMeta) { w.writeNodeWithMeta(n, "") }
+
+// writeNodeWithMeta writes n - captioned nodes get the given id (e.g. from #+NAME:) unless #+ATTR_HTML: sets an explicit :id.
+func (w *HTMLWriter) writeNodeWithMeta(n NodeWithMeta, id string) {
 	out := w.WriteNodesAsString(n.Node)
 	if p, ok := n.Node.(Paragraph); ok {
 		if len(p.Children) == 1 && isImageOrVideoLink(p.Children[0]) {
 			out = w.WriteNodesAsString(p.Children[0])
 		}
 	}
-	for _, attributes := range n.Meta.HTMLAttributes {
+	if strings.TrimSpace(out) == "" {
+		return // e.g. comment blocks & src blocks with :exports none - captions of nothing are dropped as well
+	}
+	_, isTable := n.Node.(Table)
+	isTable = isTable && !w.TableFigureCaptions
+	htmlAttributes := n.Meta.HTMLAttributes
+	if id != "" && isTable && len(n.Meta.Caption) != 0 {
+		if !hasHTMLAttribute(htmlAttributes, "id") {
+			htmlAttributes = append([][]string{{":id", id}}, htmlAttributes...)
+		}
+		id = ""
+	}
+	for _, attributes := range htmlAttributes {
 		out = w.withHTMLAttributes(out, attributes...) + "\n"
 	}
 	if len(n.Meta.Caption) != 0 {
-		caption := ""
-		for i, ns := range n.Meta.Caption {
-			if i != 0 {
-				caption += " "
-			}
-			caption += w.WriteNodesAsString(ns...)
+		caption := w.WriteNodesAsString(n.Caption()...)
+		if w.NumberCaptions {
+			kind := captionKind(n)
+			w.captionCounts[kind]++
+			label := fmt.Sprintf("%s %d", w.document.Translate(kind), w.captionCounts[kind])
+			caption = fmt.Sprintf(`<span class="caption-label">%s:</span> `, html.EscapeString(label)) + caption
+		}
+		if isTable {
+			i := strings.Index(out, ">") + 1
+			out = fmt.Sprintf("%s\n<caption>\n%s\n</caption>%s", out[:i], caption, out[i:])
+		} else if id != "" {
+			out = fmt.Sprintf("<figure id=\"%s\">\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", html.EscapeString(id), out, caption)
+		} else {
+			out = fmt.Sprintf("<figure>\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", out, caption)
 		}
-		out = fmt.Sprintf("<figure>\n%s<figcaption>\n%s\n</figcaption>\n</figure>\n", out, caption)
 	}
 	w.WriteString(out)
 }
 
 func (w *HTMLWriter) WriteNodeWithName(n NodeWithName) {
+	if m, ok := n.Node.(NodeWithMeta); ok && len(m.Meta.Caption) != 0 {
+		w.writeNodeWithMeta(m, n.Name)
+		return
+	}
 	WriteNodes(w, n.Node)
 }
 
+// hasHTMLAttribute returns true if the #+ATTR_HTML: attributes set the attribute key.
+func hasHTMLAttribute(htmlAttributes [][]string, key string) bool {
+	for _, attributes := range htmlAttributes {
+		for i := 0; i < len(attributes)-1; i += 2 {
+			if strings.EqualFold(strings.TrimPrefix(attributes[i], ":"), key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// labelCaptions returns the labels (e.g. Figure 2) of the named, captioned nodes - numbered in the order they are written.
+func (w *HTMLWriter) labelCaptions(d *Document, nodes []Node, counts map[string]int, labels map[string]string) map[string]string {
+	for _, n := range nodes {
+		if h, ok := n.(Headline); ok {
+			if !h.IsExcluded(d) {
+				w.labelCaptions(d, h.Children, counts, labels)
+			}
+			continue
+		}
+		walkNodes([]Node{n}, func(n Node) {
+			switch n := n.(type) {
+			case NodeWithName:
+				if m, ok := n.Node.(NodeWithMeta); ok && len(m.Meta.Caption) != 0 && !isUnexportedBlock(m.Node) {
+					labels[n.Name] = fmt.Sprintf("%s %d", d.Translate(captionKind(m)), counts[captionKind(m)]+1)
+				}
+			case NodeWithMeta:
+				if len(n.Meta.Caption) != 0 && !isUnexportedBlock(n.Node) {
+					counts[captionKind(n)]++
+				}
+			}
+		})
+	}
+	return labels
+}
+
+// isUnexportedBlock returns true for blocks without html output: comment blocks and src blocks with :exports none.
+func isUnexportedBlock(n Node) bool {
+	b, ok := n.(Block)
+	return ok && (b.Name == "COMMENT" || (b.Name == "SRC" && b.ParameterMap()[":exports"] == "none"))
+}
+
+func captionKind(n NodeWithMeta) string {
+	if _, isTable := n.Node.(Table); isTable {
+		return "Table"
+	}
+	return "Figure"
+}
+
 func (w *HTMLWriter) WriteTable(t Table) {
 	w.WriteString("<table>\n")
+	var spans [][]CellSpan
+	if w.TableColSpanMarker != "" || w.TableRowSpanMarker != "" {
+		spans = t.Spans(w.TableColSpanMarker, w.TableRowSpanMarker)
+	}
 	inHead := len(t.SeparatorIndices) > 0 &&
 		t.SeparatorIndices[0] != len(t.Rows)-1 &&
 		(t.SeparatorIndices[0] != 0 || len(t.SeparatorIndices) > 1 && t.SeparatorIndices[len(t.SeparatorIndices)-1] != len(t.Rows)-1)
@@ -547,23 +1701,37 @@ func (w *HTMLWriter) WriteTable(t Table) {
 		if row.IsSpecial {
 			continue
 		}
+		var rowSpans []CellSpan
+		if spans != nil {
+			rowSpans = spans[i]
+		}
 		if inHead {
-			w.writeTableColumns(row.Columns, "th")
+			w.writeTableColumns(row.Columns, rowSpans, "th")
 		} else {
-			w.writeTableColumns(row.Columns, "td")
+			w.writeTableColumns(row.Columns, rowSpans, "td")
 		}
 	}
 	w.WriteString("</tbody>\n</table>\n")
 }
 
-func (w *HTMLWriter) writeTableColumns(columns []Column, tag string) {
+func (w *HTMLWriter) writeTableColumns(columns []Column, spans []CellSpan, tag string) {
 	w.WriteString("<tr>\n")
-	for _, column := range columns {
-		if column.Align == "" {
-			w.WriteString(fmt.Sprintf("<%s>", tag))
-		} else {
-			w.WriteString(fmt.Sprintf(`<%s class="align-%s">`, tag, column.Align))
+	for i, column := range columns {
+		attributes := ""
+		if column.Align != "" {
+			attributes += fmt.Sprintf(` class="align-%s"`, column.Align)
 		}
+		if spans != nil {
+			if spans[i].ColSpan == 0 {
+				continue
+			} else if spans[i].ColSpan > 1 {
+				attributes += fmt.Sprintf(` colspan="%d"`, spans[i].ColSpan)
+			}
+			if spans[i].RowSpan > 1 {
+				attributes += fmt.Sprintf(` rowspan="%d"`, spans[i].RowSpan)
+			}
+		}
+		w.WriteString(fmt.Sprintf("<%s%s>", tag, attributes))
 		WriteNodes(w, column.Children...)
 		w.WriteString(fmt.Sprintf("</%s>\n", tag))
 	}
@@ -583,7 +1751,14 @@ func (w *HTMLWriter) withHTMLAttributes(input string, kvs ...string) string {
 	}
 	out, node := strings.Builder{}, nodes[0]
 	for i := 0; i < len(kvs)-1; i += 2 {
-		node.Attr = setHTMLAttribute(node.Attr, strings.TrimPrefix(kvs[i], ":"), kvs[i+1])
+		value := kvs[i+1]
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1] // quoted values may contain spaces & commas, e.g. :srcset "a.png 1x, b.png 2x"
+		}
+		if value == "" && w.XHTML {
+			value = strings.TrimPrefix(kvs[i], ":") // boolean attributes, e.g. :hidden :class foo
+		}
+		node.Attr = setHTMLAttribute(node.Attr, strings.TrimPrefix(kvs[i], ":"), value)
 	}
 	err = h.Render(&out, nodes[0])
 	if err != nil {
@@ -606,6 +1781,50 @@ func (w *HTMLWriter) blockContent(name string, children []Node) string {
 	}
 }
 
+// hasResultsParameter returns true if the :results header argument (e.g. :results output silent) contains value.
+func hasResultsParameter(params map[string]string, value string) bool {
+	for _, v := range strings.Fields(params[":results"]) {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lineNumbers returns the number of the first line of the block if its lines should be numbered.
+func (w *HTMLWriter) lineNumbers(b Block, content string) (int, bool) {
+	start, continued, ok := b.LineNumbers()
+	if !ok && w.LineNumberAllCode && b.ParameterMap()[":number-lines"] != "nil" {
+		start, ok = 1, true
+	}
+	return w.nextLineNumbers(start, continued, ok, content)
+}
+
+// nextLineNumbers returns the number of the first line of a numbered block like Org mode: -n N starts at N,
+// +n N continues N lines after the last numbered line of the preceding numbered blocks (+n at the next line).
+func (w *HTMLWriter) nextLineNumbers(start int, continued, ok bool, content string) (int, bool) {
+	if !ok {
+		return 0, false
+	}
+	if continued {
+		start += w.lastLineNumber
+	}
+	lines := 0
+	if content != "" {
+		lines = strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+	}
+	w.lastLineNumber = start - 1 + lines
+	return start, true
+}
+
+func numberLines(content string, start int) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf(`<span class="line-number">%d</span>%s`, start+i, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func setHTMLAttribute(attributes []h.Attribute, k, v string) []h.Attribute {
 	for i, a := range attributes {
 		if strings.ToLower(a.Key) == strings.ToLower(k) {
@@ -621,6 +1840,25 @@ func setHTMLAttribute(attributes []h.Attribute, k, v string) []h.Attribute {
 	return append(attributes, h.Attribute{Namespace: "", Key: k, Val: v})
 }
 
+// isRightToLeft returns true if the first strong (directional) character of the text in nodes is a right-to-left character.
+func isRightToLeft(nodes []Node) bool {
+	rtl, found := false, false
+	walkNodes(nodes, func(n Node) {
+		if t, ok := n.(Text); ok && !found {
+			for _, r := range t.Content {
+				if unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko) {
+					rtl, found = true, true
+					break
+				} else if unicode.IsLetter(r) {
+					found = true
+					break
+				}
+			}
+		}
+	})
+	return rtl
+}
+
 func isParagraphNodeSlice(ns []Node) bool {
 	for _, n := range ns {
 		if reflect.TypeOf(n).Name() != "Paragraph" {
@@ -634,14 +1872,31 @@ func (fs *footnotes) add(f FootnoteLink) int {
 	if i, ok := fs.mapping[f.Name]; ok && f.Name != "" {
 		return i
 	}
-	fs.list = append(fs.list, f.Definition)
+	fs.list, fs.names = append(fs.list, f.Definition), append(fs.names, f.Name)
 	i := len(fs.list) - 1
 	if f.Name != "" {
 		fs.mapping[f.Name] = i
 	}
+	if fs.current != 0 {
+		if fs.parents == nil {
+			fs.parents = map[int]int{}
+		}
+		fs.parents[i] = fs.current - 1
+	}
 	return i
 }
 
+// isCyclic returns true if footnote i is the footnote whose definition is being written or one of the footnotes
+// whose definitions (transitively) led to it - i.e. if referencing i from the current definition creates a cycle.
+func (fs *footnotes) isCyclic(i int) bool {
+	for j, ok := fs.current-1, fs.current != 0; ok; j, ok = fs.parents[j] {
+		if j == i {
+			return true
+		}
+	}
+	return false
+}
+
 func (fs *footnotes) updateDefinition(f FootnoteDefinition) {
 	if i, ok := fs.mapping[f.Name]; ok {
 		fs.list[i] = &f
@@ -1,8 +1,16 @@
 package org
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"unicode"
+	"unicode/utf8"
 )
 
 type ExtendedHTMLWriter struct {
@@ -56,3 +64,1022 @@ func TestPrettyRelativeLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestVerseLineBreaks(t *testing.T) {
+	input := "#+BEGIN_VERSE\nGreat clouds *overhead*\n  Tiny black birds rise and fall\n\n    Snow covers [[https://www.gnu.org/software/emacs/][Emacs]]\n#+END_VERSE\n"
+	if actual := String(New().Silent().Parse(strings.NewReader(input), "./verse.org").Nodes); actual != input {
+		t.Errorf("expected verse block to round trip:\n%s", diff(actual, input))
+	}
+	testHTMLWriterContains(t, map[string]string{
+		input: "<p class=\"verse\">\nGreat clouds <strong>overhead</strong><br>\n&#xa0;&#xa0;Tiny black birds rise and fall<br>\n<br>\n" +
+			"&#xa0;&#xa0;&#xa0;&#xa0;Snow covers <a href=\"https://www.gnu.org/software/emacs/\">Emacs</a><br>\n</p>\n",
+	}, func(w *HTMLWriter) { w.VerseLineBreaks = true })
+	testHTMLWriterContains(t, map[string]string{
+		input: "<div class=\"verse-block\">\n<p>Great clouds <strong>overhead</strong>",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestTagLinkFormat(t *testing.T) {
+	input := "#+FILETAGS: :blog:go:\n* a :c#:tips:\n"
+	testHTMLWriterContains(t, map[string]string{
+		input: `<span class="tags"><a href="/tags/c%23">c#</a>&#xa0;<a href="/tags/tips">tips</a></span>`,
+	}, func(w *HTMLWriter) { w.TagLinkFormat = "/tags/%s" })
+	testHTMLWriterContains(t, map[string]string{
+		input: `<p class="filetags"><span class="tags"><a href="/tags/blog">blog</a>&#xa0;<a href="/tags/go">go</a></span></p>`,
+	}, func(w *HTMLWriter) { w.TagLinkFormat = "/tags/%s" })
+	testHTMLWriterContains(t, map[string]string{
+		input: `<span class="tags"><span>c#</span>&#xa0;<span>tips</span></span>`,
+	}, func(w *HTMLWriter) {})
+	if out, _ := New().Silent().Parse(strings.NewReader(input), "./tags.org").Write(NewHTMLWriter()); strings.Contains(out, "filetags") {
+		t.Errorf("expected no filetags without TagLinkFormat: %s", out)
+	}
+}
+
+func TestPlanning(t *testing.T) {
+	input := "* TODO a\nSCHEDULED: <2024-01-02 Tue> CLOSED: [2024-01-03 Wed]\ntext\n"
+	testHTMLWriterContains(t, map[string]string{
+		"#+OPTIONS: p:t\n" + input: "<div id=\"outline-text-headline-1\" class=\"outline-text-2\">\n<div class=\"planning\">\n" +
+			"<span class=\"planning-keyword\">SCHEDULED:</span> <span class=\"timestamp\">&lt;2024-01-02 Tue&gt;</span> " +
+			"<span class=\"planning-keyword\">CLOSED:</span> <span class=\"timestamp\">[2024-01-03 Wed]</span>\n</div>\n<p>text</p>",
+		input: "<div id=\"outline-text-headline-1\" class=\"outline-text-2\">\n<p>text</p>",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestExecutableLinks(t *testing.T) {
+	tests := map[string]string{
+		"[[elisp:(org-agenda)][agenda]]": "<p>agenda</p>",
+		"[[shell:rm -rf ~][*clean*]]":    "<p><strong>clean</strong></p>",
+		"[[elisp:(kill-emacs)]]":         "<p>elisp:(kill-emacs)</p>",
+	}
+	testHTMLWriterContains(t, tests, func(w *HTMLWriter) { w.BaseURL = "https://example.com/" })
+	for input := range tests {
+		if out, _ := New().Silent().Parse(strings.NewReader(input), "./executable.org").Write(NewHTMLWriter()); strings.Contains(out, "href") {
+			t.Errorf("%s: expected no href, got %s", input, out)
+		}
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"[[./notes.org][notes]]":                             `<a href="https://example.com/docs/notes.html">notes</a>`,
+		"[[../other/notes.org::*Heading]]":                   `<a href="https://example.com/other/notes.html::*Heading">`,
+		"[[notes.org#intro][intro]]":                         `<a href="https://example.com/docs/notes.html#intro">intro</a>`,
+		"[[img/pic.png]]":                                    `<img src="https://example.com/docs/img/pic.png"`,
+		"[[file:a b.pdf][pdf]]":                              `<a href="https://example.com/docs/a%20b.pdf">pdf</a>`,
+		"[[https://go.dev/x.org][go]]":                       `<a href="https://go.dev/x.org">go</a>`,
+		"[[mailto:a@example.com][mail]]":                     `<a href="mailto:a@example.com">mail</a>`,
+		"<<target>> [[target][t]]":                           `<a href="#target">t</a>`,
+		"* a\n:PROPERTIES:\n:CUSTOM_ID: a\n:END:\n[[#a][a]]": `<a href="#a">a</a>`,
+	}, func(w *HTMLWriter) { w.BaseURL = "https://example.com/docs/" })
+	testHTMLWriterContains(t, map[string]string{
+		"[[notes.org::*Heading]]": `<a href="notes.html::*Heading">`,
+	}, func(w *HTMLWriter) {})
+}
+
+var lineNumberAllCodeTests = map[string]string{
+	"#+BEGIN_SRC bash\necho a\necho b\n#+END_SRC":                                 "<span class=\"line-number\">1</span>echo a\n<span class=\"line-number\">2</span>echo b",
+	"#+BEGIN_SRC bash -n 5\necho a\n#+END_SRC":                                    "<span class=\"line-number\">5</span>echo a",
+	"#+BEGIN_SRC bash :number-lines nil\necho a\n#+END_SRC":                       "<pre>\necho a\n</pre>",
+	"#+BEGIN_SRC bash -n :number-lines nil\necho a\n#+END_SRC":                    "<span class=\"line-number\">1</span>echo a",
+	"#+BEGIN_SRC bash\necho a\n#+END_SRC\n#+BEGIN_SRC bash +n\necho b\n#+END_SRC": "<span class=\"line-number\">2</span>echo b",
+	"#+BEGIN_EXAMPLE\na\n#+END_EXAMPLE":                                           "<span class=\"line-number\">1</span>a",
+	"#+BEGIN_EXAMPLE :number-lines nil\na\n#+END_EXAMPLE":                         "<pre class=\"example\">\na\n</pre>",
+	": a\n: b": "<span class=\"line-number\">1</span>a\n<span class=\"line-number\">2</span>b",
+}
+
+func TestLineNumberAllCode(t *testing.T) {
+	testHTMLWriterContains(t, lineNumberAllCodeTests, func(w *HTMLWriter) { w.LineNumberAllCode = true })
+}
+
+var continuedLineNumberTests = map[string]string{
+	"#+BEGIN_SRC bash -n\necho a\necho b\n#+END_SRC\n#+BEGIN_SRC bash +n\necho c\n#+END_SRC":                                "<span class=\"line-number\">3</span>echo c",
+	"#+BEGIN_SRC bash -n\necho a\necho b\n#+END_SRC\n#+BEGIN_SRC bash +n 10\necho c\n#+END_SRC":                             "<span class=\"line-number\">12</span>echo c",
+	"#+BEGIN_SRC bash -n 5\necho a\n#+END_SRC\n#+BEGIN_SRC bash\necho b\n#+END_SRC\n#+BEGIN_SRC bash +n\necho c\n#+END_SRC": "<span class=\"line-number\">6</span>echo c",
+	"#+BEGIN_EXAMPLE -n 3\na\nb\n#+END_EXAMPLE\n#+BEGIN_SRC bash +n\necho c\n#+END_SRC\n":                                   "<span class=\"line-number\">5</span>echo c",
+	"#+BEGIN_SRC bash -n\n#+END_SRC\n#+BEGIN_SRC bash +n\necho a\n#+END_SRC":                                                "<span class=\"line-number\">1</span>echo a",
+}
+
+func TestContinuedLineNumbers(t *testing.T) {
+	testHTMLWriterContains(t, continuedLineNumberTests, func(w *HTMLWriter) {})
+}
+
+var tableFigureCaptionTests = map[string]string{
+	"#+CAPTION: a table\n| a |":                      "<figure>\n<table>\n<tbody>\n<tr>\n<td>a</td>\n</tr>\n</tbody>\n</table>\n<figcaption>\na table\n</figcaption>\n</figure>",
+	"#+CAPTION: a block\n#+BEGIN_QUOTE\n#+END_QUOTE": "<figure>\n<blockquote>\n</blockquote>\n<figcaption>\na block\n</figcaption>\n</figure>",
+}
+
+func TestTableFigureCaptions(t *testing.T) {
+	testHTMLWriterContains(t, tableFigureCaptionTests, func(w *HTMLWriter) { w.TableFigureCaptions = true })
+}
+
+var prettifyTextTests = map[string]string{
+	"a -> b => c <- d":                        "<p>a → b ⇒ c ← d</p>",
+	"wait...":                                 "<p>wait…</p>",
+	"~a -> b~ and =c => d=":                   `<p><code>a -&gt; b</code> and <code class="verbatim">c =&gt; d</code></p>`,
+	"[[https://example.com][a -> b]]":         `<p><a href="https://example.com">a -&gt; b</a></p>`,
+	"#+BEGIN_SRC sh\necho a --> b\n#+END_SRC": "echo a --&gt; b",
+	": a -> b":                                "a -&gt; b",
+	`"a" said 'b', don't`:                     "<p>“a” said ‘b’, don’t</p>",
+	`(*a*'s "/b/")`:                           "<p>(<strong>a</strong>’s “<em>b</em>”)</p>",
+	`"~a "b"~"`:                               `<p>“<code>a &#34;b&#34;</code>”</p>`,
+	"#+BEGIN_EXAMPLE\n\"a\"\n#+END_EXAMPLE":   "\n&#34;a&#34;\n",
+}
+
+func TestPrettifyText(t *testing.T) {
+	testHTMLWriterContains(t, prettifyTextTests, func(w *HTMLWriter) { w.PrettifyText = true })
+	testHTMLWriterContains(t, map[string]string{
+		`"a"`:                        "<p>&#34;a&#34;</p>",
+		"#+OPTIONS: ':t\n\"a -> b\"": "<p>“a -&gt; b”</p>",
+	}, func(w *HTMLWriter) {})
+}
+
+var rightToLeftTests = map[string]string{
+	"#+OPTIONS: rtl:t\nשלום עולם":     `<p dir="rtl">שלום עולם</p>`,
+	"#+OPTIONS: rtl:t\n*مرحبا* hello": `<p dir="rtl"><strong>مرحبا</strong> hello</p>`,
+	"#+OPTIONS: rtl:t\n2 hello שלום":  `<p>2 hello שלום</p>`,
+	"שלום עולם":                       `<p>שלום עולם</p>`,
+}
+
+func TestRightToLeftParagraphs(t *testing.T) {
+	testHTMLWriterContains(t, rightToLeftTests, func(w *HTMLWriter) {})
+}
+
+var attachmentTests = map[string]string{
+	"* a\n:PROPERTIES:\n:ID: abc\n:END:\n** b\n[[attachment:img.png]]": `<img src="/data/abc/img.png" alt="/data/abc/img.png" title="/data/abc/img.png" />`,
+	"* a\n:PROPERTIES:\n:DIR: dir\n:END:\n[[attachment:a.pdf][a pdf]]": `<a href="/data/dir/a.pdf">a pdf</a>`,
+	"[[attachment:a.pdf][a pdf]]":                                      `<p>a pdf</p>`,
+	"[[attachment:a.pdf]]":                                             `<p>a.pdf</p>`,
+}
+
+func TestAttachmentResolver(t *testing.T) {
+	testHTMLWriterContains(t, attachmentTests, func(w *HTMLWriter) {
+		w.AttachmentResolver = func(headlineID, path string) string {
+			if headlineID == "" {
+				return ""
+			}
+			return "/data/" + headlineID + "/" + path
+		}
+	})
+}
+
+type mapRenderCache struct {
+	entries    map[string]string
+	hits, sets int
+}
+
+func (c *mapRenderCache) Get(key string) (string, bool) {
+	html, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return html, ok
+}
+
+func (c *mapRenderCache) Set(key, html string) { c.entries[key], c.sets = html, c.sets+1 }
+
+func renderCacheDocument(sections int, changed string) string {
+	out := &strings.Builder{}
+	for i := 0; i < sections; i++ {
+		fmt.Fprintf(out, "* headline %d\nsome *text* with a [[https://example.com][link]]\n", i)
+		fmt.Fprintf(out, "** sub headline %d\n| a | table |\n|---+-------|\n| b | c |\n", i)
+		if i == sections/2 {
+			out.WriteString(changed + "\n")
+		}
+	}
+	return out.String()
+}
+
+func TestRenderCache(t *testing.T) {
+	cache := &mapRenderCache{entries: map[string]string{}}
+	render := func(input string, cache RenderCache) string {
+		w := NewHTMLWriter()
+		w.RenderCache = cache
+		out, err := New().Silent().Parse(strings.NewReader(input), "./cache.org").Write(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	a, b := renderCacheDocument(10, "a"), renderCacheDocument(10, "b")
+	if expected, actual := render(a, nil), render(a, cache); actual != expected {
+		t.Errorf("cached:\n%s", diff(actual, expected))
+	}
+	if cache.sets != 20 || cache.hits != 0 {
+		t.Errorf("expected 20 sets and 0 hits, got %d & %d", cache.sets, cache.hits)
+	}
+	cache.sets = 0
+	if expected, actual := render(b, nil), render(b, cache); actual != expected {
+		t.Errorf("cached:\n%s", diff(actual, expected))
+	}
+	// only the changed sub headline & its parent are rendered again - the other 9 top level headlines are cached.
+	if cache.sets != 2 || cache.hits != 9 {
+		t.Errorf("expected 2 sets and 9 hits, got %d & %d", cache.sets, cache.hits)
+	}
+	if out := render("* a[fn:1]\n\n[fn:1] a\n", cache); !strings.Contains(out, "footnotes") || cache.sets != 2 {
+		t.Errorf("expected headlines with footnotes to not be cached: %s", out)
+	}
+	offset := NewHTMLWriter()
+	offset.HeadlineOffset, offset.RenderCache = 2, cache
+	if out, err := New().Silent().Parse(strings.NewReader(a), "./cache.org").Write(offset); err != nil || !strings.Contains(out, `<h4 id="headline-1">`) {
+		t.Errorf("expected the writer configuration to be part of the cache key: %v", err)
+	}
+	cache.sets = 0
+	for _, input := range []string{"* a\n[[*b]]\n* b\n", "* a\n<<<radio>>>\n* b\nradio\n"} {
+		if render(input, cache); cache.sets != 1 {
+			t.Errorf("expected headlines with cross references to not be cached: %q", input)
+		}
+		cache.sets = 0
+	}
+	numbered := "#+OPTIONS: num:t\n* a\n* b\n"
+	render(numbered, cache)
+	if expected, actual := render(strings.Replace(numbered, "* a", "* a :noexport:", 1), nil), render(strings.Replace(numbered, "* a", "* a :noexport:", 1), cache); actual != expected {
+		t.Errorf("expected section numbers to be part of the cache key:\n%s", diff(actual, expected))
+	}
+}
+
+func BenchmarkRenderCache(b *testing.B) {
+	documents := []*Document{
+		New().Silent().Parse(strings.NewReader(renderCacheDocument(100, "a")), "./a.org"),
+		New().Silent().Parse(strings.NewReader(renderCacheDocument(100, "b")), "./b.org"),
+	}
+	for name, cache := range map[string]RenderCache{"uncached": nil, "cached": &mapRenderCache{entries: map[string]string{}}} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				w := NewHTMLWriter()
+				w.RenderCache = cache
+				if _, err := documents[i%2].Write(w); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+var radioTargetTests = map[string]string{
+	"a <<<cat>>>\nthe cat & the category":           `<p>a <a id="radio-target-cat">cat</a>` + "\n" + `the <a href="#radio-target-cat">cat</a> &amp; the category</p>`,
+	"<<<Org Mode>>>\nwe like org   mode.":           `we like <a href="#radio-target-org-mode">org   mode</a>.`,
+	"<<<cat>>> <<<cat food>>>\ncat food, cats":      `<a href="#radio-target-cat-food">cat food</a>, cats`,
+	"<<<cat>>>\n=cat= [[https://example.com][cat]]": `<code class="verbatim">cat</code> <a href="https://example.com">cat</a>`,
+	"use <<<x>>> before x":                          `use <a id="radio-target-x">x</a> before <a href="#radio-target-x">x</a>`,
+	"CAT\n\nthe <<<cat>>>":                          `<p><a href="#radio-target-cat">CAT</a></p>`,
+}
+
+func TestRadioTargets(t *testing.T) {
+	testHTMLWriterContains(t, radioTargetTests, func(w *HTMLWriter) {})
+}
+
+var headlineLvlTests = map[string]string{
+	"#+OPTIONS: H:2 toc:nil\n* a\n** b\n*** TODO c\ncontent\n*** d\n**** e\n": `<h3 id="headline-2">
+b
+</h3>
+<div id="outline-text-headline-2" class="outline-text-3">
+<ul>
+<li id="headline-3">
+<span class="todo TODO">TODO</span>
+c
+<p>content</p>
+</li>
+<li id="headline-4">
+d
+<ul>
+<li id="headline-5">
+e
+</li>
+</ul>
+</li>
+</ul>
+</div>`,
+	"#+OPTIONS: H:2\n* a\n** b\n*** c\n": `<li><a href="#headline-2">b</a>
+</li>`,
+	"* a\n** b\n*** c\n": `<h4 id="headline-3">`,
+}
+
+func TestHeadlineLvlOption(t *testing.T) {
+	testHTMLWriterContains(t, headlineLvlTests, func(w *HTMLWriter) {})
+}
+
+var nonBreakingSpaceTests = map[string]string{
+	"a\\nbsp{}b \\nbsp c":        `<p>a&nbsp;b &nbsp; c</p>`,
+	"a\u00a0b":                   `<p>a&nbsp;b</p>`,
+	"| \u00a0a\u00a0 | b |":      `<td>&nbsp;a&nbsp;</td>`,
+	"#+OPTIONS: e:nil\na\u00a0b": `<p>a&nbsp;b</p>`,
+}
+
+func TestNonBreakingSpaces(t *testing.T) {
+	testHTMLWriterContains(t, nonBreakingSpaceTests, func(w *HTMLWriter) {})
+	for input := range nonBreakingSpaceTests {
+		if out := New().Silent().Parse(strings.NewReader(input), "./nbsp.org").Nodes; !strings.Contains(String(out), "\u00a0") && strings.Contains(input, "\u00a0") {
+			t.Errorf("expected non-breaking space to survive round trip: %q", String(out))
+		}
+	}
+}
+
+var languageAliasTests = map[string]string{
+	"#+BEGIN_SRC elisp\n(+ 1 2)\n#+END_SRC":  `<div class="src src-elisp">` + "\nlisp\n</div>",
+	"#+BEGIN_SRC sh\necho\n#+END_SRC":        `<div class="src src-sh">` + "\nbash\n</div>",
+	"#+BEGIN_SRC cobol\nDISPLAY.\n#+END_SRC": `<div class="src src-cobol">` + "\ncobol\n</div>",
+	"src_elisp[:exports code]{(+ 1 2)}":      `<div class="src src-inline src-elisp">` + "\nlisp\n</div>",
+}
+
+func TestLanguageAliases(t *testing.T) {
+	testHTMLWriterContains(t, languageAliasTests, func(w *HTMLWriter) {
+		w.HighlightCodeBlock = func(source, lang string, inline bool, params map[string]string) string { return lang }
+	})
+}
+
+var tableSpanTests = map[string]string{
+	"| a | < | < | b |\n| c | d | e | f |": "<tr>\n<td colspan=\"3\">a</td>\n<td>b</td>\n</tr>\n<tr>\n<td>c</td>",
+	"| < | a | < |\n|---|\n| b | < | c |":  "<tr>\n<th>&lt;</th>\n<th colspan=\"2\">a</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td colspan=\"2\">b</td>\n<td>c</td>",
+	"| a | b |\n| ^ | c |\n| ^ | < |":      "<tr>\n<td rowspan=\"3\">a</td>\n<td>b</td>\n</tr>\n<tr>\n<td>c</td>\n</tr>\n<tr>\n<td>&lt;</td>",
+}
+
+func TestTableSpans(t *testing.T) {
+	testHTMLWriterContains(t, tableSpanTests, func(w *HTMLWriter) { w.TableColSpanMarker, w.TableRowSpanMarker = "<", "^" })
+	testHTMLWriterContains(t, map[string]string{
+		"| a | < |": "<tr>\n<td>a</td>\n<td>&lt;</td>\n</tr>",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestTranslations(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"foo[fn:1]\n\n[fn:1] bar":                    `<div class="footnotes" aria-label="Footnotes">`,
+		"#+LANGUAGE: fr\nfoo[fn:1]\n\n[fn:1] bar":    `<div class="footnotes" aria-label="Notes de bas de page">`,
+		"#+LANGUAGE: de-AT\nfoo[fn:1]\n\n[fn:1] bar": `<div class="footnotes" aria-label="Fußnoten">`,
+		"#+LANGUAGE: tlh\nfoo[fn:1]\n\n[fn:1] bar":   `<div class="footnotes" aria-label="Footnotes">`,
+		"#+LANGUAGE: de\n* a":                        `<nav aria-label="Inhaltsverzeichnis">`,
+	}, func(w *HTMLWriter) { w.AriaLabels = true })
+	testHTMLWriterContains(t, map[string]string{
+		"#+LANGUAGE: fr\nfoo[fn:1]\n\n[fn:1] bar": "<div class=\"footnotes\">\n",
+		"#+LANGUAGE: fr\n* a":                     "<nav>\n",
+	}, func(w *HTMLWriter) {})
+	c := New().Silent()
+	c.Translations = map[string]map[string]string{"fr": {"Footnotes": "Notes"}, "es": {"Footnotes": "Notas"}}
+	for input, expected := range map[string]string{
+		"#+LANGUAGE: fr\n": "Notes",
+		"#+LANGUAGE: es\n": "Notas",
+		"#+LANGUAGE: de\n": "Fußnoten",
+	} {
+		if actual := c.Parse(strings.NewReader(input), "./translations.org").Translate("Footnotes"); actual != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, actual)
+		}
+	}
+}
+
+var tabReplacementInput = "a\tb ~c\td~\n\n#+BEGIN_SRC go\ne\tf\n#+END_SRC\n\n#+BEGIN_EXAMPLE\ng\th\n#+END_EXAMPLE\n"
+
+func TestTabReplacement(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		tabReplacementInput: "<p>a\tb <code>c\td</code></p>",
+	}, func(w *HTMLWriter) {})
+	for _, replacement := range []string{"    ", "&#9;"} {
+		for _, expected := range []string{"<p>a" + replacement + "b <code>c\td</code></p>", "e\tf", "g\th"} {
+			testHTMLWriterContains(t, map[string]string{tabReplacementInput: expected}, func(w *HTMLWriter) { w.TabReplacement = replacement })
+		}
+	}
+}
+
+func TestDiagramLanguages(t *testing.T) {
+	mermaid := "#+BEGIN_SRC mermaid\ngraph TD; A-->B\n#+END_SRC\n"
+	testHTMLWriterContains(t, map[string]string{
+		mermaid: "<pre class=\"mermaid\">\ngraph TD; A--&gt;B\n</pre>\n",
+	}, func(w *HTMLWriter) { w.DiagramLanguages = []string{"mermaid", "plantuml"} })
+	testHTMLWriterContains(t, map[string]string{
+		mermaid: "<div class=\"src src-mermaid\">",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestSlugHeadlineIDs(t *testing.T) {
+	input := "#+OPTIONS: toc:t\n* Foo Bar\n* Foo bar!\n* Other\n:PROPERTIES:\n:CUSTOM_ID: foo-bar-2\n:END:\n* Foo Bar\n"
+	testHTMLWriterContains(t, map[string]string{
+		input: "<li><a href=\"#foo-bar\">Foo Bar</a>\n</li>\n<li><a href=\"#foo-bar-1\">Foo bar!</a>\n</li>\n" +
+			"<li><a href=\"#foo-bar-2\">Other</a>\n</li>\n<li><a href=\"#foo-bar-3\">Foo Bar</a>\n</li>\n",
+		input + "* ?\n": `<h2 id="headline-5">`,
+	}, func(w *HTMLWriter) { w.SlugHeadlineIDs = true })
+	testHTMLWriterContains(t, map[string]string{
+		input: `<h2 id="headline-1">`,
+	}, func(w *HTMLWriter) {})
+}
+
+func TestHeadlineIDPrecedence(t *testing.T) {
+	input := "* Both\n:PROPERTIES:\n:ID: abc\n:CUSTOM_ID: both\n:END:\n* ID\n:PROPERTIES:\n:ID: def\n:END:\n" +
+		"* Custom\n:PROPERTIES:\n:CUSTOM_ID: custom\n:END:\n* Custom\n"
+	toc := func(ids ...string) string {
+		out := ""
+		for i, title := range []string{"Both", "ID", "Custom", "Custom"} {
+			out += fmt.Sprintf("<li><a href=\"#%s\">%s</a>\n</li>\n", ids[i], title)
+		}
+		return out
+	}
+	tests := map[string]struct {
+		precedence []string
+		slug       bool
+		expected   string
+	}{
+		"default":              {nil, false, toc("both", "headline-2", "custom", "headline-4")},
+		"default slug":         {nil, true, toc("both", "id", "custom", "custom-1")},
+		"ID, CUSTOM_ID, slug":  {[]string{"ID", "CUSTOM_ID", "slug"}, false, toc("abc", "def", "custom", "custom-1")},
+		"CUSTOM_ID, ID, slug":  {[]string{"CUSTOM_ID", "ID", "slug"}, false, toc("both", "def", "custom", "custom-1")},
+		"ID":                   {[]string{"ID"}, false, toc("abc", "def", "headline-3", "headline-4")},
+		"slug, ID":             {[]string{"slug", "ID"}, false, toc("both", "id", "custom", "custom-1")},
+		"ID (SlugHeadlineIDs)": {[]string{"ID"}, true, toc("abc", "def", "headline-3", "headline-4")},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testHTMLWriterContains(t, map[string]string{input: test.expected}, func(w *HTMLWriter) {
+				w.HeadlineIDPrecedence, w.SlugHeadlineIDs = test.precedence, test.slug
+			})
+		})
+	}
+}
+
+func TestInternalLinks(t *testing.T) {
+	input := "* Foo Bar\n:PROPERTIES:\n:CUSTOM_ID: custom\n:END:\n* Other\n<<target>> and <<<radio>>>\n\n"
+	testHTMLWriterContains(t, map[string]string{
+		input + "[[#custom]]":            `<a href="#custom">#custom</a>`,
+		input + "[[*Other][other]]":      `<a href="#other">other</a>`,
+		input + "[[target]]":             `<a id="target"></a> and`,
+		input + "[[target][the target]]": `<a href="#target">the target</a>`,
+		input + "[[Radio]]":              `<a href="#radio-target-radio">Radio</a>`,
+		input + "[[Target][t]]":          `<a href="#target">t</a>`,
+		"<<My Target>> [[my target][t]]": `<a id="my-target"></a> <a href="#my-target">t</a>`,
+		input + "[[#missing][missing]]":  `<span class="broken-link">missing</span>`,
+		input + "[[*Missing]]":           `<span class="broken-link">Missing</span>`,
+		input + "[[missing]]":            `<span class="broken-link">missing</span>`,
+		input + "[[missing.org]]":        `<a href="missing.html">missing.html</a>`,
+	}, func(w *HTMLWriter) { w.SlugHeadlineIDs = true })
+}
+
+func TestDateHeader(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"#+TITLE: Post\n#+DATE: [2023-05-01 Mon]\ntext": "<h1 class=\"title\">Post</h1>\n<p class=\"date\">May 1, 2023</p>\n",
+		"#+DATE: <2023-05-01 Mon 10:30>\ntext":          `<p class="date">May 1, 2023</p>`,
+		"#+DATE: Spring <2023>\ntext":                   `<p class="date">Spring &lt;2023&gt;</p>`,
+		"#+DATE: [2023-05-01] or so\ntext":              `<p class="date">[2023-05-01] or so</p>`,
+	}, func(w *HTMLWriter) { w.TimestampFormat = "January 2, 2006" })
+	for _, options := range [][]Option{{WithTimestampFormat("2006"), WithDefaultSetting("OPTIONS", "date:nil")}, nil} {
+		if out, err := RenderHTML("#+DATE: [2023-05-01]\ntext", append(options, WithSilent())...); err != nil || strings.Contains(out, `class="date"`) {
+			t.Errorf("expected no date (%v): %s", err, out)
+		}
+	}
+}
+
+func TestBlockRenderers(t *testing.T) {
+	alert := func(b Block) string {
+		level := b.ParameterMap()[":level"]
+		return fmt.Sprintf("<div class=\"alert alert-%s\"><i class=\"icon-%s\"></i>%s</div>\n", level, level, strings.TrimSpace(String(b.Children)))
+	}
+	testHTMLWriterContains(t, map[string]string{
+		"#+BEGIN_ALERT :level warning\nCareful *now*\n#+END_ALERT\n": "<div class=\"alert alert-warning\"><i class=\"icon-warning\"></i>Careful *now*</div>\n",
+		"#+begin_alert :level info\nhi\n#+end_alert\n":               "<div class=\"alert alert-info\"><i class=\"icon-info\"></i>hi</div>\n",
+		"#+BEGIN_NOTE\nnote\n#+END_NOTE\n":                           "<div class=\"note-block\">\n<p>note</p>\n</div>\n",
+	}, func(w *HTMLWriter) { w.BlockRenderers = map[string]func(Block) string{"alert": alert} })
+	ignored := func(Block) string { return "ignored" }
+	for i := 0; i < 10; i++ {
+		testHTMLWriterContains(t, map[string]string{"#+BEGIN_ALERT\nhi\n#+END_ALERT\n": `<div class="alert alert-">`}, func(w *HTMLWriter) {
+			w.BlockRenderers = map[string]func(Block) string{"alert": ignored, "ALERT": alert, "Alert": ignored}
+		})
+	}
+}
+
+func TestHorizontalRuleClass(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"-----":      `<hr class="rule" data-dashes="5">`,
+		"----------": `<hr class="rule" data-dashes="10">`,
+	}, func(w *HTMLWriter) { w.HorizontalRuleClass = "rule" })
+	testHTMLWriterContains(t, map[string]string{
+		"----------": "<hr>\n",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestInlineImages(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := png.Encode(b, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+	fsys := fstest.MapFS{"images/pixel.png": {Data: b.Bytes()}}
+	testHTMLWriterContains(t, map[string]string{
+		"[[file:images/pixel.png]]":                      `<img src="` + data + `" alt="images/pixel.png" title="images/pixel.png" />`,
+		"[[./images/pixel.png]]":                         `<img src="` + data + `" alt="./images/pixel.png"`,
+		"[[https://example.com][file:images/pixel.png]]": `<a href="https://example.com"><img src="` + data + `" alt="images/pixel.png" /></a>`,
+		"[[missing.png]]":                                `<img src="missing.png" alt="missing.png" title="missing.png" />`,
+		"[[https://example.com/images/pixel.png]]":       `<img src="https://example.com/images/pixel.png"`,
+	}, func(w *HTMLWriter) { w.InlineImages = fsys })
+	testHTMLWriterContains(t, map[string]string{
+		"[[file:images/pixel.png]]": `<img src="images/pixel.png"`,
+	}, func(w *HTMLWriter) { w.InlineImages, w.InlineImageMaxSize = fsys, 1 })
+}
+
+func TestGitHubSlugStyle(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":                 "hello-world",
+		"C++ & Go":                      "c--go",
+		"What's new in v1.2?":           "whats-new-in-v12",
+		"Über Größe":                    "über-größe",
+		"snake_case  and  kebab-case":   "snake_case--and--kebab-case",
+		"/emphasis/ and ~code~ (maybe)": "emphasis-and-code-maybe",
+		"日本語のタイトル":                      "日本語のタイトル",
+	}
+	for title, expected := range tests {
+		testHTMLWriterContains(t, map[string]string{
+			"* " + title + "\n":                  fmt.Sprintf(`<h2 id="%s">`, expected),
+			"* " + title + "\n* " + title + "\n": fmt.Sprintf(`<h2 id="%s-1">`, expected),
+		}, func(w *HTMLWriter) { w.SlugHeadlineIDs, w.SlugStyle = true, "github" })
+	}
+}
+
+func TestCheckboxInputs(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"- [X] a\n- [-] b\n  - [ ] c\n": "<ul>\n<li class=\"checked\"><input type=\"checkbox\" checked disabled> a</li>\n" +
+			"<li class=\"indeterminate\"><input type=\"checkbox\" aria-checked=\"mixed\" disabled> \n<p>b</p>\n" +
+			"<ul>\n<li class=\"unchecked\"><input type=\"checkbox\" disabled> c</li>\n</ul>\n</li>\n</ul>\n",
+		"- [X] term :: details\n": "<dt class=\"checked\">\n<input type=\"checkbox\" checked disabled> term\n</dt>",
+		"- no checkbox\n":         "<li>no checkbox</li>",
+	}, func(w *HTMLWriter) { w.CheckboxInputs = true })
+}
+
+func TestTOCMaxLvl(t *testing.T) {
+	input := "* a\n** b\n*** c\n"
+	testHTMLWriterContains(t, map[string]string{
+		input:                        "<nav>\n<ul>\n<li><a href=\"#headline-1\">a</a>\n<ul>\n<li><a href=\"#headline-2\">b</a>\n</li>\n</ul>\n</li>\n</ul>\n</nav>\n",
+		"#+OPTIONS: toc:1\n" + input: "<nav>\n<ul>\n<li><a href=\"#headline-1\">a</a>\n</li>\n</ul>\n</nav>\n",
+	}, func(w *HTMLWriter) { w.TOCMaxLvl = 2 })
+}
+
+func TestTOCMinLvl(t *testing.T) {
+	input := "* title\n** a\n*** b\n** c\n"
+	testHTMLWriterContains(t, map[string]string{
+		input:                        "<nav>\n<ul>\n<li><a href=\"#headline-2\">a</a>\n<ul>\n<li><a href=\"#headline-3\">b</a>\n</li>\n</ul>\n</li>\n<li><a href=\"#headline-4\">c</a>\n</li>\n</ul>\n</nav>\n",
+		"#+OPTIONS: toc:2\n" + input: "<nav>\n<ul>\n<li><a href=\"#headline-2\">a</a>\n</li>\n<li><a href=\"#headline-4\">c</a>\n</li>\n</ul>\n</nav>\n",
+	}, func(w *HTMLWriter) { w.TOCMinLvl = 2 })
+}
+
+func TestHeadlineOffset(t *testing.T) {
+	input := "* 1\n** 2\n*** 3\n**** 4\n***** 5\n****** 6\n******* 7\n"
+	testHTMLWriterContains(t, map[string]string{
+		input: "<h2 id=\"headline-1\">\n1\n</h2>",
+	}, func(w *HTMLWriter) {})
+	testHTMLWriterContains(t, map[string]string{
+		input: "<div id=\"outline-container-headline-1\" class=\"outline-4\">\n<h4 id=\"headline-1\">\n1\n</h4>",
+	}, func(w *HTMLWriter) { w.HeadlineOffset = 2 })
+	d := New().Silent().Parse(strings.NewReader(input), "./headlineOffset.org")
+	w := NewHTMLWriter()
+	w.HeadlineOffset = 2
+	out, err := d.Write(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "<h7") || strings.Contains(out, "<h8") || strings.Count(out, "<h6") != 5 {
+		t.Errorf("expected headlines to be clamped at <h6>:\n%s", out)
+	}
+}
+
+func TestXHTML(t *testing.T) {
+	input := "- [X] a\\\\\n  b\n- [ ] [[file:c.png]]\n-----\n"
+	setup := func(xhtml bool) func(w *HTMLWriter) {
+		return func(w *HTMLWriter) { w.CheckboxInputs, w.XHTML = true, xhtml }
+	}
+	testHTMLWriterContains(t, map[string]string{
+		input: "<input type=\"checkbox\" checked disabled> a<br>\nb",
+	}, setup(false))
+	testHTMLWriterContains(t, map[string]string{
+		input: "<input type=\"checkbox\" disabled> <img src=\"c.png\" alt=\"c.png\" title=\"c.png\" />",
+	}, setup(false))
+	testHTMLWriterContains(t, map[string]string{
+		input: "</ul>\n<hr>\n",
+	}, setup(false))
+	testHTMLWriterContains(t, map[string]string{
+		input: "<input type=\"checkbox\" checked=\"checked\" disabled=\"disabled\" /> a<br />\nb",
+	}, setup(true))
+	testHTMLWriterContains(t, map[string]string{
+		input: "<input type=\"checkbox\" disabled=\"disabled\" /> <img src=\"c.png\" alt=\"c.png\" title=\"c.png\" />",
+	}, setup(true))
+	testHTMLWriterContains(t, map[string]string{
+		input: "</ul>\n<hr />\n",
+	}, setup(true))
+}
+
+func TestResultsType(t *testing.T) {
+	block := func(results string) string {
+		return "#+BEGIN_SRC sh :exports results" + results + "\necho\n#+END_SRC\n\n#+RESULTS:\n: <b>x</b>\n"
+	}
+	testHTMLWriterContains(t, map[string]string{
+		block(" :results html"):     "<b>x</b>\n",
+		block(" :results raw"):      "<b>x</b>\n",
+		block(" :results verbatim"): "<pre class=\"example\">\n&lt;b&gt;x&lt;/b&gt;\n</pre>\n",
+		block(""):                   "<pre class=\"example\">\n&lt;b&gt;x&lt;/b&gt;\n</pre>\n",
+		"#+BEGIN_SRC sh :results output html\necho\n#+END_SRC\n\n#+RESULTS:\n#+begin_example\n<i>y</i>\n#+end_example\n": "</div>\n<i>y</i>\n",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestTimestampClasses(t *testing.T) {
+	input := "<2024-01-02 Tue> and [2024-01-03 Wed]\n"
+	testHTMLWriterContains(t, map[string]string{
+		input:                             `<p><span class="timestamp">&lt;2024-01-02 Tue&gt;</span> and <span class="timestamp">[2024-01-03 Wed]</span></p>`,
+		"#+OPTIONS: <:active\n" + input:   `<p><span class="timestamp">&lt;2024-01-02 Tue&gt;</span> and </p>`,
+		"#+OPTIONS: <:inactive\n" + input: `<p> and <span class="timestamp">[2024-01-03 Wed]</span></p>`,
+	}, func(w *HTMLWriter) {})
+	testHTMLWriterContains(t, map[string]string{
+		input: `<p><span class="timestamp timestamp-active">&lt;2024-01-02 Tue&gt;</span> and <span class="timestamp timestamp-inactive">[2024-01-03 Wed]</span></p>`,
+	}, func(w *HTMLWriter) { w.TimestampClasses = true })
+	testHTMLWriterContains(t, map[string]string{
+		input: `<p><span class="timestamp">&lt;2024-01-02 Tue&gt;</span> and </p>`,
+	}, func(w *HTMLWriter) { w.HideInactiveTimestamps = true })
+}
+
+func TestHighlightCodeBlock(t *testing.T) {
+	input := "#+BEGIN_SRC go\nfmt.Println(\"<a>\")\n#+END_SRC\n"
+	testHTMLWriterContains(t, map[string]string{
+		input: "<div class=\"src src-go\">\n<div class=\"highlight\">\n<pre>\nfmt.Println(&#34;&lt;a&gt;&#34;)\n</pre>\n</div>\n</div>\n",
+	}, func(w *HTMLWriter) { w.HighlightCodeBlock = nil })
+	testHTMLWriterContains(t, map[string]string{
+		input: "<div class=\"src src-go\">\ngo: fmt.Println(\"<a>\")\n</div>\n",
+	}, func(w *HTMLWriter) {
+		w.HighlightCodeBlock = func(source, lang string, inline bool, params map[string]string) string { return lang + ": " + source }
+	})
+}
+
+func TestBuiltinMacros(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"#+OPTIONS: title:nil\n#+TITLE: /The/ Title\n#+AUTHOR: Jane\n#+DATE: 2024\n{{{title}}} by {{{author}}}, {{{date}}}\n": "<p><em>The</em> Title by Jane, 2024</p>",
+		"#+AUTHOR: Jane\n#+MACRO: author someone else\n{{{author}}}\n":                                                        "<p>someone else</p>",
+	}, func(w *HTMLWriter) {})
+}
+
+var srcBlockFoldingTests = map[string]string{
+	"#+BEGIN_SRC go :hidden t\nfmt.Println()\n#+END_SRC":        "<details class=\"src-details\">\n<summary>go</summary>\n<div class=\"src src-go\">",
+	"#+BEGIN_SRC sh :hidden \"Show solution\"\necho\n#+END_SRC": "<details class=\"src-details\">\n<summary>Show solution</summary>\n<div class=\"src src-sh\">",
+	"#+BEGIN_SRC go\n1\n2\n3\n4\n#+END_SRC":                     "<details class=\"src-details\">\n<summary>go</summary>",
+	"#+BEGIN_SRC go :hidden t\necho\n#+END_SRC\n":               "</pre>\n</div>\n</div>\n</details>\n",
+}
+
+func TestSrcBlockFolding(t *testing.T) {
+	testHTMLWriterContains(t, srcBlockFoldingTests, func(w *HTMLWriter) { w.FoldSrcBlockLines = 3 })
+	for _, input := range []string{"#+BEGIN_SRC go\n1\n2\n3\n4\n#+END_SRC", "#+BEGIN_SRC go :hidden nil\n1\n2\n3\n4\n#+END_SRC"} {
+		for _, lines := range []int{0, 3} {
+			w := NewHTMLWriter()
+			w.FoldSrcBlockLines = lines
+			out, _ := New().Silent().Parse(strings.NewReader(input), "./fold.org").Write(w)
+			if folded := strings.Contains(out, "<details"); folded != (lines == 3 && !strings.Contains(input, ":hidden nil")) {
+				t.Errorf("%q (FoldSrcBlockLines %d): unexpected folding: %s", input, lines, out)
+			}
+		}
+	}
+}
+
+var statisticProgressTests = map[string]string{
+	"- groceries [/]\n  - [X] milk\n  - [ ] eggs\n  - [X] bread\n  - [-] fruit\n": `groceries <code class="statistic statistic-cookie">[2/4]</code><progress max="4" value="2">2/4</progress>`,
+	"- groceries [%]\n  - [X] milk\n  - [ ] eggs\n  - [X] bread\n  - [ ] fruit\n": `groceries <code class="statistic statistic-cookie">[50%]</code><progress max="4" value="2">50%</progress>`,
+	"* groceries [0/0]\n- [X] milk\n- [ ] eggs\n- [X] bread\n- [ ] fruit\n":       `groceries <code class="statistic statistic-cookie">[2/4]</code><progress max="4" value="2">2/4</progress>`,
+	"- no checkboxes [/]\n  - milk\n":                                             `no checkboxes <code class="statistic statistic-cookie">[/]</code>`,
+}
+
+func TestStatisticProgress(t *testing.T) {
+	testHTMLWriterContains(t, statisticProgressTests, func(w *HTMLWriter) { w.StatisticProgress = true })
+	testHTMLWriterContains(t, map[string]string{
+		"- groceries [1/4]\n  - [X] milk\n  - [ ] eggs\n  - [X] bread\n  - [ ] fruit\n": `<p>groceries <code class="statistic statistic-cookie">[1/4]</code></p>`,
+	}, func(w *HTMLWriter) {})
+}
+
+var sectionNumberTests = map[string]string{
+	"#+STARTUP: num\n* a\n** b\n* c\n":                                "<h3 id=\"headline-2\">\n<span class=\"section-number section-number-3\">1.1</span>\nb\n</h3>",
+	"#+STARTUP: nonum\n#+OPTIONS: num:t\n* a\n* b\n":                  "<h2 id=\"headline-2\">\n<span class=\"section-number section-number-2\">2</span>\nb\n</h2>",
+	"#+OPTIONS: num:1\n* a\n** b\n":                                   "<h3 id=\"headline-2\">\nb\n</h3>",
+	"#+STARTUP: num\n* a\n:PROPERTIES:\n:UNNUMBERED: t\n:END:\n* b\n": "<li><a href=\"#headline-2\"><span class=\"section-number section-number-2\">1</span> b</a>",
+	"#+STARTUP: num\n* COMMENT a\n** b\n* c\n":                        "<h2 id=\"headline-3\">\n<span class=\"section-number section-number-2\">1</span>\nc\n</h2>",
+	"#+STARTUP: num\n* TODO a\n** b\n* c\n":                           "<h2 id=\"headline-3\">\n<span class=\"section-number section-number-2\">1</span>\nc\n</h2>",
+	"#+STARTUP: num\n* DONE a\n* c\n":                                 "<h2 id=\"headline-1\">\n<span class=\"done DONE\">DONE</span>\na\n</h2>",
+}
+
+func TestSectionNumbers(t *testing.T) {
+	testHTMLWriterContains(t, sectionNumberTests, func(w *HTMLWriter) {})
+	testHTMLWriterContains(t, map[string]string{
+		"#+STARTUP: num\n* a\n": "<h3 id=\"headline-1\">\n<span class=\"section-number section-number-3\">1</span>\na\n</h3>",
+	}, func(w *HTMLWriter) { w.HeadlineOffset = 1 })
+	for _, input := range []string{"* a\n", "#+STARTUP: num\n#+OPTIONS: num:nil\n* a\n", "#+STARTUP: nonum\n* a\n"} {
+		if out, _ := New().Silent().Parse(strings.NewReader(input), "./num.org").Write(NewHTMLWriter()); strings.Contains(out, "section-number") {
+			t.Errorf("%q: expected no section numbers: %s", input, out)
+		}
+	}
+}
+
+var abbreviationTests = map[string]string{
+	"#+ABBREV: WHO World Health Organization\nThe WHO, not the who or WHOM.":  `<p>The <abbr title="World Health Organization">WHO</abbr>, not the who or WHOM.</p>`,
+	"#+ABBREV: HTML Hypertext \"Markup\" Language\n~HTML~ and =HTML= vs HTML": `<p><code>HTML</code> and <code class="verbatim">HTML</code> vs <abbr title="Hypertext &#34;Markup&#34; Language">HTML</abbr></p>`,
+	"#+ABBREV: EU European Union\n#+ABBREV: EUR Euro\nEUR in the EU (EURO)":   `<p><abbr title="Euro">EUR</abbr> in the <abbr title="European Union">EU</abbr> (EURO)</p>`,
+	"#+BEGIN_SRC go\nWHO\n#+END_SRC\n#+ABBREV: WHO World Health Organization": "<pre>\nWHO\n</pre>",
+}
+
+func TestAbbreviations(t *testing.T) {
+	testHTMLWriterContains(t, abbreviationTests, func(w *HTMLWriter) {})
+}
+
+var quoteAttributionTests = map[string]string{
+	"#+BEGIN_QUOTE\nTo be, or not to be.\n-- William /Shakespeare/\n#+END_QUOTE": "<blockquote>\n<p>To be, or not to be.</p>\n<footer>William <em>Shakespeare</em></footer>\n</blockquote>",
+	"#+BEGIN_QUOTE\nQuote\n\n— Anonymous\n#+END_QUOTE":                           "<blockquote>\n<p>Quote</p>\n<footer>Anonymous</footer>\n</blockquote>",
+	"#+BEGIN_QUOTE\nNot an -- attribution\n#+END_QUOTE":                          "<blockquote>\n<p>Not an – attribution</p>\n</blockquote>",
+	"#+BEGIN_QUOTE\nQuote[fn::note]\n-- Author\n#+END_QUOTE":                     `<a href="#footnote-reference-1">1</a></sup>`,
+}
+
+func TestQuoteAttribution(t *testing.T) {
+	testHTMLWriterContains(t, quoteAttributionTests, func(w *HTMLWriter) { w.QuoteAttribution = "footer" })
+	testHTMLWriterContains(t, map[string]string{
+		"#+BEGIN_QUOTE\nQuote\n-- Author\n#+END_QUOTE": "<blockquote>\n<p>Quote\n– Author</p>\n</blockquote>",
+	}, func(w *HTMLWriter) {})
+
+	d := New().Silent().Parse(strings.NewReader("#+BEGIN_QUOTE\nQuote[fn::note]\n-- Author\n#+END_QUOTE\n"), "")
+	w := NewHTMLWriter()
+	w.QuoteAttribution = "footer"
+	if out, err := d.Write(w); err != nil || strings.Count(out, `class="footnote-definition"`) != 1 {
+		t.Errorf("expected the footnote of the quote to be defined once: %q %v", out, err)
+	}
+}
+
+var responsiveImageTests = map[string]string{
+	"#+ATTR_HTML: :srcset \"a.png 1x, b.png 2x\" :sizes \"(max-width: 600px) 100vw, 50vw\" :alt a :b\n[[file:a.png]]": `<img src="a.png" alt="a :b" title="a.png" srcset="a.png 1x, b.png 2x" sizes="(max-width: 600px) 100vw, 50vw"/>`,
+	"#+ATTR_HTML: :srcset \"x.png :foo 1x\"\n[[file:a.png]]":                                                          `srcset="x.png :foo 1x"`,
+}
+
+func TestResponsiveImageAttributes(t *testing.T) {
+	testHTMLWriterContains(t, responsiveImageTests, func(w *HTMLWriter) {})
+	for input := range responsiveImageTests {
+		if out := New().Silent().Parse(strings.NewReader(input), "./image.org").Nodes[0].String(); out != input+"\n" {
+			t.Errorf("expected %q to round trip, got %q", input, out)
+		}
+	}
+}
+
+var htmlAttributeTests = map[string]string{
+	"#+ATTR_HTML: :class wide :loading lazy\n[[file:a.png]]":                 `<img src="a.png" alt="a.png" title="a.png" class="wide" loading="lazy"/>`,
+	"#+ATTR_HTML: :class wide :data-lang go\n#+BEGIN_SRC go\nx\n#+END_SRC":   `<div class="src src-go wide" data-lang="go">`,
+	"#+ATTR_HTML: :hidden :title \"a b\"\n#+BEGIN_QUOTE\nquote\n#+END_QUOTE": `<blockquote hidden="" title="a b">`,
+}
+
+func TestHTMLAttributes(t *testing.T) {
+	testHTMLWriterContains(t, htmlAttributeTests, func(w *HTMLWriter) {})
+	testHTMLWriterContains(t, map[string]string{
+		"#+ATTR_HTML: :hidden :title \"a b\"\n#+BEGIN_QUOTE\nquote\n#+END_QUOTE": `<blockquote hidden="hidden" title="a b">`,
+	}, func(w *HTMLWriter) { w.XHTML = true })
+	for input := range htmlAttributeTests {
+		if out := New().Silent().Parse(strings.NewReader(input), "./attributes.org").Nodes[0].String(); out != input+"\n" {
+			t.Errorf("expected %q to round trip, got %q", input, out)
+		}
+	}
+}
+
+var numberCaptionsInput = `See [[cat]] and [[dog][the dog]].
+
+#+NAME: cat
+#+CAPTION: A cat
+[[file:cat.png]]
+
+#+CAPTION: Some numbers
+| 1 | 2 |
+
+#+NAME: dog
+#+CAPTION: A dog
+[[file:dog.png]]
+`
+
+func TestNumberCaptions(t *testing.T) {
+	for _, expected := range []string{
+		`<p>See <a href="#cat">Figure 1</a> and <a href="#dog">the dog</a>.</p>`,
+		"<figure id=\"cat\">\n<img src=\"cat.png\" alt=\"cat.png\" title=\"cat.png\" /><figcaption>\n<span class=\"caption-label\">Figure 1:</span> A cat\n</figcaption>\n</figure>",
+		"<caption>\n<span class=\"caption-label\">Table 1:</span> Some numbers\n</caption>",
+		"<span class=\"caption-label\">Figure 2:</span> A dog",
+	} {
+		testHTMLWriterContains(t, map[string]string{numberCaptionsInput: expected}, func(w *HTMLWriter) { w.NumberCaptions = true })
+	}
+	testHTMLWriterContains(t, map[string]string{
+		"#+LANGUAGE: de\n" + numberCaptionsInput: `<p>See <a href="#cat">Abbildung 1</a>`,
+	}, func(w *HTMLWriter) { w.NumberCaptions = true })
+	testHTMLWriterContains(t, map[string]string{
+		numberCaptionsInput: "<figcaption>\nA cat\n</figcaption>",
+	}, func(w *HTMLWriter) {})
+}
+
+func TestCaptionFigures(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		"#+NAME: cat\n#+CAPTION: A cat\n#+ATTR_HTML: :width 100\n[[file:cat.png]]\n": "<figure id=\"cat\">\n<img src=\"cat.png\" alt=\"cat.png\" title=\"cat.png\" width=\"100\"/>\n<figcaption>\nA cat\n</figcaption>\n</figure>\n",
+		"#+CAPTION: A cat\n#+NAME: cat\n[[file:cat.png]]\n":                          "<figure id=\"cat\">\n<img src=\"cat.png\" alt=\"cat.png\" title=\"cat.png\" /><figcaption>\nA cat\n</figcaption>\n</figure>\n",
+		"#+NAME: numbers\n#+CAPTION: Numbers\n#+ATTR_HTML: :class t\n| 1 |\n":        "<table id=\"numbers\" class=\"t\">\n<caption>\nNumbers\n</caption>",
+		"#+NAME: code\n#+CAPTION: Code\n#+BEGIN_SRC go\nx\n#+END_SRC\n":              "<figure id=\"code\">\n<div class=\"src src-go\">",
+		"#+NAME: cat\n[[file:cat.png]]\n":                                            "<p><img src=\"cat.png\" alt=\"cat.png\" title=\"cat.png\" /></p>\n",
+		"#+NAME: tbl\n#+CAPTION: Numbers\n#+ATTR_HTML: :id mine\n| 1 |\n":            "<table id=\"mine\">\n<caption>",
+	}, func(w *HTMLWriter) {})
+}
+
+var footnoteOrderInput = "a[fn:b] b[fn:a] c[fn::anonymous]\n\n[fn:a] definition a\n\n[fn:b] definition b\n"
+
+func TestFootnoteOrder(t *testing.T) {
+	testHTMLWriterContains(t, map[string]string{
+		footnoteOrderInput: `a<sup class="footnote-reference"><a id="footnote-reference-1" href="#footnote-1">1</a></sup> b<sup class="footnote-reference"><a id="footnote-reference-2" href="#footnote-2">2</a></sup>`,
+	}, func(w *HTMLWriter) {})
+	testHTMLWriterContains(t, map[string]string{
+		footnoteOrderInput: `a<sup class="footnote-reference"><a id="footnote-reference-2" href="#footnote-2">2</a></sup> b<sup class="footnote-reference"><a id="footnote-reference-1" href="#footnote-1">1</a></sup> c<sup class="footnote-reference"><a id="footnote-reference-3" href="#footnote-3">3</a></sup>`,
+	}, func(w *HTMLWriter) { w.FootnotesInDefinitionOrder = true })
+}
+
+func TestNestedFootnotes(t *testing.T) {
+	input := "text[fn:1]\n\n[fn:1] one[fn:2]\n\n[fn:2] two[fn:1] and three[fn::[[https://example.com][inline]][fn:3]]\n\n[fn:3] four\n"
+	out, err := RenderHTML(input, WithSilent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<p>one<sup class="footnote-reference"><a id="footnote-reference-2" href="#footnote-2">2</a></sup></p>`,
+		`<p>two<sup class="footnote-reference"><a href="#footnote-1">1</a></sup> and three<sup class="footnote-reference"><a id="footnote-reference-3" href="#footnote-3">3</a></sup></p>`,
+		`<p><a href="https://example.com">inline</a><sup class="footnote-reference"><a id="footnote-reference-4" href="#footnote-4">4</a></sup></p>`,
+		`<sup id="footnote-4"><a href="#footnote-reference-4">4</a></sup>` + "\n" + `<div class="footnote-body">` + "\n<p>four</p>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected html to contain %s:\n%s", expected, out)
+		}
+	}
+}
+
+func TestFootnoteNumberingIsDeterministic(t *testing.T) {
+	input := footnoteOrderInput + "d[fn:undefined] e[fn:inline: inline definition] f[fn:b] g[fn::another anonymous]\n"
+	for _, inDefinitionOrder := range []bool{false, true} {
+		expected := ""
+		for i := 0; i < 100; i++ {
+			w := NewHTMLWriter()
+			w.FootnotesInDefinitionOrder = inDefinitionOrder
+			actual, err := New().Silent().Parse(strings.NewReader(input), "./footnotes.org").Write(w)
+			if err != nil {
+				t.Fatal(err)
+			} else if i == 0 {
+				expected = actual
+			} else if actual != expected {
+				t.Fatalf("render %d differs:\n%s", i, diff(actual, expected))
+			}
+		}
+	}
+}
+
+// testHTMLWriterContains checks that the html output for each org input (key) contains the expected html snippet (value).
+func testHTMLWriterContains(t *testing.T, tests map[string]string, setup func(*HTMLWriter)) {
+	for org, expected := range tests {
+		t.Run(org, func(t *testing.T) {
+			writer := NewHTMLWriter()
+			setup(writer)
+			actual, err := New().Silent().Parse(strings.NewReader(org), "./test.org").Write(writer)
+			if err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if !strings.Contains(actual, expected) {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected))
+			}
+		})
+	}
+}
+
+type Mention struct{ Name string }
+
+func (m Mention) String() string { return "@" + m.Name }
+
+var mentionParser = InlineParser{
+	Chars: "@＠",
+	Parse: func(input string, start int) (int, Node) {
+		_, size := utf8.DecodeRuneInString(input[start:])
+		end := start + size
+		for ; end < len(input) && unicode.IsLetter(rune(input[end])); end++ {
+		}
+		if end == start+size {
+			return 0, nil
+		}
+		return end - start, Mention{input[start+size : end]}
+	},
+}
+
+var inlineParserTests = map[string][]string{
+	"hello @user":        {`<p>hello <a class="mention" href="/users/user">user</a></p>`, "hello @user\n"},
+	"@@html:<b>b</b>@@":  {`<p><b>b</b></p>`, "@@html:<b>b</b>@@\n"},
+	"hello ＠user":        {`<p>hello <a class="mention" href="/users/user">user</a></p>`, "hello @user\n"},
+	"*@user* and @other": {`<p><strong><a class="mention" href="/users/user">user</a></strong> and <a class="mention" href="/users/other">other</a></p>`, "*@user* and @other\n"},
+}
+
+func TestInlineParsers(t *testing.T) {
+	for org, expected := range inlineParserTests {
+		t.Run(org, func(t *testing.T) {
+			conf := New().Silent()
+			conf.InlineParsers = []InlineParser{mentionParser}
+			htmlWriter := NewHTMLWriter()
+			htmlWriter.NodeRenderHook = func(w Writer, n Node) (string, bool) {
+				if m, ok := n.(Mention); ok {
+					return fmt.Sprintf(`<a class="mention" href="/users/%s">%s</a>`, m.Name, m.Name), true
+				}
+				return "", false
+			}
+			d := conf.Parse(strings.NewReader(org), "./inlineParserTests.org")
+			if actual, err := d.Write(htmlWriter); err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual := strings.TrimSpace(actual); actual != expected[0] {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected[0]))
+			}
+			if actual, err := d.Write(NewOrgWriter()); err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual != expected[1] {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected[1]))
+			}
+		})
+	}
+}
+
+var linkKindTests = []struct {
+	link         string
+	exts         []string
+	image, video bool
+}{
+	{"[[file:foo.png]]", nil, true, false},
+	{"[[./foo.jpeg]]", nil, true, false},
+	{"[[https://example.com/foo.svg]]", nil, true, false},
+	{"[[https://example.com/foo.mp4]]", nil, false, true},
+	{"[[https://example.com/foo.png][description]]", nil, false, false},
+	{"[[https://example.com][https://example.com/foo.png]]", nil, true, false},
+	{"[[id:foo.png]]", nil, false, false},
+	{"[[file:foo.webp]]", nil, false, false},
+	{"[[file:foo.WEBP]]", []string{".webp"}, true, false},
+	{"[[file:foo.png]]", []string{".webp"}, false, false},
+}
+
+func TestRegularLinkKind(t *testing.T) {
+	for _, test := range linkKindTests {
+		t.Run(test.link, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(test.link), "./linkKindTests.org")
+			l := d.Nodes[0].(Paragraph).Children[0].(RegularLink)
+			if l.IsImage(test.exts) != test.image || l.IsVideo(nil) != test.video {
+				t.Errorf("%s: expected image %v & video %v, got %v & %v", test.link, test.image, test.video, l.IsImage(test.exts), l.IsVideo(nil))
+			}
+		})
+	}
+}
+
+var descriptiveListTermTests = map[string]string{
+	"- [[https://example.com/a::b][Name]] :: desc": "<dt>\n<a href=\"https://example.com/a::b\">Name</a>\n</dt>\n<dd>desc</dd>",
+	"- [[https://example.com::b]] :: desc":         "<dt>\n<a href=\"https://example.com::b\">https://example.com::b</a>\n</dt>\n<dd>desc</dd>",
+	"- ~code~ :: desc":                             "<dt>\n<code>code</code>\n</dt>\n<dd>desc</dd>",
+	"- =a :: b= and /c/ :: desc":                   "<dt>\n<code class=\"verbatim\">a :: b</code> and <em>c</em>\n</dt>\n<dd>desc</dd>",
+}
+
+func TestDescriptiveListTerms(t *testing.T) {
+	testHTMLWriterContains(t, descriptiveListTermTests, func(w *HTMLWriter) {})
+}
+
+func TestIndexAnchors(t *testing.T) {
+	input := "* a\ntext[fn:1]\n\n[fn:1] see\n#+INDEX: alpha\n\n* b :noexport:\n#+INDEX: beta\n* c\n#+INDEX: delta\n* Index\n#+PRINT_INDEX: t\n"
+	out, err := New().Silent().Parse(strings.NewReader(input), "./index.org").Write(NewHTMLWriter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<li><a href="#index-1">alpha</a></li>`, `<li><a href="#index-3">delta</a></li>`,
+		"<div id=\"outline-text-headline-3\" class=\"outline-text-2\">\n<a id=\"index-3\"></a>",
+		"<p>see</p>\n<a id=\"index-1\"></a>",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestExportBlocks(t *testing.T) {
+	input := "#+BEGIN_EXPORT html\n<div class=\"raw\">a & b</div>\n#+END_EXPORT\n#+BEGIN_EXPORT latex\n\\newpage\n#+END_EXPORT\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./export.org")
+	if out, err := d.Write(NewHTMLWriter()); err != nil || out != "<div class=\"raw\">a & b</div>\n" {
+		t.Errorf("expected html export blocks verbatim & other export blocks to be dropped: %v\n%s", err, out)
+	}
+	if out, err := d.Write(NewOrgWriter()); err != nil || out != input {
+		t.Errorf("expected export blocks to round trip: %v\n%s", err, diff(out, input))
+	}
+}
+
+func TestCommentedContent(t *testing.T) {
+	input := "* COMMENT hidden\nsecret\n** child\n* shown\n#+CAPTION: nope\n#+BEGIN_COMMENT\nnope\n#+END_COMMENT\nvisible\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./comment.org")
+	for _, w := range []Writer{NewHTMLWriter(), NewMarkdownWriter(), NewPlainTextWriter()} {
+		if out, err := d.Write(w); err != nil || !strings.Contains(out, "visible") || strings.Contains(out, "hidden") || strings.Contains(out, "secret") || strings.Contains(out, "child") || strings.Contains(out, "nope") {
+			t.Errorf("%T: expected commented subtrees & comment blocks to be dropped: %v\n%s", w, err, out)
+		}
+	}
+	if out, err := d.Write(NewOrgWriter()); err != nil || out != input {
+		t.Errorf("expected commented content to round trip: %v\n%s", err, diff(out, input))
+	}
+	w := NewHTMLWriter()
+	w.NumberCaptions = true
+	input = "#+CAPTION: nope\n#+BEGIN_SRC go :exports none\nx\n#+END_SRC\n#+NAME: code\n#+CAPTION: shown\n#+BEGIN_SRC go\ny\n#+END_SRC\n[[code]]\n"
+	if out, err := New().Silent().Parse(strings.NewReader(input), "./comment.org").Write(w); err != nil || strings.Contains(out, "nope") || strings.Count(out, "<figure") != 1 || !strings.Contains(out, "Figure 1") || strings.Contains(out, "Figure 2") {
+		t.Errorf("expected captions of unexported blocks to be dropped: %v\n%s", err, out)
+	}
+}
+
+func TestTodoKeywordClasses(t *testing.T) {
+	input := "#+TODO: TODO NEXT WAITING | DONE CANCELLED\n* NEXT a\n* CANCELLED b\n* FOO c\n"
+	testHTMLWriterContains(t, map[string]string{
+		input:                   "<span class=\"todo NEXT\">NEXT</span>\na\n</h2>",
+		input + "* WAITING d\n": "<span class=\"done CANCELLED\">CANCELLED</span>\nb\n</h2>",
+		input + "* TODO e\n":    "<h2 id=\"headline-3\">\nFOO c\n</h2>",
+	}, func(w *HTMLWriter) {})
+	if out := New().Silent().Parse(strings.NewReader(input), "./todo.org").Nodes[1].String(); out != "* NEXT a\n" {
+		t.Errorf("expected custom todo keyword to round trip, got %q", out)
+	}
+}
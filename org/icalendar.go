@@ -0,0 +1,137 @@
+package org
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// VEvent is a calendar event exported from a timestamp of a headline (see Document.ICalendar).
+type VEvent struct {
+	UID        string   // UID is the ID property (or Headline.ID) of the headline prefixed with SC- (scheduled), DL- (deadline) or TS- (active timestamp).
+	Summary    string   // Summary is the plain text title of the headline.
+	Categories []string // Categories are the tags of the headline.
+	RRule      string   // RRule is the recurrence rule derived from the repeater of the timestamp (e.g. FREQ=WEEKLY;INTERVAL=2) - empty if it does not repeat.
+	Timestamp
+}
+
+var repeaterRegexp = regexp.MustCompile(`^(?:\+|\+\+|\.\+)(\d+)([hdwmy])`)
+
+var repeaterFrequencies = map[string]string{"h": "HOURLY", "d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY"}
+
+var icalendarEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// ICalendar returns an event for the SCHEDULED & DEADLINE timestamps and the active timestamps in
+// the title and direct content of each headline, in document order. Excluded headlines are skipped.
+func (d *Document) ICalendar() []VEvent {
+	events := []VEvent{}
+	var walk func([]Node)
+	walk = func(nodes []Node) {
+		for _, n := range nodes {
+			h, ok := n.(Headline)
+			if !ok || h.IsExcluded(d) {
+				continue
+			}
+			id, ok := h.Properties.Get("ID")
+			if !ok {
+				id = h.ID()
+			}
+			event := func(prefix string, t Timestamp) VEvent {
+				rrule := ""
+				if m := repeaterRegexp.FindStringSubmatch(t.Interval); m != nil {
+					rrule = fmt.Sprintf("FREQ=%s;INTERVAL=%s", repeaterFrequencies[m[2]], m[1])
+				}
+				return VEvent{prefix + id, strings.TrimSpace(plainText(h.Title)), h.Tags, rrule, t}
+			}
+			if h.Scheduled != nil {
+				events = append(events, event("SC-", *h.Scheduled))
+			}
+			if h.Deadline != nil {
+				events = append(events, event("DL-", *h.Deadline))
+			}
+			timestamps := []Timestamp{}
+			collect := func(n Node) {
+				if t, ok := n.(Timestamp); ok && t.IsActive {
+					timestamps = append(timestamps, t)
+				}
+			}
+			walkNodes(h.Title, collect)
+			for _, c := range h.Children {
+				if _, ok := c.(Headline); !ok {
+					walkNodes([]Node{c}, collect)
+				}
+			}
+			for i, t := range timestamps {
+				prefix := "TS-"
+				if i != 0 {
+					prefix = fmt.Sprintf("TS%d-", i)
+				}
+				events = append(events, event(prefix, t))
+			}
+			walk(h.Children)
+		}
+	}
+	walk(d.Nodes)
+	return events
+}
+
+// ICS returns the events of d (see Document.ICalendar) as an iCalendar (RFC 5545) file.
+// Times are exported as floating local times, i.e. without a time zone.
+func (d *Document) ICS() string {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//go-org//EN"}
+	for _, e := range d.ICalendar() {
+		lines = append(lines, "BEGIN:VEVENT", "UID:"+icalendarEscaper.Replace(e.UID), "DTSTAMP:"+stamp)
+		lines = append(lines, e.dates()...)
+		lines = append(lines, "SUMMARY:"+icalendarEscaper.Replace(e.Summary))
+		if len(e.Categories) != 0 {
+			categories := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				categories[i] = icalendarEscaper.Replace(c)
+			}
+			lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		if e.RRule != "" {
+			lines = append(lines, "RRULE:"+e.RRule)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	out := strings.Builder{}
+	for _, line := range lines {
+		out.WriteString(foldICalendarLine(line) + "\r\n")
+	}
+	return out.String()
+}
+
+// dates returns the DTSTART & DTEND lines of e. The DTEND of dates is exclusive, i.e. the day after the (last) day.
+func (e VEvent) dates() []string {
+	if e.IsDate && (!e.IsRange || e.EndIsDate) {
+		end := e.Time
+		if e.End != nil {
+			end = *e.End
+		}
+		return []string{"DTSTART;VALUE=DATE:" + e.Time.Format("20060102"), "DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format("20060102")}
+	}
+	dates := []string{"DTSTART:" + e.Time.Format("20060102T150405")}
+	if e.End != nil {
+		dates = append(dates, "DTEND:"+e.End.Format("20060102T150405"))
+	}
+	return dates
+}
+
+// foldICalendarLine splits lines longer than 75 octets into continuation lines starting with a space - without splitting utf8 characters.
+func foldICalendarLine(line string) string {
+	out, n := strings.Builder{}, 0
+	for _, r := range line {
+		if n+utf8.RuneLen(r) > 75 {
+			out.WriteString("\r\n ")
+			n = 1
+		}
+		out.WriteRune(r)
+		n += utf8.RuneLen(r)
+	}
+	return out.String()
+}
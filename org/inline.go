@@ -2,6 +2,7 @@ package org
 
 import (
 	"fmt"
+	"net/url"
 	"path"
 	"regexp"
 	"strings"
@@ -26,9 +27,26 @@ type StatisticToken struct{ Content string }
 type Timestamp struct {
 	Time     time.Time
 	IsDate   bool
-	Interval string
+	Interval string     // Interval is the repeater and/or delay cookie, e.g. +1w, .+1d/3d or +1m -2d.
+	IsActive bool       // IsActive is true for <...> timestamps and false for inactive [...] timestamps.
+	End      *time.Time // End is the end of ranges like <2024-01-02>--<2024-01-05> and <2024-01-02 Tue 10:00-12:00>.
+	IsRange  bool       // IsRange is true for ranges of two timestamps, e.g. [2024-01-02 Tue 10:00]--[2024-01-02 Tue 12:00] (as in CLOCK lines).
+
+	EndIsDate   bool   // EndIsDate is IsDate for the End of ranges of two timestamps - IsDate only applies to the start.
+	EndInterval string // EndInterval is the Interval of the End of ranges of two timestamps.
+}
+
+type RadioTarget struct{ Name string }
+
+// Entity is an entity like \alpha or \rightarrow{} - see Entity.UTF8 & Entity.HTML for its representations.
+type Entity struct {
+	Name   string // Name is the name of the entity without the leading backslash, e.g. alpha.
+	Braces bool   // Braces is true if the entity is terminated by {}, e.g. \alpha{}beta.
 }
 
+// Target is a <<target>> - internal links to its name ([[name]]) link to it.
+type Target struct{ Name string }
+
 type Emphasis struct {
 	Kind    string
 	Content []Node
@@ -40,6 +58,14 @@ type InlineBlock struct {
 	Children   []Node
 }
 
+// InlineBabelCall is an inline call of a named src block, e.g. call_square[:results raw](x=4)[:exports both].
+type InlineBabelCall struct {
+	Name         string
+	InsideHeader string // InsideHeader are the header arguments applied to the called block, e.g. :results raw.
+	Arguments    string
+	EndHeader    string // EndHeader are the header arguments applied to the result of the call, e.g. :exports both.
+}
+
 type LatexFragment struct {
 	OpeningPair string
 	ClosingPair string
@@ -52,7 +78,7 @@ type FootnoteLink struct {
 }
 
 type RegularLink struct {
-	Protocol    string
+	Protocol    string // Protocol is the link type (e.g. file, https, id) - empty for links without a protocol (e.g. [[./foo.org]]).
 	Description []Node
 	URL         string
 	AutoLink    bool
@@ -65,17 +91,25 @@ type Macro struct {
 
 var validURLCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~:/?#[]@!$&'()*+,;="
 var autolinkProtocols = regexp.MustCompile(`^(https?|ftp|file)$`)
+
+// linkTypeRegexp matches link types (e.g. https or file+sys) - paths with search options (e.g. ./notes.org::*Heading) have none.
+var linkTypeRegexp = regexp.MustCompile(`^[\w+-]+$`)
 var imageExtensionRegexp = regexp.MustCompile(`^[.](png|gif|jpe?g|svg|tiff?)$`)
 var videoExtensionRegexp = regexp.MustCompile(`^[.](webm|mp4)$`)
 
 var subScriptSuperScriptRegexp = regexp.MustCompile(`^([_^]){([^{}]+?)}`)
-var timestampRegexp = regexp.MustCompile(`^<(\d{4}-\d{2}-\d{2})( [A-Za-z]+)?( \d{2}:\d{2})?( \+\d+[dwmy])?>`)
+var unbracedSubScriptSuperScriptRegexp = regexp.MustCompile(`^([_^])(\*|[+-]?[\pL\pN.,\\]*[\pL\pN])`)
+var radioTargetRegexp = regexp.MustCompile(`^<<<([^<>\s](?:[^<>\n]*[^<>\s])?)>>>`)
+var entityRegexp = regexp.MustCompile(`^\\([a-zA-Z]+[0-9]*|_ +)(\{\})?`)
+var targetRegexp = regexp.MustCompile(`^<<([^<>\s](?:[^<>\n]*[^<>\s])?)>>`)
+var timestampRegexp = regexp.MustCompile(`^([<\[])(\d{4}-\d{2}-\d{2})( \pL+\.?)?( (\d{1,2}:\d{2})(?:-(\d{1,2}:\d{2}))?)?((?: (?:\+\+|\.\+|\+)\d+[hdwmy](?:/\d+[hdwmy])?)?(?: --?\d+[hdwmy])?)([>\]])`)
 var footnoteRegexp = regexp.MustCompile(`^\[fn:([\w-]*?)(:(.*?))?\]`)
-var statisticsTokenRegexp = regexp.MustCompile(`^\[(\d+/\d+|\d+%)\]`)
+var statisticsTokenRegexp = regexp.MustCompile(`^\[(\d*/\d*|\d*%)\]`)
 var latexFragmentRegexp = regexp.MustCompile(`(?s)^\\begin{(\w+)}(.*)\\end{(\w+)}`)
-var inlineBlockRegexp = regexp.MustCompile(`src_(\w+)(\[(.*)\])?{(.*)}`)
+var inlineBlockRegexp = regexp.MustCompile(`^src_(\w+)(?:\[([^\]\n]*)\])?{`)
+var inlineBabelCallRegexp = regexp.MustCompile(`^call_([\w-]+)(?:\[([^\]\n]*)\])?\(([^()\n]*)\)(?:\[([^\]\n]*)\])?`)
 var inlineExportBlockRegexp = regexp.MustCompile(`@@(\w+):(.*?)@@`)
-var macroRegexp = regexp.MustCompile(`{{{(.*)\((.*)\)}}}`)
+var macroRegexp = regexp.MustCompile(`^{{{([a-zA-Z][\w-]*)(?:\((.*?)\))?}}}`)
 
 var timestampFormat = "2006-01-02 Mon 15:04"
 var datestampFormat = "2006-01-02 Mon"
@@ -91,31 +125,40 @@ func (d *Document) parseInline(input string) (nodes []Node) {
 	previous, current := 0, 0
 	for current < len(input) {
 		rewind, consumed, node := 0, 0, (Node)(nil)
-		switch input[current] {
-		case '^':
+		if len(d.inlineParsers) != 0 {
+			consumed, node = d.parseCustomInline(input, current, true)
+		}
+		switch c := input[current]; {
+		case consumed != 0:
+		case c == '^':
 			consumed, node = d.parseSubOrSuperScript(input, current)
-		case '_':
+		case c == '_':
 			rewind, consumed, node = d.parseSubScriptOrEmphasisOrInlineBlock(input, current)
-		case '@':
+		case c == '@':
 			consumed, node = d.parseInlineExportBlock(input, current)
-		case '*', '/', '+':
+		case c == '*', c == '/', c == '+':
 			consumed, node = d.parseEmphasis(input, current, false)
-		case '=', '~':
+		case c == '=', c == '~':
 			consumed, node = d.parseEmphasis(input, current, true)
-		case '[':
+		case c == '[':
 			consumed, node = d.parseOpeningBracket(input, current)
-		case '{':
+		case c == '{':
 			consumed, node = d.parseMacro(input, current)
-		case '<':
-			consumed, node = d.parseTimestamp(input, current)
-		case '\\':
+		case c == '<':
+			consumed, node = d.parseTimestampOrRadioTarget(input, current)
+		case c == '\\':
 			consumed, node = d.parseExplicitLineBreakOrLatexFragment(input, current)
-		case '$':
+		case c == '$':
 			consumed, node = d.parseLatexFragment(input, current, 1)
-		case '\n':
+		case c == '\n':
 			consumed, node = d.parseLineBreak(input, current)
-		case ':':
+		case c == ':':
 			rewind, consumed, node = d.parseAutoLink(input, current)
+		case strings.IndexByte(d.EmphasisMarkers, c) != -1:
+			consumed, node = d.parseEmphasis(input, current, false)
+		}
+		if consumed == 0 && len(d.inlineParsers) != 0 {
+			consumed, node = d.parseCustomInline(input, current, false)
 		}
 		current -= rewind
 		if consumed != 0 {
@@ -138,6 +181,35 @@ func (d *Document) parseInline(input string) (nodes []Node) {
 	return nodes
 }
 
+// addInlineError records a ParseError at the end of before, the inline content of the current token preceding the problem (see addError).
+// The content of nested markup (e.g. emphasis) is parsed separately - positions are relative to its start.
+func (d *Document) addInlineError(before, message string) {
+	if d.line >= len(d.tokens) {
+		return
+	}
+	line, column := d.line, len(before)+1+d.tokens[d.line].lvl
+	if i := strings.LastIndexByte(before, '\n'); i != -1 {
+		line, column = line+strings.Count(before, "\n"), len(before)-i
+	}
+	d.addError(line, column, message)
+}
+
+func (d *Document) parseCustomInline(input string, start int, beforeBuiltins bool) (int, Node) {
+	r, _ := utf8.DecodeRuneInString(input[start:])
+	if r == utf8.RuneError {
+		return 0, nil
+	}
+	for _, p := range d.inlineParsers {
+		if (p.Priority > 0) != beforeBuiltins || !strings.ContainsRune(p.Chars, r) {
+			continue
+		}
+		if consumed, node := p.Parse(input, start); consumed != 0 {
+			return consumed, node
+		}
+	}
+	return 0, nil
+}
+
 func (d *Document) parseRawInline(input string) (nodes []Node) {
 	previous, current := 0, 0
 	for current < len(input) {
@@ -169,15 +241,53 @@ func (d *Document) parseLineBreak(input string, start int) (int, Node) {
 }
 
 func (d *Document) parseInlineBlock(input string, start int) (int, int, Node) {
+	if strings.HasSuffix(input[:start], "call") && (start-5 < 0 || unicode.IsSpace(rune(input[start-5]))) {
+		if m := inlineBabelCallRegexp.FindStringSubmatch(input[start-4:]); m != nil {
+			return 4, len(m[0]), InlineBabelCall{m[1], m[2], m[3], m[4]}
+		}
+		return 0, 0, nil
+	}
 	if !(strings.HasSuffix(input[:start], "src") && (start-4 < 0 || unicode.IsSpace(rune(input[start-4])))) {
 		return 0, 0, nil
 	}
 	if m := inlineBlockRegexp.FindStringSubmatch(input[start-3:]); m != nil {
-		return 3, len(m[0]), InlineBlock{"src", strings.Fields(m[1] + " " + m[3]), d.parseRawInline(m[4])}
+		if end := matchingBrace(input[start-3:], len(m[0])-1); end != -1 {
+			content := input[start-3+len(m[0]) : start-3+end]
+			return 3, end + 1, InlineBlock{"src", strings.Fields(m[1] + " " + m[2]), d.parseRawInline(content)}
+		}
 	}
 	return 0, 0, nil
 }
 
+// matchingBrace returns the index of the } closing the { at index i of s - nested braces must be balanced.
+// Returns -1 if the brace is not closed on the same line.
+func matchingBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s) && s[i] != '\n'; i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ParameterMap returns the combined inside and end header arguments of c - end header arguments take precedence.
+func (c InlineBabelCall) ParameterMap() map[string]string {
+	m := map[string]string{}
+	for _, header := range []string{c.InsideHeader, c.EndHeader} {
+		parameters, _ := splitParameters(" " + header)
+		for i := 0; i+1 < len(parameters); i += 2 {
+			m[parameters[i]] = parameters[i+1]
+		}
+	}
+	return m
+}
+
 func (d *Document) parseInlineExportBlock(input string, start int) (int, Node) {
 	if m := inlineExportBlockRegexp.FindStringSubmatch(input[start:]); m != nil {
 		return len(m[0]), InlineBlock{"export", m[1:2], d.parseRawInline(m[2])}
@@ -205,7 +315,31 @@ func (d *Document) parseExplicitLineBreakOrLatexFragment(input string, start int
 			}
 		}
 	}
-	return 0, nil
+	return d.parseEntity(input, start)
+}
+
+// parseEntity parses entities like \alpha, \alpha{} & \_  (space entities). Unknown names (e.g. \foo) and
+// names followed by a letter (e.g. \alphabet) are not entities. Known names followed by digits (e.g. \alpha2) are.
+func (d *Document) parseEntity(input string, start int) (int, Node) {
+	m := entityRegexp.FindStringSubmatch(input[start:])
+	if m == nil {
+		return 0, nil
+	}
+	name, braces := m[1], m[2] != ""
+	if strings.HasPrefix(name, "_") {
+		for ; len(name) > 1 && entities[name] == ""; name = name[:len(name)-1] {
+		}
+		braces = braces && name == m[1]
+	} else if _, ok := entities[name]; !ok {
+		name, braces = strings.TrimRight(name, "0123456789"), false
+	}
+	if _, ok := entities[name]; !ok {
+		return 0, nil
+	}
+	if braces {
+		return len(name) + 3, Entity{name, true}
+	}
+	return len(name) + 1, Entity{name, false}
 }
 
 func (d *Document) parseLatexFragment(input string, start int, pairLength int) (int, Node) {
@@ -217,7 +351,13 @@ func (d *Document) parseLatexFragment(input string, start int, pairLength int) (
 	}
 	openingPair := input[start : start+pairLength]
 	closingPair := latexFragmentPairs[openingPair]
+	if openingPair == "$" && !isValidBorderChar(nextRune(input, start)) {
+		return 0, nil
+	}
 	if i := strings.Index(input[start+pairLength:], closingPair); i != -1 {
+		if openingPair == "$" && !isValidBorderChar(prevRune(input, start+pairLength+i)) {
+			return 0, nil
+		}
 		content := d.parseRawInline(input[start+pairLength : start+pairLength+i])
 		return i + pairLength + pairLength, LatexFragment{openingPair, closingPair, content}
 	}
@@ -225,9 +365,18 @@ func (d *Document) parseLatexFragment(input string, start int, pairLength int) (
 }
 
 func (d *Document) parseSubOrSuperScript(input string, start int) (int, Node) {
+	option := d.GetOption("^")
+	if option == "nil" {
+		return 0, nil
+	}
 	if m := subScriptSuperScriptRegexp.FindStringSubmatch(input[start:]); m != nil {
 		return len(m[2]) + 3, Emphasis{m[1] + "{}", []Node{Text{m[2], false}}}
 	}
+	if option == "t" && start > 0 && !unicode.IsSpace(rune(input[start-1])) {
+		if m := unbracedSubScriptSuperScriptRegexp.FindStringSubmatch(input[start:]); m != nil {
+			return len(m[0]), Emphasis{m[1] + "x", []Node{Text{m[2], false}}}
+		}
+	}
 	return 0, nil
 }
 
@@ -248,20 +397,96 @@ func (d *Document) parseOpeningBracket(input string, start int) (int, Node) {
 		return d.parseFootnoteReference(input, start)
 	} else if statisticsTokenRegexp.MatchString(input[start:]) {
 		return d.parseStatisticToken(input, start)
+	} else if timestampRegexp.MatchString(input[start:]) {
+		return d.parseTimestamp(input, start)
 	}
 	return 0, nil
 }
 
 func (d *Document) parseMacro(input string, start int) (int, Node) {
 	if m := macroRegexp.FindStringSubmatch(input[start:]); m != nil {
-		return len(m[0]), Macro{m[1], strings.Split(m[2], ",")}
+		if !strings.HasPrefix(m[0], "{{{"+m[1]+"(") {
+			return len(m[0]), Macro{m[1], nil}
+		}
+		return len(m[0]), Macro{m[1], splitMacroParameters(m[2])}
 	}
 	return 0, nil
 }
 
+// splitMacroParameters splits s on commas - escaped commas (\,) do not split and are unescaped.
+func splitMacroParameters(s string) []string {
+	parameters, parameter := []string{}, strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] == ',' {
+			parameter.WriteByte(',')
+			i++
+		} else if s[i] == ',' {
+			parameters, parameter = append(parameters, parameter.String()), strings.Builder{}
+		} else {
+			parameter.WriteByte(s[i])
+		}
+	}
+	return append(parameters, parameter.String())
+}
+
+// ExpandMacro returns the inline nodes of the expansion of m: the #+MACRO definition of m.Name with $1 to $9 replaced by
+// the parameters of m. The built-in macros title, author, email and date expand to the value of the respective keyword
+// unless defined via #+MACRO. ok is false for undefined macros.
+func (d *Document) ExpandMacro(m Macro) (nodes []Node, ok bool) {
+	macro, ok := d.Macros[m.Name]
+	if !ok {
+		switch m.Name {
+		case "title", "author", "email", "date":
+			macro, ok = d.Get(strings.ToUpper(m.Name)), true
+		default:
+			return nil, false
+		}
+	}
+	for i := 1; i <= 9; i++ {
+		parameter := ""
+		if i <= len(m.Parameters) {
+			parameter = m.Parameters[i-1]
+		}
+		macro = strings.Replace(macro, fmt.Sprintf("$%d", i), parameter, -1)
+	}
+	macroDocument := d.Parse(strings.NewReader(macro), d.Path)
+	if macroDocument.Error != nil {
+		d.Log.Printf("bad macro: %s -> %s: %v", m.Name, macro, macroDocument.Error)
+	}
+	if len(macroDocument.Nodes) == 1 {
+		if p, ok := macroDocument.Nodes[0].(Paragraph); ok {
+			return p.Children, true
+		}
+	}
+	return macroDocument.Nodes, true
+}
+
+// ExpandLink returns the url of l with its #+LINK abbreviation expanded: For [[abbreviation:tag]] %s is replaced with the tag
+// and %h with the url encoded tag - the tag is appended if the abbreviation contains neither. [[abbreviation]] expands without a tag.
+// Prefixes are matched case sensitively, ok is false for links without an abbreviation (e.g. mailto: links).
+func (d *Document) ExpandLink(l RegularLink) (expanded string, ok bool) {
+	if prefix := d.Links[l.Protocol]; prefix != "" {
+		tag := strings.TrimPrefix(l.URL, l.Protocol+":")
+		if strings.Contains(prefix, "%s") || strings.Contains(prefix, "%h") {
+			return strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", tag), "%h", url.QueryEscape(tag)), true
+		}
+		return prefix + tag, true
+	} else if prefix := d.Links[l.URL]; prefix != "" {
+		return strings.ReplaceAll(strings.ReplaceAll(prefix, "%s", ""), "%h", ""), true
+	}
+	return l.URL, false
+}
+
 func (d *Document) parseFootnoteReference(input string, start int) (int, Node) {
 	if m := footnoteRegexp.FindStringSubmatch(input[start:]); m != nil {
-		name, definition := m[1], m[3]
+		name, definition, consumed := m[1], m[3], len(m[0])
+		if m[2] != "" {
+			// inline definitions can contain brackets, e.g. links or nested footnote references - unless they are unbalanced
+			offset := len("[fn:" + name + ":")
+			if end := closingBracketIndex(input[start+offset:]); end != -1 {
+				definition, consumed = input[start+offset:start+offset+end], offset+end+1
+			}
+		}
 		if name == "" && definition == "" {
 			return 0, nil
 		}
@@ -269,11 +494,27 @@ func (d *Document) parseFootnoteReference(input string, start int) (int, Node) {
 		if definition != "" {
 			link.Definition = &FootnoteDefinition{name, []Node{Paragraph{d.parseInline(definition)}}, true}
 		}
-		return len(m[0]), link
+		d.Footnotes.addReference(link)
+		return consumed, link
 	}
 	return 0, nil
 }
 
+// closingBracketIndex returns the index of the ] closing an already opened [ in s - or -1.
+func closingBracketIndex(s string) int {
+	for i, depth := 0, 1; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 func (d *Document) parseStatisticToken(input string, start int) (int, Node) {
 	if m := statisticsTokenRegexp.FindStringSubmatch(input[start:]); m != nil {
 		return len(m[1]) + 2, StatisticToken{m[1]}
@@ -308,12 +549,13 @@ func (d *Document) parseAutoLink(input string, start int) (int, int, Node) {
 }
 
 func (d *Document) parseRegularLink(input string, start int) (int, Node) {
-	input = input[start:]
+	input, before := input[start:], input[:start]
 	if len(input) < 3 || input[:2] != "[[" || input[2] == '[' {
 		return 0, nil
 	}
 	end := strings.Index(input, "]]")
 	if end == -1 {
+		d.addInlineError(before, "[[ without closing ]]")
 		return 0, nil
 	}
 	rawLinkParts := strings.Split(input[2:end], "][")
@@ -326,31 +568,101 @@ func (d *Document) parseRegularLink(input string, start int) (int, Node) {
 	}
 	consumed := end + 2
 	protocol, linkParts := "", strings.SplitN(link, ":", 2)
-	if len(linkParts) == 2 {
+	if len(linkParts) == 2 && linkTypeRegexp.MatchString(linkParts[0]) {
 		protocol = linkParts[0]
 	}
 	return consumed, RegularLink{protocol, description, link, false}
 }
 
+func (d *Document) parseTimestampOrRadioTarget(input string, start int) (int, Node) {
+	if m := radioTargetRegexp.FindStringSubmatch(input[start:]); m != nil {
+		d.RadioTargets = append(d.RadioTargets, m[1])
+		return len(m[0]), RadioTarget{m[1]}
+	} else if m := targetRegexp.FindStringSubmatch(input[start:]); m != nil {
+		d.Targets = append(d.Targets, m[1])
+		return len(m[0]), Target{m[1]}
+	}
+	return d.parseTimestamp(input, start)
+}
+
+// parseTimestamp parses active <...> & inactive [...] timestamps and ranges. Invalid dates & times are not timestamps.
 func (d *Document) parseTimestamp(input string, start int) (int, Node) {
-	if m := timestampRegexp.FindStringSubmatch(input[start:]); m != nil {
-		ddmmyy, hhmm, interval, isDate := m[1], m[3], strings.TrimSpace(m[4]), false
-		if hhmm == "" {
-			hhmm, isDate = "00:00", true
-		}
-		t, err := time.Parse(timestampFormat, fmt.Sprintf("%s Mon %s", ddmmyy, hhmm))
+	consumed, timestamp, ok := parseTimestamp(input[start:])
+	if !ok {
+		return 0, nil
+	}
+	if !timestamp.IsDate && timestamp.End != nil || !strings.HasPrefix(input[start+consumed:], "--") {
+		return consumed, timestamp
+	}
+	if n, end, ok := parseTimestamp(input[start+consumed+2:]); ok && end.IsActive == timestamp.IsActive && end.End == nil && !end.Time.Before(timestamp.Time) {
+		timestamp.End, timestamp.IsRange = &end.Time, true
+		timestamp.EndIsDate, timestamp.EndInterval = end.IsDate, end.Interval
+		return consumed + 2 + n, timestamp
+	}
+	return consumed, timestamp
+}
+
+func parseTimestamp(s string) (int, Timestamp, bool) {
+	m := timestampRegexp.FindStringSubmatch(s)
+	if m == nil || (m[1] == "<") != (m[8] == ">") {
+		return 0, Timestamp{}, false
+	}
+	ddmmyy, hhmm, endHHMM, interval := m[2], m[5], m[6], strings.TrimSpace(m[7])
+	timestamp := Timestamp{IsDate: hhmm == "", Interval: interval, IsActive: m[1] == "<"}
+	if hhmm == "" {
+		hhmm = "00:00"
+	}
+	t, err := time.Parse(timestampFormat, fmt.Sprintf("%s Mon %s", ddmmyy, padTime(hhmm)))
+	if err != nil {
+		return 0, Timestamp{}, false
+	}
+	timestamp.Time = t
+	if endHHMM != "" {
+		end, err := time.Parse(timestampFormat, fmt.Sprintf("%s Mon %s", ddmmyy, padTime(endHHMM)))
 		if err != nil {
-			return 0, nil
+			return 0, Timestamp{}, false
 		}
-		timestamp := Timestamp{t, isDate, interval}
-		return len(m[0]), timestamp
+		timestamp.End = &end
+	}
+	return len(m[0]), timestamp, true
+}
+
+// padTime pads times like 9:30 to the 09:30 expected by timestampFormat.
+func padTime(hhmm string) string {
+	if len(hhmm) == len("9:30") {
+		return "0" + hhmm
+	}
+	return hhmm
+}
+
+// orgString returns t in canonical org syntax, e.g. <2024-01-02 Tue 10:00-12:00 +1w> or [2024-01-02 Tue]--[2024-01-05 Fri].
+func (t Timestamp) orgString() string {
+	open, close := "<", ">"
+	if !t.IsActive {
+		open, close = "[", "]"
+	}
+	format := func(tm time.Time, isDate bool, suffix, interval string) string {
+		if interval != "" {
+			suffix += " " + interval
+		}
+		if isDate {
+			return open + tm.Format(datestampFormat) + suffix + close
+		}
+		return open + tm.Format(timestampFormat) + suffix + close
+	}
+	switch {
+	case t.End == nil:
+		return format(t.Time, t.IsDate, "", t.Interval)
+	case !t.IsDate && !t.IsRange && t.End.Year() == t.Time.Year() && t.End.YearDay() == t.Time.YearDay():
+		return format(t.Time, t.IsDate, "-"+t.End.Format("15:04"), t.Interval)
+	default:
+		return format(t.Time, t.IsDate, "", t.Interval) + "--" + format(*t.End, t.EndIsDate, "", t.EndInterval)
 	}
-	return 0, nil
 }
 
 func (d *Document) parseEmphasis(input string, start int, isRaw bool) (int, Node) {
 	marker, i := input[start], start
-	if !hasValidPreAndBorderChars(input, i) {
+	if strings.IndexByte(d.EmphasisMarkers, marker) == -1 || !hasValidPreAndBorderChars(input, i) {
 		return 0, nil
 	}
 	for i, consumedNewLines := i+1, 0; i < len(input) && consumedNewLines <= d.MaxEmphasisNewLines; i++ {
@@ -359,6 +671,9 @@ func (d *Document) parseEmphasis(input string, start int, isRaw bool) (int, Node
 		}
 
 		if input[i] == marker && i != start+1 && hasValidPostAndBorderChars(input, i) {
+			if isPseudoEmphasis(input[start+1:i], marker) {
+				continue
+			}
 			if isRaw {
 				return i + 1 - start, Emphasis{input[start : start+1], d.parseRawInline(input[start+1 : i])}
 			}
@@ -368,6 +683,12 @@ func (d *Document) parseEmphasis(input string, start int, isRaw bool) (int, Node
 	return 0, nil
 }
 
+// isPseudoEmphasis returns true for content consisting only of markers and whitespace (e.g. the * * of ** **) -
+// such emphasis markers are kept as literal text (e.g. separators like ====) rather than parsed as emphasis.
+func isPseudoEmphasis(content string, marker byte) bool {
+	return strings.TrimFunc(content, func(r rune) bool { return r == rune(marker) || unicode.IsSpace(r) }) == ""
+}
+
 // see org-emphasis-regexp-components (emacs elisp variable)
 
 func hasValidPreAndBorderChars(input string, i int) bool {
@@ -389,35 +710,58 @@ func nextRune(input string, i int) rune {
 	return r
 }
 
+// isValidPreChar & isValidPostChar accept zero width spaces (U+200B) - Org mode's way of separating markup from adjacent text, e.g. *bold*\u200btext.
 func isValidPreChar(r rune) bool {
-	return r == utf8.RuneError || unicode.IsSpace(r) || strings.ContainsRune(`-({'"`, r)
+	return r == utf8.RuneError || unicode.IsSpace(r) || r == '\u200b' || strings.ContainsRune(`-({'"`, r)
 }
 
 func isValidPostChar(r rune) bool {
-	return r == utf8.RuneError || unicode.IsSpace(r) || strings.ContainsRune(`-.,:!?;'")}[\`, r)
+	return r == utf8.RuneError || unicode.IsSpace(r) || r == '\u200b' || strings.ContainsRune(`-.,:!?;'")}[\`, r)
 }
 
-func isValidBorderChar(r rune) bool { return !unicode.IsSpace(r) }
+func isValidBorderChar(r rune) bool { return !unicode.IsSpace(r) && r != '\u200b' }
 
 func (l RegularLink) Kind() string {
-	description := String(l.Description)
-	descProtocol, descExt := strings.SplitN(description, ":", 2)[0], path.Ext(description)
-	if ok := descProtocol == "file" || descProtocol == "http" || descProtocol == "https"; ok && imageExtensionRegexp.MatchString(descExt) {
+	if l.IsImage(nil) {
 		return "image"
-	} else if ok && videoExtensionRegexp.MatchString(descExt) {
+	} else if l.IsVideo(nil) {
 		return "video"
 	}
+	return "regular"
+}
+
+// IsExecutable returns true for elisp: & shell: links - they run code when followed in Emacs and are exported as plain text.
+func (l RegularLink) IsExecutable() bool { return l.Protocol == "elisp" || l.Protocol == "shell" }
+
+// IsImage returns true if the link (or its description) points to a file with one of the given extensions (e.g. ".png").
+// If exts is nil, the image extensions embedded by the HTMLWriter are used.
+func (l RegularLink) IsImage(exts []string) bool { return l.isMedia(exts, imageExtensionRegexp) }
+
+// IsVideo returns true if the link (or its description) points to a file with one of the given extensions (e.g. ".mp4").
+// If exts is nil, the video extensions embedded by the HTMLWriter are used.
+func (l RegularLink) IsVideo(exts []string) bool { return l.isMedia(exts, videoExtensionRegexp) }
 
-	if p := l.Protocol; l.Description != nil || (p != "" && p != "file" && p != "http" && p != "https") {
-		return "regular"
+func (l RegularLink) isMedia(exts []string, defaultExtensionRegexp *regexp.Regexp) bool {
+	hasExtension := func(url string) bool {
+		ext := path.Ext(url)
+		if exts == nil {
+			return defaultExtensionRegexp.MatchString(ext)
+		}
+		for _, e := range exts {
+			if strings.EqualFold(e, ext) {
+				return true
+			}
+		}
+		return false
 	}
-	if imageExtensionRegexp.MatchString(path.Ext(l.URL)) {
-		return "image"
+	description := String(l.Description)
+	if p := strings.SplitN(description, ":", 2)[0]; (p == "file" || p == "http" || p == "https") && hasExtension(description) {
+		return true
 	}
-	if videoExtensionRegexp.MatchString(path.Ext(l.URL)) {
-		return "video"
+	if p := l.Protocol; l.Description != nil || (p != "" && p != "file" && p != "http" && p != "https" && p != "attachment") {
+		return false
 	}
-	return "regular"
+	return hasExtension(l.URL)
 }
 
 func (n Text) String() string              { return orgWriter.WriteNodesAsString(n) }
@@ -426,8 +770,12 @@ func (n ExplicitLineBreak) String() string { return orgWriter.WriteNodesAsString
 func (n StatisticToken) String() string    { return orgWriter.WriteNodesAsString(n) }
 func (n Emphasis) String() string          { return orgWriter.WriteNodesAsString(n) }
 func (n InlineBlock) String() string       { return orgWriter.WriteNodesAsString(n) }
+func (n InlineBabelCall) String() string   { return orgWriter.WriteNodesAsString(n) }
 func (n LatexFragment) String() string     { return orgWriter.WriteNodesAsString(n) }
 func (n FootnoteLink) String() string      { return orgWriter.WriteNodesAsString(n) }
 func (n RegularLink) String() string       { return orgWriter.WriteNodesAsString(n) }
 func (n Macro) String() string             { return orgWriter.WriteNodesAsString(n) }
+func (n RadioTarget) String() string       { return orgWriter.WriteNodesAsString(n) }
+func (n Target) String() string            { return orgWriter.WriteNodesAsString(n) }
+func (n Entity) String() string            { return orgWriter.WriteNodesAsString(n) }
 func (n Timestamp) String() string         { return orgWriter.WriteNodesAsString(n) }
@@ -0,0 +1,311 @@
+package org
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonNodeTypes contains the node types known to MarshalDocument & UnmarshalDocument by their "type" discriminator.
+var jsonNodeTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, n := range []Node{
+		Comment{}, Keyword{}, NodeWithName{}, NodeWithMeta{}, Include{},
+		Headline{}, Block{}, Result{}, Example{}, LatexBlock{}, Drawer{}, PropertyDrawer{},
+		List{}, ListItem{}, DescriptiveListItem{}, Table{}, Paragraph{}, HorizontalRule{}, FootnoteDefinition{},
+		Text{}, LineBreak{}, ExplicitLineBreak{}, StatisticToken{}, Timestamp{}, RadioTarget{}, Target{}, Entity{},
+		Emphasis{}, InlineBlock{}, InlineBabelCall{}, LatexFragment{}, FootnoteLink{}, RegularLink{}, Macro{},
+	} {
+		jsonNodeTypes[reflect.TypeOf(n).Name()] = reflect.TypeOf(n)
+	}
+}
+
+// jsonDocument contains the parts of a Document that are serialized by MarshalDocument.
+type jsonDocument struct {
+	Path           string
+	Nodes          []Node
+	NamedNodes     map[string]Node
+	Macros         map[string]string
+	Links          map[string]string
+	Abbreviations  map[string]string
+	RadioTargets   []string
+	Targets        []string
+	BufferSettings map[string]string
+	Index          []jsonIndexEntry
+	KeywordCases   map[string]string
+}
+
+// jsonIndexEntry is an IndexEntry with the section replaced by the index of its headline - 0 for the root section.
+type jsonIndexEntry struct {
+	Term, ID string
+	Headline int
+}
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// MarshalDocument returns the JSON encoding of the nodes and settings of d. Nodes are encoded as objects of their
+// exported fields with an additional "type" field containing the name of the node type, e.g. {"type": "Text", "Content": "foo", ...}.
+// Custom nodes (e.g. from InlineParsers) are not known to go-org and cannot be encoded.
+func MarshalDocument(d *Document) ([]byte, error) {
+	jd := jsonDocument{d.Path, d.Nodes, d.NamedNodes, d.Macros, d.Links, d.Abbreviations, d.RadioTargets, d.Targets, d.BufferSettings, nil, d.keywordCases}
+	for _, e := range d.Index {
+		jd.Index = append(jd.Index, jsonIndexEntry{e.Term, e.ID, 0})
+		if e.Section != nil && e.Section.Headline != nil {
+			jd.Index[len(jd.Index)-1].Headline = e.Section.Headline.Index
+		}
+	}
+	v, err := encodeJSON(reflect.ValueOf(jd))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalDocument parses the JSON encoding of a document as returned by MarshalDocument.
+// The outline and footnotes are rebuilt from the nodes - the returned document uses the default Configuration (see New).
+func UnmarshalDocument(bs []byte) (*Document, error) {
+	jd := jsonDocument{}
+	if err := json.Unmarshal(bs, &struct{ Path *string }{&jd.Path}); err != nil {
+		return nil, err
+	}
+	d := New().Silent().Parse(strings.NewReader(""), jd.Path) // the path is needed to resolve includes while decoding
+	if err := d.decodeJSON(bs, reflect.ValueOf(&jd).Elem()); err != nil {
+		return nil, err
+	}
+	d.Nodes, d.RadioTargets, d.Targets = jd.Nodes, jd.RadioTargets, jd.Targets
+	for m, values := range map[*map[string]string]map[string]string{
+		&d.Macros: jd.Macros, &d.Links: jd.Links, &d.Abbreviations: jd.Abbreviations, &d.BufferSettings: jd.BufferSettings, &d.keywordCases: jd.KeywordCases,
+	} {
+		for k, v := range values {
+			(*m)[k] = v
+		}
+	}
+	for k, n := range jd.NamedNodes {
+		d.NamedNodes[k] = n
+	}
+	walkNodes(d.Nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			d.Footnotes.addReference(n)
+		case FootnoteDefinition:
+			if !n.Inline {
+				d.Footnotes.addDefinition(&n)
+			}
+		case Headline:
+			d.Outline.count++
+		}
+	})
+	d.rebuildOutline()
+	sections := map[int]*Section{0: d.Outline.Section}
+	var collect func(*Section)
+	collect = func(s *Section) {
+		for _, c := range s.Children {
+			sections[c.Headline.Index] = c
+			collect(c)
+		}
+	}
+	collect(d.Outline.Section)
+	for _, e := range jd.Index {
+		d.Index = append(d.Index, IndexEntry{e.Term, e.ID, sections[e.Headline]})
+	}
+	return d, nil
+}
+
+func encodeJSON(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		} else if v.Type() != nodeType {
+			return encodeJSON(v.Elem())
+		}
+		name := v.Elem().Type().Name()
+		if jsonNodeTypes[name] != v.Elem().Type() {
+			return nil, fmt.Errorf("cannot encode unknown node type %T", v.Interface())
+		}
+		m, err := encodeJSON(v.Elem())
+		if err != nil {
+			return nil, err
+		}
+		m.(map[string]interface{})["type"] = name
+		switch n := v.Interface().(type) {
+		case Block:
+			m.(map[string]interface{})["rawParameters"] = n.rawParameters
+		case PropertyDrawer:
+			m.(map[string]interface{})["separators"] = n.separators
+		}
+		return m, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeJSON(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			value, err := encodeJSON(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		values := map[string]interface{}{}
+		for _, k := range v.MapKeys() {
+			value, err := encodeJSON(v.MapIndex(k))
+			if err != nil {
+				return nil, err
+			}
+			values[k.String()] = value
+		}
+		return values, nil
+	case reflect.Struct:
+		if _, ok := v.Interface().(json.Marshaler); ok {
+			return v.Interface(), nil
+		}
+		values := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" || f.Type.Kind() == reflect.Func {
+				continue
+			}
+			value, err := encodeJSON(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if embedded, ok := value.(map[string]interface{}); ok && f.Anonymous {
+				for k, v := range embedded {
+					values[k] = v
+				}
+			} else if !f.Anonymous {
+				values[f.Name] = value
+			}
+		}
+		return values, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+func (d *Document) decodeJSON(bs []byte, v reflect.Value) error {
+	if v.CanAddr() {
+		if _, ok := v.Addr().Interface().(json.Unmarshaler); ok {
+			return json.Unmarshal(bs, v.Addr().Interface())
+		}
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if string(bs) == "null" {
+			return nil
+		} else if v.Type() != nodeType {
+			return fmt.Errorf("cannot decode %s", v.Type())
+		}
+		n := struct{ Type string }{}
+		if err := json.Unmarshal(bs, &n); err != nil {
+			return err
+		}
+		t, ok := jsonNodeTypes[n.Type]
+		if !ok {
+			return fmt.Errorf("cannot decode unknown node type %q", n.Type)
+		}
+		node := reflect.New(t).Elem()
+		if err := d.decodeJSON(bs, node); err != nil {
+			return err
+		}
+		switch n := node.Addr().Interface().(type) {
+		case *Table:
+			n.linkColumnInfos()
+		case *Block:
+			raw := struct{ RawParameters string }{}
+			if err := json.Unmarshal(bs, &raw); err != nil {
+				return err
+			}
+			n.rawParameters = raw.RawParameters
+		case *PropertyDrawer:
+			raw := struct{ Separators map[string]string }{}
+			if err := json.Unmarshal(bs, &raw); err != nil {
+				return err
+			}
+			n.separators = raw.Separators
+		case *Include:
+			// functions cannot be encoded - org includes keep their parsed Children so only non-org includes are resolved again
+			if _, kind, _, _, ok := parseIncludeValue(n.Value); !ok || kind != "" {
+				_, include := d.parseInclude(0, n.Keyword)
+				node = reflect.ValueOf(include)
+			}
+		}
+		v.Set(node)
+	case reflect.Ptr:
+		if string(bs) == "null" {
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		return d.decodeJSON(bs, v.Elem())
+	case reflect.Slice:
+		values := []json.RawMessage(nil)
+		if err := json.Unmarshal(bs, &values); err != nil || values == nil {
+			return err
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(values), len(values)))
+		for i, value := range values {
+			if err := d.decodeJSON(value, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		values := map[string]json.RawMessage(nil)
+		if err := json.Unmarshal(bs, &values); err != nil || values == nil {
+			return err
+		}
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(values)))
+		for k, value := range values {
+			element := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decodeJSON(value, element); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), element)
+		}
+	case reflect.Struct:
+		values := map[string]json.RawMessage{}
+		if err := json.Unmarshal(bs, &values); err != nil {
+			return err
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" || f.Type.Kind() == reflect.Func {
+				continue
+			} else if f.Anonymous {
+				if err := d.decodeJSON(bs, v.Field(i)); err != nil {
+					return err
+				}
+			} else if value, ok := values[f.Name]; ok {
+				if err := d.decodeJSON(value, v.Field(i)); err != nil {
+					return fmt.Errorf("%s.%s: %s", v.Type().Name(), f.Name, err)
+				}
+			}
+		}
+	default:
+		return json.Unmarshal(bs, v.Addr().Interface())
+	}
+	return nil
+}
+
+// linkColumnInfos points the columns of t to the ColumnInfos of t like the parser does.
+func (t *Table) linkColumnInfos() {
+	for _, row := range t.Rows {
+		for i := range row.Columns {
+			if i < len(t.ColumnInfos) {
+				row.Columns[i].ColumnInfo = &t.ColumnInfos[i]
+			}
+		}
+	}
+}
@@ -2,15 +2,18 @@ package org
 
 import (
 	"bytes"
+	"fmt"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 type Comment struct{ Content string }
 
 type Keyword struct {
-	Key   string
+	Key   string // Key is the upper case key of the keyword, e.g. TITLE for #+title:.
 	Value string
 }
 
@@ -29,16 +32,35 @@ type Metadata struct {
 	HTMLAttributes [][]string
 }
 
+// IndexEntry is a #+INDEX: term. HTMLWriter emits an anchor at each entry and lists all entries at #+PRINT_INDEX:.
+type IndexEntry struct {
+	Term    string
+	ID      string   // ID is the id of the anchor emitted at the position of the entry, e.g. index-1.
+	Section *Section // Section is the section containing the entry - it is the root section for entries before the first headline.
+}
+
 type Include struct {
 	Keyword
-	Resolve func() Node
+	Resolve  func() Node // Resolve lazily reads src, example & export includes into a Block - it is nil for org includes.
+	Children []Node      // Children are the nodes of org includes, parsed in place of the keyword.
 }
 
 var keywordRegexp = regexp.MustCompile(`^(\s*)#\+([^:]+):(\s+(.*)|$)`)
-var commentRegexp = regexp.MustCompile(`^(\s*)#\s(.*)`)
+var commentRegexp = regexp.MustCompile(`^(\s*)#(?:\s(.*)|$)`)
 
-var includeFileRegexp = regexp.MustCompile(`(?i)^"([^"]+)" (src|example|export) (\w+)$`)
-var attributeRegexp = regexp.MustCompile(`(?:^|\s+)(:[-\w]+)\s+(.*)$`)
+// knownKeywords are the keywords that are not affected by Configuration.UnknownKeywords (besides ATTR_*, HTML_* & LATEX_*).
+var knownKeywords = map[string]bool{
+	"TITLE": true, "SUBTITLE": true, "AUTHOR": true, "DATE": true, "EMAIL": true, "LANGUAGE": true, "CREATOR": true,
+	"DESCRIPTION": true, "KEYWORDS": true, "OPTIONS": true, "STARTUP": true, "SETUPFILE": true, "INCLUDE": true, "BIND": true,
+	"TODO": true, "SEQ_TODO": true, "TYP_TODO": true, "TAGS": true, "FILETAGS": true, "EXCLUDE_TAGS": true, "SELECT_TAGS": true,
+	"PROPERTY": true, "CATEGORY": true, "PRIORITIES": true, "ARCHIVE": true, "COLUMNS": true, "CONSTANTS": true,
+	"LINK": true, "MACRO": true, "ABBREV": true, "NAME": true, "CAPTION": true, "RESULTS": true, "HEADER": true, "HEADERS": true,
+	"CALL": true, "TBLFM": true, "PLOT": true, "INDEX": true, "PRINT_INDEX": true, "TOC": true, "HTML": true, "LATEX": true, "EXPORT_FILE_NAME": true,
+}
+
+var includeFileRegexp = regexp.MustCompile(`^"([^"]+)"(.*)$`)
+var includeLinesRegexp = regexp.MustCompile(`:lines\s+"(\d*)-(\d*)"`)
+var attributeKeyRegexp = regexp.MustCompile(`^:[-\w]+\s+`)
 
 func lexKeywordOrComment(line string) (token, bool) {
 	if m := keywordRegexp.FindStringSubmatch(line); m != nil {
@@ -55,23 +77,34 @@ func (d *Document) parseComment(i int, stop stopFn) (int, Node) {
 
 func (d *Document) parseKeyword(i int, stop stopFn) (int, Node) {
 	k := parseKeyword(d.tokens[i])
+	if _, ok := d.keywordCases[k.Key]; !ok && !isKnownKeyword(k.Key) {
+		d.keywordCases[k.Key] = d.tokens[i].matches[2]
+	}
 	switch k.Key {
 	case "NAME":
 		return d.parseNodeWithName(k, i, stop)
 	case "SETUPFILE":
 		return d.loadSetupFile(k)
 	case "INCLUDE":
-		return d.parseInclude(k)
+		return d.parseInclude(i, k)
 	case "LINK":
 		if parts := strings.SplitN(k.Value, " ", 2); len(parts) == 2 {
 			d.Links[parts[0]] = parts[1]
 		}
 		return 1, k
 	case "MACRO":
-		if parts := strings.Split(k.Value, " "); len(parts) >= 2 {
-			d.Macros[parts[0]] = parts[1]
+		if parts := strings.SplitN(k.Value, " ", 2); parts[0] != "" {
+			d.Macros[parts[0]] = strings.TrimSpace(strings.Join(parts[1:], ""))
 		}
 		return 1, k
+	case "ABBREV":
+		if parts := strings.SplitN(strings.TrimSpace(k.Value), " ", 2); len(parts) == 2 {
+			d.Abbreviations[parts[0]] = strings.TrimSpace(parts[1])
+		}
+		return 1, k
+	case "INDEX":
+		d.Index = append(d.Index, IndexEntry{k.Value, fmt.Sprintf("index-%d", len(d.Index)+1), d.Outline.last})
+		return 1, k
 	case "CAPTION", "ATTR_HTML":
 		consumed, node := d.parseAffiliated(i, stop)
 		if consumed != 0 {
@@ -79,15 +112,52 @@ func (d *Document) parseKeyword(i int, stop stopFn) (int, Node) {
 		}
 		fallthrough
 	default:
-		if _, ok := d.BufferSettings[k.Key]; ok {
-			d.BufferSettings[k.Key] = strings.Join([]string{d.BufferSettings[k.Key], k.Value}, "\n")
-		} else {
-			d.BufferSettings[k.Key] = k.Value
+		d.addBufferSetting(k.Key, k.Value)
+		if !isKnownKeyword(k.Key) {
+			switch d.UnknownKeywords {
+			case "drop":
+				return 1, nil
+			case "error":
+				d.Log.Printf("Unknown keyword: #+%s: %s", k.Key, k.Value)
+			}
 		}
 		return 1, k
 	}
 }
 
+func isKnownKeyword(key string) bool {
+	return knownKeywords[key] || strings.HasPrefix(key, "ATTR_") || strings.HasPrefix(key, "HTML_") || strings.HasPrefix(key, "LATEX_")
+}
+
+// addBufferSetting appends value to the values of key - repeated keywords (e.g. #+HTML_HEAD) are joined with newlines.
+func (d *Document) addBufferSetting(key, value string) {
+	addSetting(d.ownSettings, key, value)
+	d.updateBufferSetting(key)
+}
+
+// updateBufferSetting sets BufferSettings[key] to the buffer's own value of key or, if the buffer does not set key,
+// to the value imported from setup files. The #+OPTIONS of setup files are kept after the buffer's own (see GetOption).
+func (d *Document) updateBufferSetting(key string) {
+	own, isOwn := d.ownSettings[key]
+	setup, isSetup := d.setupSettings[key]
+	switch {
+	case isOwn && isSetup && key == "OPTIONS":
+		d.BufferSettings[key] = own + "\n" + setup
+	case isOwn:
+		d.BufferSettings[key] = own
+	case isSetup:
+		d.BufferSettings[key] = setup
+	}
+}
+
+func addSetting(settings map[string]string, key, value string) {
+	if _, ok := settings[key]; ok {
+		settings[key] = strings.Join([]string{settings[key], value}, "\n")
+	} else {
+		settings[key] = value
+	}
+}
+
 func (d *Document) parseNodeWithName(k Keyword, i int, stop stopFn) (int, Node) {
 	if stop(d, i+1) {
 		return 0, nil
@@ -100,29 +170,42 @@ func (d *Document) parseNodeWithName(k Keyword, i int, stop stopFn) (int, Node)
 	return consumed + 1, NodeWithName{k.Value, node}
 }
 
+// Caption returns the inline nodes of all #+CAPTION keywords of n, separated by a space.
+func (n NodeWithMeta) Caption() []Node {
+	caption := []Node{}
+	for i, ns := range n.Meta.Caption {
+		if i != 0 {
+			caption = append(caption, Text{" ", false})
+		}
+		caption = append(caption, ns...)
+	}
+	return caption
+}
+
+// HTMLAttr returns the value of the #+ATTR_HTML attribute key (with or without leading colon) of n.
+// If the attribute is set multiple times, the last value is returned.
+func (n NodeWithMeta) HTMLAttr(key string) (string, bool) {
+	value, found, key := "", false, ":"+strings.TrimPrefix(key, ":")
+	for _, attributes := range n.Meta.HTMLAttributes {
+		for i := 0; i < len(attributes)-1; i += 2 {
+			if strings.EqualFold(attributes[i], key) {
+				value, found = attributes[i+1], true
+			}
+		}
+	}
+	return value, found
+}
+
 func (d *Document) parseAffiliated(i int, stop stopFn) (int, Node) {
-	start, meta := i, Metadata{}
+	start, meta, name := i, Metadata{}, ""
 	for ; !stop(d, i) && d.tokens[i].kind == "keyword"; i++ {
 		switch k := parseKeyword(d.tokens[i]); k.Key {
+		case "NAME":
+			name = k.Value
 		case "CAPTION":
 			meta.Caption = append(meta.Caption, d.parseInline(k.Value))
 		case "ATTR_HTML":
-			attributes, rest := []string{}, k.Value
-			for {
-				if k, m := "", attributeRegexp.FindStringSubmatch(rest); m != nil {
-					k, rest = m[1], m[2]
-					attributes = append(attributes, k)
-					if v, m := "", attributeRegexp.FindStringSubmatchIndex(rest); m != nil {
-						v, rest = rest[:m[0]], rest[m[0]:]
-						attributes = append(attributes, v)
-					} else {
-						attributes = append(attributes, strings.TrimSpace(rest))
-						break
-					}
-				} else {
-					break
-				}
-			}
+			attributes := splitHTMLAttributes(k.Value)
 			meta.HTMLAttributes = append(meta.HTMLAttributes, attributes)
 		default:
 			return 0, nil
@@ -136,21 +219,64 @@ func (d *Document) parseAffiliated(i int, stop stopFn) (int, Node) {
 		return 0, nil
 	}
 	i += consumed
+	if name != "" { // e.g. #+CAPTION before #+NAME
+		d.NamedNodes[name] = NodeWithMeta{node, meta}
+		return i - start, NodeWithName{name, NodeWithMeta{node, meta}}
+	}
 	return i - start, NodeWithMeta{node, meta}
 }
 
+// splitHTMLAttributes splits s (e.g. :alt foo bar :srcset "a.png 1x, b.png 2x") into alternating keys and values.
+// Values are kept as written (incl. quotes) - keys inside of double quoted values are not treated as keys.
+func splitHTMLAttributes(s string) []string {
+	attributes, keyStarts, keyEnds := []string{}, []int{}, []int{}
+	for i, inQuote := 0, false; i < len(s); i++ {
+		if s[i] == '"' {
+			inQuote = !inQuote
+		} else if !inQuote && s[i] == ':' && (i == 0 || unicode.IsSpace(rune(s[i-1]))) {
+			if m := attributeKeyRegexp.FindStringIndex(s[i:]); m != nil {
+				keyStarts, keyEnds = append(keyStarts, i), append(keyEnds, i+m[1])
+				i += m[1] - 1
+			}
+		}
+	}
+	for i := range keyStarts {
+		end := len(s)
+		if i+1 < len(keyStarts) {
+			end = keyStarts[i+1]
+		}
+		key := strings.TrimSpace(s[keyStarts[i]:keyEnds[i]])
+		attributes = append(attributes, key, strings.TrimSpace(s[keyEnds[i]:end]))
+	}
+	return attributes
+}
+
 func parseKeyword(t token) Keyword {
 	k, v := t.matches[2], t.matches[4]
 	return Keyword{strings.ToUpper(k), strings.TrimSpace(v)}
 }
 
-func (d *Document) parseInclude(k Keyword) (int, Node) {
+// parseInclude parses #+INCLUDE: "file" [src|example|export [lang]] [:lines "5-10"].
+// src, example & export includes are resolved lazily into a Block. Org includes are parsed into the Children of the
+// Include - the lines of the file are indented to the level of the keyword so e.g. includes in list items stay inside the item.
+func (d *Document) parseInclude(i int, k Keyword) (int, Node) {
 	resolve := func() Node {
 		d.Log.Printf("Bad include %#v", k)
 		return k
 	}
-	if m := includeFileRegexp.FindStringSubmatch(k.Value); m != nil {
-		path, kind, lang := m[1], m[2], m[3]
+	path, kind, lang, lines, ok := parseIncludeValue(k.Value)
+	if ok && kind == "" {
+		tokens, err := d.includeTokens(path, lines, d.tokens[i].matches[1], []string{d.Path})
+		if err != nil {
+			d.Error = fmt.Errorf("could not include %s: %s", path, err)
+			return 1, k
+		}
+		documentTokens := d.tokens
+		d.tokens = tokens
+		_, nodes := d.parseMany(0, func(d *Document, i int) bool { return i >= len(d.tokens) })
+		d.tokens = documentTokens
+		return 1, Include{k, nil, nodes}
+	} else if ok {
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(filepath.Dir(d.Path), path)
 		}
@@ -160,33 +286,159 @@ func (d *Document) parseInclude(k Keyword) (int, Node) {
 				d.Log.Printf("Bad include %#v: %s", k, err)
 				return k
 			}
-			return Block{strings.ToUpper(kind), []string{lang}, d.parseRawInline(string(bs)), nil}
+			parameters := []string{}
+			if lang != "" {
+				parameters = append(parameters, lang)
+			}
+			return Block{strings.ToUpper(kind), parameters, nil, d.parseRawInline(includeLines(string(bs), lines)), nil, ""}
+		}
+	}
+	return 1, Include{k, resolve, nil}
+}
+
+// Nodes returns the included nodes - the Children of org includes or the resolved Block of src, example & export includes.
+func (i Include) Nodes() []Node {
+	if i.Resolve == nil {
+		return i.Children
+	}
+	return []Node{i.Resolve()}
+}
+
+// includeTokens returns the tokens of the org file at path (relative to the directory of the document), each non-blank line indented by indent.
+// Org includes of the included file are expanded recursively - include paths are rewritten to be relative
+// to the directory of the document as well. stack contains the including files and is used to detect cycles.
+func (d *Document) includeTokens(path, lines, indent string, stack []string) ([]token, error) {
+	fullPath := path
+	if !filepath.IsAbs(path) {
+		fullPath = filepath.Join(filepath.Dir(d.Path), path)
+	}
+	for _, p := range stack {
+		if filepath.Clean(p) == filepath.Clean(fullPath) {
+			return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), fullPath)
+		}
+	}
+	bs, err := d.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	tokens := []token{}
+	for _, line := range strings.Split(strings.TrimSuffix(includeLines(string(bs), lines), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			line = indent + line
+		}
+		t := tokenize(d.stripCharacters(line))
+		if t.kind == "keyword" && strings.ToUpper(t.matches[2]) == "INCLUDE" {
+			k := parseKeyword(t)
+			if includePath, kind, _, includeLines, ok := parseIncludeValue(k.Value); ok {
+				options := k.Value[len(includePath)+2:]
+				if !filepath.IsAbs(includePath) {
+					includePath = filepath.Join(filepath.Dir(path), includePath)
+				}
+				if kind == "" {
+					included, err := d.includeTokens(includePath, includeLines, t.matches[1], append(stack, fullPath))
+					if err != nil {
+						return nil, err
+					}
+					tokens = append(tokens, included...)
+					continue
+				}
+				t = tokenize(fmt.Sprintf(`%s#+INCLUDE: "%s"%s`, t.matches[1], includePath, options))
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// parseIncludeValue parses the value of an #+INCLUDE keyword - kind is empty for org includes.
+func parseIncludeValue(value string) (path, kind, lang, lines string, ok bool) {
+	m := includeFileRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", "", "", false
+	}
+	path, options := m[1], m[2]
+	if m := includeLinesRegexp.FindStringSubmatch(options); m != nil {
+		lines, options = m[1]+"-"+m[2], strings.Replace(options, m[0], "", 1)
+	}
+	fields := strings.Fields(options)
+	if len(fields) != 0 && !strings.HasPrefix(fields[0], ":") {
+		switch kind = strings.ToLower(fields[0]); kind {
+		case "src", "example", "export":
+		default:
+			return "", "", "", "", false
+		}
+		if len(fields) > 1 && !strings.HasPrefix(fields[1], ":") {
+			lang = fields[1]
 		}
 	}
-	return 1, Include{k, resolve}
+	return path, kind, lang, lines, true
+}
+
+// includeLines returns the lines of content selected via :lines "5-10" - 1-based & inclusive, either bound may be omitted.
+func includeLines(content, lines string) string {
+	if lines == "" {
+		return content
+	}
+	parts, all := strings.SplitN(lines, "-", 2), strings.SplitAfter(content, "\n")
+	start, end := 1, len(all)
+	if n, err := strconv.Atoi(parts[0]); err == nil {
+		start = n
+	}
+	if n, err := strconv.Atoi(parts[1]); err == nil && n < end {
+		end = n
+	}
+	if start < 1 {
+		start = 1
+	}
+	if start > end {
+		return ""
+	}
+	return strings.Join(all[start-1:end], "")
 }
 
+// loadSetupFile imports the in-buffer settings (keywords, macros, links and abbreviations) of the file referenced by k.
+// The buffer's own settings win over those of the setup file, regardless of their position. The content of the setup file is not exported. Setup files can load setup files themselves - cycles are logged and ignored.
 func (d *Document) loadSetupFile(k Keyword) (int, Node) {
 	path := k.Value
 	if !filepath.IsAbs(path) {
 		path = filepath.Join(filepath.Dir(d.Path), path)
 	}
+	stack := append(append([]string{}, d.setupFiles...), d.Path)
+	for _, p := range stack {
+		if filepath.Clean(p) == filepath.Clean(path) {
+			d.Log.Printf("Bad setup file: %#v: setup file cycle: %s -> %s", k, strings.Join(stack, " -> "), path)
+			return 1, k
+		}
+	}
 	bs, err := d.ReadFile(path)
 	if err != nil {
 		d.Log.Printf("Bad setup file: %#v: %s", k, err)
 		return 1, k
 	}
-	setupDocument := d.Configuration.Parse(bytes.NewReader(bs), path)
+	setupDocument := d.Configuration.parse(bytes.NewReader(bs), path, stack)
 	if err := setupDocument.Error; err != nil {
 		d.Log.Printf("Bad setup file: %#v: %s", k, err)
 		return 1, k
 	}
 	for k, v := range setupDocument.BufferSettings {
-		d.BufferSettings[k] = v
+		addSetting(d.setupSettings, k, v)
+		d.updateBufferSetting(k)
 	}
+	importDefinitions(d.Macros, setupDocument.Macros)
+	importDefinitions(d.Links, setupDocument.Links)
+	importDefinitions(d.Abbreviations, setupDocument.Abbreviations)
 	return 1, k
 }
 
+// importDefinitions adds the definitions of a setup file to definitions - existing definitions of the buffer win.
+func importDefinitions(definitions, setupDefinitions map[string]string) {
+	for k, v := range setupDefinitions {
+		if _, ok := definitions[k]; !ok {
+			definitions[k] = v
+		}
+	}
+}
+
 func (n Comment) String() string      { return orgWriter.WriteNodesAsString(n) }
 func (n Keyword) String() string      { return orgWriter.WriteNodesAsString(n) }
 func (n NodeWithMeta) String() string { return orgWriter.WriteNodesAsString(n) }
@@ -0,0 +1,81 @@
+package org
+
+import (
+	"path"
+	"strings"
+)
+
+// linkResolver resolves internal links to their targets. It indexes the headlines of a document once - see newLinkResolver.
+type linkResolver struct {
+	document  *Document
+	customIDs map[string]Headline // customIDs maps Headline.ID to the first headline with that id.
+	ids       map[string]Headline // ids maps ID properties to the first headline with that ID.
+	titles    map[string]Headline // titles maps the trimmed plain text titles to the first headline with that title.
+}
+
+// linkTarget is the target of an internal link - exactly one of its fields is set for links that resolve.
+type linkTarget struct {
+	headline    *Headline
+	target      string // target is the name of a <<target>>.
+	radioTarget string // radioTarget is the name of a <<<radio target>>>.
+	name        string // name is the #+NAME: of a node.
+}
+
+func newLinkResolver(d *Document) *linkResolver {
+	r := &linkResolver{d, map[string]Headline{}, map[string]Headline{}, map[string]Headline{}}
+	add := func(m map[string]Headline, key string, h Headline) {
+		if _, ok := m[key]; !ok {
+			m[key] = h
+		}
+	}
+	walkNodes(d.Nodes, func(n Node) {
+		if h, ok := n.(Headline); ok {
+			add(r.customIDs, h.ID(), h)
+			add(r.titles, strings.TrimSpace(plainText(h.Title)), h)
+			if id, ok := h.Properties.Get("ID"); ok {
+				add(r.ids, id, h)
+			}
+		}
+	})
+	return r
+}
+
+// resolve returns the target of l: [[#custom-id]], [[*Headline]] & [[id:...]] link to headlines,
+// [[name]] links to <<targets>> & <<<radio targets>>> (case-insensitive), named nodes and (as a fallback) headlines with that title.
+// The target is empty for broken links. internal is false for external links, links to files and link abbreviations.
+func (r *linkResolver) resolve(l RegularLink) (t linkTarget, internal bool) {
+	headline := func(m map[string]Headline, key string) linkTarget {
+		if h, ok := m[key]; ok {
+			return linkTarget{headline: &h}
+		}
+		return linkTarget{}
+	}
+	switch {
+	case l.Protocol == "id":
+		return headline(r.ids, l.URL[len("id:"):]), true
+	case l.Protocol != "" || l.AutoLink || r.document.Links[l.URL] != "":
+		return linkTarget{}, false
+	case strings.HasPrefix(l.URL, "#"):
+		return headline(r.customIDs, l.URL[1:]), true
+	case strings.HasPrefix(l.URL, "*"):
+		return headline(r.titles, strings.TrimSpace(l.URL[1:])), true
+	case strings.ContainsAny(l.URL, "/~") || path.Ext(l.URL) != "":
+		return linkTarget{}, false // relative file link, e.g. [[./foo]] or [[foo.org]]
+	}
+	for _, name := range r.document.Targets {
+		if strings.EqualFold(name, l.URL) {
+			return linkTarget{target: name}, true
+		}
+	}
+	for _, name := range r.document.RadioTargets {
+		if strings.EqualFold(name, l.URL) {
+			return linkTarget{radioTarget: name}, true
+		}
+	}
+	if _, ok := r.document.NamedNodes[l.URL]; ok {
+		return linkTarget{name: l.URL}, true
+	}
+	return headline(r.titles, l.URL), true
+}
+
+func (t linkTarget) isEmpty() bool { return t == linkTarget{} }
@@ -17,6 +17,7 @@ type ListItem struct {
 	Status   string
 	Value    string
 	Children []Node
+	Spacing  int // Spacing is the number of spaces between the bullet and the content - 0 means the default single space.
 }
 
 type DescriptiveListItem struct {
@@ -24,11 +25,13 @@ type DescriptiveListItem struct {
 	Status  string
 	Term    []Node
 	Details []Node
+	Spacing int // Spacing is the number of spaces between the bullet and the content - 0 means the default single space.
 }
 
 var unorderedListRegexp = regexp.MustCompile(`^(\s*)([+*-])(\s+(.*)|$)`)
 var orderedListRegexp = regexp.MustCompile(`^(\s*)(([0-9]+|[a-zA-Z])[.)])(\s+(.*)|$)`)
 var descriptiveListItemRegexp = regexp.MustCompile(`\s::(\s|$)`)
+var descriptiveListItemProtectedRegexp = regexp.MustCompile(`\[\[.*?\]\]|(?:^|[\s({'"])(?:=[^\s=](?:.*?\S)?=|~[^\s~](?:.*?\S)?~)`)
 var listItemValueRegexp = regexp.MustCompile(`\[@(\d+)\]\s`)
 var listItemStatusRegexp = regexp.MustCompile(`\[( |X|-)\]\s`)
 
@@ -55,7 +58,7 @@ func listKind(t token) (string, string) {
 	default:
 		panic(fmt.Sprintf("bad list bullet '%s': %#v", bullet, t))
 	}
-	if descriptiveListItemRegexp.MatchString(t.content) {
+	if descriptiveListItemSeparator(t.content) != nil {
 		return kind, "descriptive"
 	}
 	return kind, kind
@@ -83,8 +86,12 @@ func (d *Document) parseList(i int, parentStop stopFn) (int, Node) {
 func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 	start, nodes, bullet := i, []Node{}, d.tokens[i].matches[2]
 	minIndent, dterm, content, status, value := d.tokens[i].lvl+len(bullet), "", d.tokens[i].content, "", ""
+	spacing := len(d.tokens[i].matches[0]) - minIndent - len(strings.TrimLeftFunc(d.tokens[i].matches[0][minIndent:], unicode.IsSpace))
+	if content == "" {
+		spacing = 1
+	}
 	originalBaseLvl := d.baseLvl
-	d.baseLvl = minIndent + 1
+	d.baseLvl = minIndent + spacing
 	if m := listItemValueRegexp.FindStringSubmatch(content); m != nil && l.Kind == "ordered" {
 		value, content = m[1], content[len("[@] ")+len(m[1]):]
 	}
@@ -92,9 +99,9 @@ func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 		status, content = m[1], content[len("[ ] "):]
 	}
 	if l.Kind == "descriptive" {
-		if m := descriptiveListItemRegexp.FindStringIndex(content); m != nil {
+		if m := descriptiveListItemSeparator(content); m != nil {
+			d.baseLvl = len(d.tokens[i].matches[0]) - len(content) + m[0] + 4
 			dterm, content = content[:m[0]], content[m[1]:]
-			d.baseLvl = strings.Index(d.tokens[i].matches[0], " ::") + 4
 		}
 	}
 
@@ -113,9 +120,23 @@ func (d *Document) parseListItem(l List, i int, parentStop stopFn) (int, Node) {
 	}
 	d.baseLvl = originalBaseLvl
 	if l.Kind == "descriptive" {
-		return i - start, DescriptiveListItem{bullet, status, d.parseInline(dterm), nodes}
+		return i - start, DescriptiveListItem{bullet, status, d.parseInline(dterm), nodes, spacing}
+	}
+	return i - start, ListItem{bullet, status, value, nodes, spacing}
+}
+
+// descriptiveListItemSeparator returns the index of the first :: separating the term of a descriptive list item from its details.
+// A :: inside a link or verbatim / code does not count.
+func descriptiveListItemSeparator(content string) []int {
+	masked := []byte(content)
+	for _, m := range descriptiveListItemProtectedRegexp.FindAllStringIndex(content, -1) {
+		for j := m[0]; j < m[1]; j++ {
+			if !unicode.IsSpace(rune(masked[j])) {
+				masked[j] = 'x'
+			}
+		}
 	}
-	return i - start, ListItem{bullet, status, value, nodes}
+	return descriptiveListItemRegexp.FindStringIndex(string(masked))
 }
 
 func (n List) String() string                { return orgWriter.WriteNodesAsString(n) }
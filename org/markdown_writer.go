@@ -0,0 +1,515 @@
+package org
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MarkdownWriter exports an org document into GitHub flavored markdown.
+// Org features without a markdown equivalent (e.g. drawers, keywords and tags) are dropped.
+type MarkdownWriter struct {
+	ExtendingWriter Writer
+	LanguageAliases map[string]string // Map src block languages to the language of the fence (e.g. emacs-lisp -> elisp). Unmapped languages are used as is.
+
+	lineWriter
+	document      *Document
+	footnotes     *footnotes
+	footnoteNames map[string]bool // footnoteNames are the names of the named footnotes of the document - see footnoteName.
+}
+
+var emphasisMarkdownBorders = map[string][]string{
+	"_":   []string{"<ins>", "</ins>"},
+	"*":   []string{"**", "**"},
+	"/":   []string{"_", "_"},
+	"+":   []string{"~~", "~~"},
+	"_{}": []string{"<sub>", "</sub>"},
+	"^{}": []string{"<sup>", "</sup>"},
+	"_x":  []string{"<sub>", "</sub>"},
+	"^x":  []string{"<sup>", "</sup>"},
+}
+
+var markdownTableAlignments = map[string]string{
+	"":       "---",
+	"left":   ":---",
+	"right":  "---:",
+	"center": ":---:",
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`)
+
+func NewMarkdownWriter() *MarkdownWriter {
+	defaultConfig := New()
+	return &MarkdownWriter{
+		document:  &Document{Configuration: defaultConfig},
+		footnotes: &footnotes{mapping: map[string]int{}},
+	}
+}
+
+func (w *MarkdownWriter) WriterWithExtensions() Writer {
+	if w.ExtendingWriter != nil {
+		return w.ExtendingWriter
+	}
+	return w
+}
+
+func (w *MarkdownWriter) Before(d *Document) {
+	w.document, w.footnoteNames = d, map[string]bool{}
+	walkNodes(d.Nodes, func(n Node) {
+		switch n := n.(type) {
+		case FootnoteLink:
+			w.footnoteNames[n.Name] = n.Name != ""
+		case FootnoteDefinition:
+			w.footnoteNames[n.Name] = n.Name != ""
+		}
+	})
+}
+
+func (w *MarkdownWriter) After(d *Document) {
+	w.WriteFootnotes(d)
+}
+
+func (w *MarkdownWriter) WriteNodesAsString(nodes ...Node) string {
+	builder := w.Builder
+	w.Builder = strings.Builder{}
+	defer func() { w.Builder = builder }()
+	WriteNodes(w, nodes...)
+	return w.String()
+}
+
+func (w *MarkdownWriter) WriteHeadline(h Headline) {
+	if h.IsExcluded(w.document) {
+		return
+	}
+	w.startBlock()
+	lvl := h.Lvl
+	if lvl > 6 {
+		lvl = 6
+	}
+	w.WriteString(strings.Repeat("#", lvl) + " ")
+	if h.Status != "" && w.document.GetOption("todo") != "nil" {
+		w.WriteString(h.Status + " ")
+	}
+	if h.Priority != "" && w.document.GetOption("pri") != "nil" {
+		w.WriteString(`\[#` + h.Priority + `\] `)
+	}
+	WriteNodes(w, h.Title...)
+	w.WriteString("\n")
+	WriteNodes(w, h.Children...)
+}
+
+func (w *MarkdownWriter) WriteBlock(b Block) {
+	content, params := rawContent(b.Children), b.ParameterMap()
+	switch b.Name {
+	case "SRC":
+		if params[":exports"] == "results" || params[":exports"] == "none" {
+			break
+		}
+		lang := params[":lang"]
+		if alias, ok := w.LanguageAliases[lang]; ok {
+			lang = alias
+		}
+		w.writeFencedCodeBlock(content, lang)
+	case "EXAMPLE":
+		w.writeFencedCodeBlock(content, "")
+	case "EXPORT":
+		if len(b.Parameters) >= 1 {
+			switch strings.ToLower(b.Parameters[0]) {
+			case "markdown", "md", "html":
+				w.startBlock()
+				w.writeLines(content)
+			}
+		}
+	case "COMMENT": // comment blocks are not exported
+	case "QUOTE":
+		w.startBlock()
+		indent := w.indent
+		w.indent += "> "
+		WriteNodes(w, b.Children...)
+		w.indent = indent
+	default:
+		WriteNodes(w, b.Children...)
+	}
+
+	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" && !hasResultsParameter(params, "silent") {
+		if file, ok := resultFile(params); ok {
+			WriteNodes(w, Paragraph{[]Node{RegularLink{"file", nil, "file:" + file, false}}})
+		} else {
+			WriteNodes(w, b.Result)
+		}
+	}
+}
+
+// writeFencedCodeBlock writes content as a fenced code block - the fence is longer than any backtick run of content.
+func (w *MarkdownWriter) writeFencedCodeBlock(content, lang string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	w.startBlock()
+	w.WriteString(w.indent + fence + lang + "\n")
+	if content = strings.TrimRight(content, "\n"); content != "" {
+		w.writeLines(content)
+	}
+	w.WriteString(w.indent + fence + "\n")
+}
+
+func (w *MarkdownWriter) WriteResult(r Result) { WriteNodes(w, r.Node) }
+
+func (w *MarkdownWriter) WriteInlineBlock(b InlineBlock) {
+	switch b.Name {
+	case "src":
+		w.writeCodeSpan(rawContent(b.Children))
+	case "export":
+		switch strings.ToLower(b.Parameters[0]) {
+		case "markdown", "md", "html":
+			w.WriteString(rawContent(b.Children))
+		}
+	}
+}
+
+// WriteInlineBabelCall writes the call itself for :exports code & both (see HTMLWriter.WriteInlineBabelCall).
+func (w *MarkdownWriter) WriteInlineBabelCall(c InlineBabelCall) {
+	switch c.ParameterMap()[":exports"] {
+	case "code", "both":
+		w.writeCodeSpan(c.String())
+	}
+}
+
+// writeCodeSpan writes content as a code span - delimited by more backticks than any backtick run of content.
+func (w *MarkdownWriter) writeCodeSpan(content string) {
+	delimiter := "`"
+	for strings.Contains(content, delimiter) {
+		delimiter += "`"
+	}
+	if strings.HasPrefix(content, "`") || strings.HasSuffix(content, "`") {
+		content = " " + content + " "
+	}
+	w.WriteString(delimiter + content + delimiter)
+}
+
+func (w *MarkdownWriter) WriteExample(e Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = rawContent([]Node{n})
+	}
+	w.writeFencedCodeBlock(strings.Join(lines, "\n"), "")
+}
+
+func (w *MarkdownWriter) WriteLatexBlock(b LatexBlock) {
+	w.startBlock()
+	w.WriteString(w.indent + "$$\n" + w.indent + strings.ReplaceAll(b.Latex(), "\n", "\n"+w.indent) + "\n" + w.indent + "$$\n")
+}
+
+func (w *MarkdownWriter) WriteDrawer(d Drawer) {
+	WriteNodes(w, d.Children...)
+}
+
+func (w *MarkdownWriter) WriteKeyword(k Keyword) {}
+
+func (w *MarkdownWriter) WriteInclude(i Include) {
+	WriteNodes(w, i.Nodes()...)
+}
+
+func (w *MarkdownWriter) WriteComment(Comment)               {}
+func (w *MarkdownWriter) WritePropertyDrawer(PropertyDrawer) {}
+
+func (w *MarkdownWriter) WriteNodeWithMeta(n NodeWithMeta) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *MarkdownWriter) WriteNodeWithName(n NodeWithName) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *MarkdownWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
+	w.footnotes.updateDefinition(f)
+}
+
+// WriteFootnotes writes the definitions of all referenced footnotes - continuation lines are indented by 4 spaces.
+func (w *MarkdownWriter) WriteFootnotes(d *Document) {
+	if w.document.GetOption("f") == "nil" || len(w.footnotes.list) == 0 {
+		return
+	}
+	for i := 0; i < len(w.footnotes.list); i++ {
+		definition := w.footnotes.list[i]
+		if definition == nil && d.Footnotes != nil {
+			definition = d.Footnotes.Definitions[w.footnotes.names[i]]
+		}
+		if definition == nil {
+			d.Log.Printf("Missing footnote definition for [fn:%s] (#%d)", w.footnotes.names[i], i+1)
+			continue
+		}
+		builder, indent := w.Builder, w.indent
+		w.Builder, w.indent = strings.Builder{}, indent+"    "
+		WriteNodes(w, definition.Children...)
+		content := strings.TrimRight(strings.TrimPrefix(w.String(), w.indent), "\n")
+		w.Builder, w.indent = builder, indent
+		w.startBlock()
+		w.WriteString(fmt.Sprintf("[^%s]: %s\n", w.footnoteName(i), content))
+	}
+}
+
+// footnoteName returns the label of the i-th footnote: its name or, for anonymous footnotes, its number -
+// suffixed with -1, -2, ... if a named footnote of the document (e.g. [fn:1]) already uses it.
+func (w *MarkdownWriter) footnoteName(i int) string {
+	if name := w.footnotes.names[i]; name != "" {
+		return name
+	}
+	name := fmt.Sprint(i + 1)
+	for j := 1; w.footnoteNames[name]; j++ {
+		name = fmt.Sprintf("%d-%d", i+1, j)
+	}
+	return name
+}
+
+func (w *MarkdownWriter) WriteParagraph(p Paragraph) {
+	if p, ok := firstParagraph([]Node{p}); ok {
+		w.startBlock()
+		w.WriteString(w.indent + w.WriteNodesAsString(p.Children...) + "\n")
+	}
+}
+
+func (w *MarkdownWriter) WriteList(l List) {
+	if w.indent == "" || strings.HasSuffix(w.indent, "> ") {
+		w.startBlock()
+	}
+	WriteNodes(w, l.Items...)
+}
+
+func (w *MarkdownWriter) WriteListItem(li ListItem) {
+	bullet := "-"
+	if last := li.Bullet[len(li.Bullet)-1]; last == '.' || last == ')' {
+		bullet = "1." // markdown only supports numeric bullets - items are numbered by their position
+		if _, err := strconv.Atoi(li.Bullet[:len(li.Bullet)-1]); err == nil {
+			bullet = li.Bullet[:len(li.Bullet)-1] + "."
+		}
+		if _, err := strconv.Atoi(li.Value); err == nil {
+			bullet = li.Value + "."
+		}
+	}
+	w.writeListItem(bullet, li.Status, li.Children)
+}
+
+// WriteDescriptiveListItem writes descriptive list items as unordered list items with a bold term: - **term**: details.
+func (w *MarkdownWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
+	details := di.Details
+	if len(di.Term) != 0 {
+		term := []Node{Emphasis{"*", di.Term}, Text{":", false}}
+		if p, ok := firstParagraph(details); ok {
+			details = append([]Node{Paragraph{append(append(term, Text{" ", false}), p.Children...)}}, details[1:]...)
+		} else {
+			details = append([]Node{Paragraph{term}}, details...)
+		}
+	}
+	w.writeListItem("-", di.Status, details)
+}
+
+// firstParagraph returns the first node of nodes without its leading line breaks if it is a non-empty paragraph.
+// A trailing explicit line break (and the whitespace before it) is dropped as well - there is no line left to break.
+func firstParagraph(nodes []Node) (Paragraph, bool) {
+	if len(nodes) == 0 {
+		return Paragraph{}, false
+	}
+	p, ok := nodes[0].(Paragraph)
+	for ok && len(p.Children) != 0 {
+		if _, isLineBreak := p.Children[0].(LineBreak); !isLineBreak {
+			break
+		}
+		p.Children = p.Children[1:]
+	}
+	if n := len(p.Children); ok && n > 1 {
+		if _, isExplicitLineBreak := p.Children[n-1].(ExplicitLineBreak); isExplicitLineBreak {
+			p.Children = append([]Node{}, p.Children[:n-1]...)
+			if t, isText := p.Children[n-2].(Text); isText {
+				t.Content = strings.TrimRightFunc(t.Content, unicode.IsSpace)
+				p.Children[n-2] = t
+			}
+		}
+	}
+	return p, ok && len(p.Children) != 0
+}
+
+func (w *MarkdownWriter) writeListItem(bullet, status string, children []Node) {
+	builder, indent := w.Builder, w.indent
+	w.Builder, w.indent = strings.Builder{}, indent+strings.Repeat(" ", len(bullet)+1)
+	WriteNodes(w, children...)
+	content := strings.TrimRight(strings.TrimPrefix(w.String(), w.indent), "\n")
+	w.Builder, w.indent = builder, indent
+	w.WriteString(w.indent + bullet)
+	switch status {
+	case "X":
+		w.WriteString(" [x]")
+	case " ", "-":
+		w.WriteString(" [ ]")
+	}
+	if content != "" {
+		w.WriteString(" " + content)
+	}
+	w.WriteString("\n")
+}
+
+// WriteTable writes t as a pipe table. The row before the first separator is the header -
+// tables without a (single row) header get an empty one as markdown tables require exactly one header row.
+func (w *MarkdownWriter) WriteTable(t Table) {
+	w.startBlock()
+	rows, headerRows := [][]Column{}, 0
+	for i, row := range t.Rows {
+		if row.IsSpecial || len(row.Columns) == 0 {
+			continue
+		}
+		if len(t.SeparatorIndices) != 0 && i < t.SeparatorIndices[0] {
+			headerRows++
+		}
+		rows = append(rows, row.Columns)
+	}
+	if headerRows != 1 {
+		rows = append([][]Column{nil}, rows...)
+	}
+	for i, columns := range rows {
+		w.writeTableRow(columns, len(t.ColumnInfos))
+		if i == 0 {
+			w.WriteString(w.indent + "|")
+			for _, info := range t.ColumnInfos {
+				w.WriteString(" " + markdownTableAlignments[info.Align] + " |")
+			}
+			w.WriteString("\n")
+		}
+	}
+}
+
+func (w *MarkdownWriter) writeTableRow(columns []Column, n int) {
+	w.WriteString(w.indent + "|")
+	for i := 0; i < n; i++ {
+		content := ""
+		if i < len(columns) {
+			content = strings.ReplaceAll(w.WriteNodesAsString(columns[i].Children...), "|", `\|`)
+		}
+		w.WriteString(" " + content + " |")
+	}
+	w.WriteString("\n")
+}
+
+func (w *MarkdownWriter) WriteHorizontalRule(hr HorizontalRule) {
+	w.startBlock()
+	w.WriteString(w.indent + "---\n")
+}
+
+func (w *MarkdownWriter) WriteText(t Text) {
+	content := t.Content
+	if !t.IsRaw && w.document.GetOption("e") != "nil" {
+		content = specialStringReplacer.Replace(content)
+	}
+	if !t.IsRaw {
+		content = markdownEscaper.Replace(content)
+	}
+	w.WriteString(content)
+}
+
+func (w *MarkdownWriter) WriteEmphasis(e Emphasis) {
+	if e.Kind == "~" || e.Kind == "=" {
+		w.writeCodeSpan(rawContent(e.Content))
+		return
+	}
+	borders, ok := emphasisMarkdownBorders[e.Kind]
+	if !ok {
+		borders = orgEmphasisBorders(e.Kind)
+	}
+	w.WriteString(borders[0])
+	WriteNodes(w, e.Content...)
+	w.WriteString(borders[1])
+}
+
+// WriteLatexFragment writes inline \(...\) & display \[...\] math with the $ & $$ delimiters supported by GitHub.
+func (w *MarkdownWriter) WriteLatexFragment(l LatexFragment) {
+	openingPair, closingPair := l.OpeningPair, l.ClosingPair
+	switch openingPair {
+	case `\(`:
+		openingPair, closingPair = "$", "$"
+	case `\[`:
+		openingPair, closingPair = "$$", "$$"
+	}
+	w.WriteString(openingPair + rawContent(l.Content) + closingPair)
+}
+
+func (w *MarkdownWriter) WriteStatisticToken(s StatisticToken) {
+	w.WriteString(`\[` + s.Content + `\]`)
+}
+
+func (w *MarkdownWriter) WriteLineBreak(l LineBreak) {
+	w.WriteString(strings.Repeat("\n"+w.indent, l.Count))
+}
+
+func (w *MarkdownWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
+	w.WriteString(`\` + "\n" + w.indent)
+}
+
+func (w *MarkdownWriter) WriteRadioTarget(t RadioTarget) {
+	w.WriteText(Text{t.Name, false})
+}
+
+func (w *MarkdownWriter) WriteEntity(e Entity) {
+	if w.document.GetOption("e") == "nil" {
+		w.WriteText(Text{e.String(), false})
+	} else {
+		w.WriteString(e.UTF8())
+	}
+}
+
+func (w *MarkdownWriter) WriteTarget(t Target) {
+	w.WriteString(fmt.Sprintf(`<a id="%s"></a>`, html.EscapeString(t.Name)))
+}
+
+func (w *MarkdownWriter) WriteTimestamp(t Timestamp) {
+	if w.document.GetOption("<") == "nil" {
+		return
+	}
+	w.WriteString(markdownEscaper.Replace(t.orgString()))
+}
+
+func (w *MarkdownWriter) WriteFootnoteLink(l FootnoteLink) {
+	if w.document.GetOption("f") == "nil" {
+		return
+	}
+	i := w.footnotes.add(l)
+	w.WriteString("[^" + w.footnoteName(i) + "]")
+}
+
+func (w *MarkdownWriter) WriteRegularLink(l RegularLink) {
+	if l.IsExecutable() {
+		w.document.Log.Printf("Not linking executable link: %s", l.URL)
+		if l.Description != nil {
+			WriteNodes(w, l.Description...)
+		} else {
+			w.WriteText(Text{l.URL, false})
+		}
+		return
+	}
+	url, expanded := w.document.ExpandLink(l)
+	if l.Protocol == "file" && !expanded {
+		url = url[len("file:"):]
+	}
+	url = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+	if l.Kind() == "image" {
+		alt := ""
+		if l.Description != nil {
+			alt = w.WriteNodesAsString(l.Description...)
+		}
+		w.WriteString(fmt.Sprintf("![%s](%s)", alt, url))
+	} else if l.Description == nil {
+		text, _ := w.document.ExpandLink(l)
+		w.WriteString(fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(text), url))
+	} else {
+		w.WriteString(fmt.Sprintf("[%s](%s)", w.WriteNodesAsString(l.Description...), url))
+	}
+}
+
+func (w *MarkdownWriter) WriteMacro(m Macro) {
+	if nodes, ok := w.document.ExpandMacro(m); ok {
+		WriteNodes(w, nodes...)
+	} else {
+		w.WriteText(Text{m.String(), true})
+	}
+}
@@ -0,0 +1,58 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownWriter(t *testing.T) {
+	testMarkdownWriter(t, map[string]string{
+		"* TODO [#A] Headline :tag:\n** Child\ntext": "# TODO \\[#A\\] Headline\n\n## Child\n\ntext\n",
+
+		"/italic/ *bold* +strike+ ~code~ =verbatim= and 2*3_4": "_italic_ **bold** ~~strike~~ `code` `verbatim` and 2\\*3\\_4\n",
+		"~a `b` c~": "``a `b` c``\n",
+		"[[https://example.com][a link]] [[https://example.com]]":  "[a link](https://example.com) [https://example.com](https://example.com)\n",
+		"[[file:foo.png]] [[./foo bar.org][foo]]":                  "![](foo.png) [foo](./foo%20bar.org)\n",
+		"#+LINK: gh https://github.com/%s\n[[gh:a/b]] [[gh:a][b]]": "[https://github.com/a/b](https://github.com/a/b) [b](https://github.com/a)\n",
+		"[[elisp:(org-agenda)][agenda]] [[shell:ls *]]":            "agenda shell:ls \\*\n",
+		"a hard \\\\\nbreak \\\\\n\nparagraph":                     "a hard \\\nbreak\n\nparagraph\n",
+
+		"- a\n  - b\n  - [X] c\n- d\n\n  second paragraph\n": "- a\n  - b\n  - [x] c\n- d\n\n  second paragraph\n",
+		"1. one\n2. two\n   - nested\n":                      "1. one\n2. two\n   - nested\n",
+		"- term :: details\n":                                "- **term**: details\n",
+
+		"| a | b |\n|---+---|\n| 1 | x \\vert{} y |\n": "| a | b |\n| ---: | --- |\n| 1 | x \\| y |\n",
+		"| 1 | 2 |\n": "|  |  |\n| ---: | ---: |\n| 1 | 2 |\n",
+
+		"#+BEGIN_SRC go\nfunc main() {}\n#+END_SRC\n":             "```go\nfunc main() {}\n```\n",
+		"#+BEGIN_EXAMPLE\n```\n#+END_EXAMPLE\n":                   "````\n```\n````\n",
+		"- item\n  #+BEGIN_SRC sh\n  echo a\n  #+END_SRC\n":       "- item\n\n  ```sh\n  echo a\n  ```\n",
+		"#+BEGIN_QUOTE\nquoted\ntext\n\nmore\n#+END_QUOTE\n":      "> quoted\n> text\n>\n> more\n",
+		"#+BEGIN_EXPORT markdown\n<kbd>raw</kbd>\n#+END_EXPORT\n": "<kbd>raw</kbd>\n",
+
+		"a footnote[fn:1] and an inline one[fn::inline]\n\n[fn:1] the definition\n": "a footnote[^1] and an inline one[^2]\n\n[^1]: the definition\n\n[^2]: inline\n",
+		"an inline footnote[fn::inline] and[fn:1]\n\n[fn:1] the definition\n":       "an inline footnote[^1-1] and[^1]\n\n[^1-1]: inline\n\n[^1]: the definition\n",
+	}, func(*MarkdownWriter) {})
+}
+
+func TestMarkdownLanguageAliases(t *testing.T) {
+	testMarkdownWriter(t, map[string]string{
+		"#+BEGIN_SRC emacs-lisp\n(message \"hi\")\n#+END_SRC\n": "```elisp\n(message \"hi\")\n```\n",
+		"#+BEGIN_SRC python\nprint(1)\n#+END_SRC\n":             "```python\nprint(1)\n```\n",
+	}, func(w *MarkdownWriter) { w.LanguageAliases = map[string]string{"emacs-lisp": "elisp"} })
+}
+
+func testMarkdownWriter(t *testing.T, tests map[string]string, setup func(*MarkdownWriter)) {
+	for org, expected := range tests {
+		t.Run(org, func(t *testing.T) {
+			writer := NewMarkdownWriter()
+			setup(writer)
+			actual, err := New().Silent().Parse(strings.NewReader(org), "./test.org").Write(writer)
+			if err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual != expected {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected))
+			}
+		})
+	}
+}
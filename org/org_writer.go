@@ -3,6 +3,7 @@ package org
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -10,11 +11,24 @@ import (
 
 // OrgWriter export an org document into pretty printed org document.
 type OrgWriter struct {
-	ExtendingWriter Writer
-	TagsColumn      int
+	ExtendingWriter          Writer
+	TagsColumn               int
+	NodeRenderHook           func(w Writer, n Node) (string, bool) // NodeRenderHook renders nodes unknown to the writer. Defaults to Node.String().
+	NormalizeListItemSpacing bool                                  // Write a single space after list bullets instead of the parsed Spacing.
+	NumberFootnotes          bool                                  // Rename footnotes to their number in order of definition (see Footnotes.Ordered) - anonymous footnotes are numbered too.
+	TableTabWidth            int                                   // Expand tabs in table cells to spaces up to the next multiple of this width (relative to the start of the cell) so columns align. Disabled if 0.
+	ListBullet               string                                // Write the bullets of unordered (& descriptive) lists as this bullet, e.g. - (* is only used for indented lists). Disabled if empty.
+	FillColumn               int                                   // Wrap paragraphs at word boundaries to this column (including the indent) like org-fill-paragraph. Links, emphasis & other markup are never split. Disabled if 0.
+	RenumberOrderedLists     bool                                  // Number the items of ordered lists sequentially from 1 (or the [@N] counter of an item) rather than writing the parsed numbers.
+	CollapseLineBreaks       bool                                  // Write explicit line breaks (\\) as plain newlines.
+	PreserveKeywordCase      bool                                  // Write custom keywords in their original case (e.g. #+my_Key:) - keywords known to go-org (e.g. TITLE) are always upper case.
+	AlignProperties          bool                                  // Align property values like org-property-format (%-10s) instead of keeping the parsed whitespace after the key.
 
 	strings.Builder
-	indent string
+	indent          string
+	footnoteNumbers map[string]int
+	inlineFootnotes map[*FootnoteDefinition]int
+	keywordCases    map[string]string
 }
 
 var exampleBlockUnescapeRegexp = regexp.MustCompile(`(^|\n)([ \t]*)(\*|,\*|#\+|,#\+)`)
@@ -28,11 +42,22 @@ var emphasisOrgBorders = map[string][]string{
 	"=":   []string{"=", "="},
 	"_{}": []string{"_{", "}"},
 	"^{}": []string{"^{", "}"},
+	"_x":  []string{"_", ""},
+	"^x":  []string{"^", ""},
+}
+
+// orgEmphasisBorders returns the Org mode borders of an emphasis kind - custom Configuration.EmphasisMarkers are their own borders.
+func orgEmphasisBorders(kind string) []string {
+	if borders, ok := emphasisOrgBorders[kind]; ok {
+		return borders
+	}
+	return []string{kind, kind}
 }
 
 func NewOrgWriter() *OrgWriter {
 	return &OrgWriter{
-		TagsColumn: 77,
+		TagsColumn:    77,
+		TableTabWidth: 8,
 	}
 }
 
@@ -43,16 +68,61 @@ func (w *OrgWriter) WriterWithExtensions() Writer {
 	return w
 }
 
-func (w *OrgWriter) Before(d *Document) {}
-func (w *OrgWriter) After(d *Document)  {}
+func (w *OrgWriter) Before(d *Document) {
+	w.keywordCases = d.keywordCases
+	w.footnoteNumbers, w.inlineFootnotes = nil, nil
+	if w.NumberFootnotes {
+		w.footnoteNumbers, w.inlineFootnotes = map[string]int{}, map[*FootnoteDefinition]int{}
+		if d.Footnotes != nil {
+			for i, f := range d.Footnotes.Ordered(true) {
+				if f != nil && f.Name != "" {
+					w.footnoteNumbers[f.Name] = i + 1
+				} else if f != nil {
+					w.inlineFootnotes[f] = i + 1
+				}
+			}
+		}
+	}
+}
+
+func (w *OrgWriter) After(d *Document) {}
+
+// footnoteName returns the name of the footnote with the given name (and inline definition) - its number if NumberFootnotes is set.
+// Footnotes missing from Footnotes.Ordered (e.g. unreferenced definitions) are numbered after all others.
+func (w *OrgWriter) footnoteName(name string, inline *FootnoteDefinition) string {
+	if w.footnoteNumbers == nil {
+		return name
+	}
+	next := len(w.footnoteNumbers) + len(w.inlineFootnotes) + 1
+	if name == "" {
+		if _, ok := w.inlineFootnotes[inline]; !ok {
+			w.inlineFootnotes[inline] = next
+		}
+		return strconv.Itoa(w.inlineFootnotes[inline])
+	}
+	if _, ok := w.footnoteNumbers[name]; !ok {
+		w.footnoteNumbers[name] = next
+	}
+	return strconv.Itoa(w.footnoteNumbers[name])
+}
 
 func (w *OrgWriter) WriteNodesAsString(nodes ...Node) string {
 	builder := w.Builder
 	w.Builder = strings.Builder{}
+	defer func() { w.Builder = builder }()
 	WriteNodes(w, nodes...)
-	out := w.String()
-	w.Builder = builder
-	return out
+	return w.String()
+}
+
+func (w *OrgWriter) WriteCustomNode(n Node) bool {
+	if w.NodeRenderHook != nil {
+		if out, ok := w.NodeRenderHook(w.WriterWithExtensions(), n); ok {
+			w.WriteString(out)
+			return true
+		}
+	}
+	w.WriteString(n.String())
+	return true
 }
 
 func (w *OrgWriter) WriteHeadline(h Headline) {
@@ -75,6 +145,9 @@ func (w *OrgWriter) WriteHeadline(h Headline) {
 		}
 	}
 	w.WriteString("\n")
+	if planning := h.planningLine(); planning != "" {
+		w.WriteString(planning + "\n")
+	}
 	if len(h.Children) != 0 {
 		w.WriteString(w.indent)
 	}
@@ -86,15 +159,22 @@ func (w *OrgWriter) WriteHeadline(h Headline) {
 
 func (w *OrgWriter) WriteBlock(b Block) {
 	w.WriteString(w.indent + "#+BEGIN_" + b.Name)
-	if len(b.Parameters) != 0 {
-		w.WriteString(" " + strings.Join(b.Parameters, " "))
+	if line, ok := b.parameterLine(); ok && line != "" {
+		w.WriteString(" " + line)
+	} else if parameters := b.Parameters; len(parameters) != 0 || len(b.Switches) != 0 {
+		if len(parameters) != 0 && !strings.HasPrefix(parameters[0], ":") {
+			parameters = append(append([]string{parameters[0]}, b.Switches...), parameters[1:]...)
+		} else {
+			parameters = append(append([]string{}, b.Switches...), parameters...)
+		}
+		w.WriteString(" " + strings.Join(parameters, " "))
 	}
 	w.WriteString("\n")
 	if isRawTextBlock(b.Name) {
 		w.WriteString(w.indent)
 	}
 	content := w.WriteNodesAsString(b.Children...)
-	if b.Name == "EXAMPLE" || (b.Name == "SRC" && len(b.Parameters) >= 1 && b.Parameters[0] == "org") {
+	if isRawTextBlock(b.Name) {
 		content = exampleBlockUnescapeRegexp.ReplaceAllString(content, "$1$2,$3")
 	}
 	w.WriteString(content)
@@ -109,6 +189,11 @@ func (w *OrgWriter) WriteBlock(b Block) {
 	}
 }
 
+func (w *OrgWriter) WriteLatexBlock(b LatexBlock) {
+	indent := w.indent + b.Indent
+	w.WriteString(indent + strings.ReplaceAll(b.Latex(), "\n", "\n"+indent) + "\n")
+}
+
 func (w *OrgWriter) WriteResult(r Result) {
 	w.WriteString("#+RESULTS:\n")
 	WriteNodes(w, r.Node)
@@ -131,6 +216,17 @@ func (w *OrgWriter) WriteInlineBlock(b InlineBlock) {
 	}
 }
 
+func (w *OrgWriter) WriteInlineBabelCall(c InlineBabelCall) {
+	w.WriteString("call_" + c.Name)
+	if c.InsideHeader != "" {
+		w.WriteString("[" + c.InsideHeader + "]")
+	}
+	w.WriteString("(" + c.Arguments + ")")
+	if c.EndHeader != "" {
+		w.WriteString("[" + c.EndHeader + "]")
+	}
+}
+
 func (w *OrgWriter) WriteDrawer(d Drawer) {
 	w.WriteString(w.indent + ":" + d.Name + ":\n")
 	WriteNodes(w, d.Children...)
@@ -138,19 +234,24 @@ func (w *OrgWriter) WriteDrawer(d Drawer) {
 }
 
 func (w *OrgWriter) WritePropertyDrawer(d PropertyDrawer) {
-	w.WriteString(":PROPERTIES:\n")
+	w.WriteString(w.indent + ":PROPERTIES:\n")
 	for _, kvPair := range d.Properties {
 		k, v := kvPair[0], kvPair[1]
-		if v != "" {
-			v = " " + v
+		if v == "" {
+			w.WriteString(w.indent + ":" + k + ":\n")
+		} else if separator, ok := d.separators[k]; ok && !w.AlignProperties {
+			w.WriteString(w.indent + ":" + k + ":" + separator + v + "\n")
+		} else if w.AlignProperties {
+			w.WriteString(w.indent + fmt.Sprintf("%-10s %s\n", ":"+k+":", v)) // aligned like org-property-format
+		} else {
+			w.WriteString(w.indent + ":" + k + ": " + v + "\n")
 		}
-		w.WriteString(fmt.Sprintf(":%s:%s\n", k, v))
 	}
-	w.WriteString(":END:\n")
+	w.WriteString(w.indent + ":END:\n")
 }
 
 func (w *OrgWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
-	w.WriteString(fmt.Sprintf("[fn:%s]", f.Name))
+	w.WriteString(fmt.Sprintf("[fn:%s]", w.footnoteName(f.Name, nil)))
 	content := w.WriteNodesAsString(f.Children...)
 	if content != "" && !unicode.IsSpace(rune(content[0])) {
 		w.WriteString(" ")
@@ -159,13 +260,88 @@ func (w *OrgWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
 }
 
 func (w *OrgWriter) WriteParagraph(p Paragraph) {
-	content := w.WriteNodesAsString(p.Children...)
+	if w.FillColumn > 0 {
+		w.WriteString(w.fillParagraph(p.Children) + "\n")
+		return
+	}
+	children := append([]Node{}, p.Children...)
+	for i := 1; w.CollapseLineBreaks && i < len(children); i++ {
+		if _, isBreak := children[i].(ExplicitLineBreak); isBreak {
+			if t, ok := children[i-1].(Text); ok {
+				children[i-1] = Text{strings.TrimRightFunc(t.Content, unicode.IsSpace), t.IsRaw}
+			}
+		}
+	}
+	content := w.WriteNodesAsString(children...)
 	if len(content) > 0 && content[0] != '\n' {
 		w.WriteString(w.indent)
 	}
+	if n := len(children); n != 0 {
+		if _, isBreak := children[n-1].(ExplicitLineBreak); isBreak {
+			content = strings.TrimRightFunc(content, unicode.IsSpace) // drop the newline (& indent) of a break at the end of the paragraph
+		}
+	}
 	w.WriteString(content + "\n")
 }
 
+// fillParagraph returns the paragraph content wrapped to FillColumn. Text is split into words at whitespace (including line breaks),
+// all other nodes are written as part of the surrounding word. Lines are never broken before words that would start
+// another element (e.g. a list bullet) and always after explicit line breaks (unless CollapseLineBreaks is set).
+func (w *OrgWriter) fillParagraph(nodes []Node) string {
+	words, word := []string{}, ""
+	flush := func() {
+		if word != "" {
+			words, word = append(words, word), ""
+		}
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case Text:
+			if strings.TrimLeftFunc(n.Content, unicode.IsSpace) != n.Content {
+				flush()
+			}
+			for i, field := range strings.Fields(n.Content) {
+				if i != 0 {
+					flush()
+				}
+				word += field
+			}
+			if strings.TrimRightFunc(n.Content, unicode.IsSpace) != n.Content {
+				flush()
+			}
+		case LineBreak:
+			flush()
+		case ExplicitLineBreak:
+			if w.CollapseLineBreaks {
+				flush()
+				continue
+			}
+			word += `\\`
+			flush()
+			words = append(words, "\n")
+		default:
+			word += w.WriteNodesAsString(n)
+		}
+	}
+	flush()
+	lines, line, width := []string{}, "", w.FillColumn-utf8.RuneCountInString(w.indent)
+	for _, word := range words {
+		if word == "\n" {
+			lines, line = append(lines, line), ""
+		} else if line == "" {
+			line = word
+		} else if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width && tokenize(word+" x").kind == "text" {
+			lines, line = append(lines, line), word
+		} else {
+			line += " " + word
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return w.indent + strings.Join(lines, "\n"+w.indent)
+}
+
 func (w *OrgWriter) WriteExample(e Example) {
 	for _, n := range e.Children {
 		w.WriteString(w.indent + ":")
@@ -177,7 +353,11 @@ func (w *OrgWriter) WriteExample(e Example) {
 }
 
 func (w *OrgWriter) WriteKeyword(k Keyword) {
-	w.WriteString(w.indent + "#+" + k.Key + ":")
+	key := k.Key
+	if rawKey, ok := w.keywordCases[k.Key]; ok && w.PreserveKeywordCase {
+		key = rawKey
+	}
+	w.WriteString(w.indent + "#+" + key + ":")
 	if k.Value != "" {
 		w.WriteString(" " + k.Value)
 	}
@@ -195,8 +375,13 @@ func (w *OrgWriter) WriteNodeWithMeta(n NodeWithMeta) {
 		w.WriteString("\n")
 	}
 	for _, attributes := range n.Meta.HTMLAttributes {
-		w.WriteString("#+ATTR_HTML: ")
-		w.WriteString(strings.Join(attributes, " ") + "\n")
+		parts := []string{}
+		for _, part := range attributes {
+			if part != "" {
+				parts = append(parts, part) // boolean attributes (e.g. :hidden) have no value
+			}
+		}
+		w.WriteString("#+ATTR_HTML: " + strings.Join(parts, " ") + "\n")
 	}
 	WriteNodes(w, n.Node)
 }
@@ -207,42 +392,101 @@ func (w *OrgWriter) WriteNodeWithName(n NodeWithName) {
 }
 
 func (w *OrgWriter) WriteComment(c Comment) {
-	w.WriteString(w.indent + "# " + c.Content + "\n")
+	if c.Content == "" {
+		w.WriteString(w.indent + "#\n")
+	} else {
+		w.WriteString(w.indent + "# " + c.Content + "\n")
+	}
+}
+
+func (w *OrgWriter) WriteList(l List) {
+	if w.ListBullet == "" && !w.RenumberOrderedLists {
+		WriteNodes(w, l.Items...)
+		return
+	}
+	number := 1
+	for _, item := range l.Items {
+		switch item := item.(type) {
+		case ListItem:
+			item.Bullet, number = w.listBullet(l.Kind, item.Bullet, item.Value, number)
+			WriteNodes(w, item)
+		case DescriptiveListItem:
+			item.Bullet, number = w.listBullet(l.Kind, item.Bullet, "", number)
+			WriteNodes(w, item)
+		default:
+			WriteNodes(w, item)
+		}
+	}
 }
 
-func (w *OrgWriter) WriteList(l List) { WriteNodes(w, l.Items...) }
+// listBullet returns the bullet of a list item (see ListBullet & RenumberOrderedLists) and the number of the next item.
+func (w *OrgWriter) listBullet(kind, bullet, value string, number int) (string, int) {
+	if kind == "ordered" {
+		if !w.RenumberOrderedLists {
+			return bullet, number
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			number = n
+		}
+		delimiter := bullet[len(bullet)-1:]
+		if c := bullet[0]; (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && number <= 26 {
+			base := byte('a')
+			if c <= 'Z' {
+				base = 'A'
+			}
+			return string(rune(base+byte(number-1))) + delimiter, number + 1
+		}
+		return strconv.Itoa(number) + delimiter, number + 1
+	}
+	if w.ListBullet != "" && (w.ListBullet != "*" || w.indent != "") {
+		return w.ListBullet, number
+	}
+	return bullet, number
+}
 
 func (w *OrgWriter) WriteListItem(li ListItem) {
+	spacing := w.listItemSpacing(li.Spacing)
 	originalBuilder, originalIndent := w.Builder, w.indent
-	w.Builder, w.indent = strings.Builder{}, w.indent+strings.Repeat(" ", len(li.Bullet)+1)
+	w.Builder, w.indent = strings.Builder{}, w.indent+strings.Repeat(" ", len(li.Bullet)+len(spacing))
 	WriteNodes(w, li.Children...)
 	content := strings.TrimPrefix(w.String(), w.indent)
 	w.Builder, w.indent = originalBuilder, originalIndent
 	w.WriteString(w.indent + li.Bullet)
 	if li.Value != "" {
-		w.WriteString(fmt.Sprintf(" [@%s]", li.Value))
+		w.WriteString(fmt.Sprintf("%s[@%s]", spacing, li.Value))
+		spacing = " "
 	}
 	if li.Status != "" {
-		w.WriteString(fmt.Sprintf(" [%s]", li.Status))
+		w.WriteString(fmt.Sprintf("%s[%s]", spacing, li.Status))
+		spacing = " "
 	}
 	if len(content) > 0 && content[0] == '\n' {
 		w.WriteString(content)
 	} else {
-		w.WriteString(" " + content)
+		w.WriteString(spacing + content)
+	}
+}
+
+// listItemSpacing returns the whitespace written between the bullet and the content of a list item.
+func (w *OrgWriter) listItemSpacing(spacing int) string {
+	if spacing < 1 || w.NormalizeListItemSpacing {
+		spacing = 1
 	}
+	return strings.Repeat(" ", spacing)
 }
 
 func (w *OrgWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
-	indent := w.indent + strings.Repeat(" ", len(di.Bullet)+1)
+	spacing := w.listItemSpacing(di.Spacing)
+	indent := w.indent + strings.Repeat(" ", len(di.Bullet)+len(spacing))
 	w.WriteString(w.indent + di.Bullet)
 	if di.Status != "" {
-		w.WriteString(fmt.Sprintf(" [%s]", di.Status))
-		indent = indent + strings.Repeat(" ", len(di.Status)+3)
+		w.WriteString(fmt.Sprintf("%s[%s]", spacing, di.Status))
+		indent, spacing = indent+strings.Repeat(" ", len(di.Status)+3), " "
 	}
 	if len(di.Term) != 0 {
 		term := w.WriteNodesAsString(di.Term...)
-		w.WriteString(" " + term + " ::")
-		indent = indent + strings.Repeat(" ", len(term)+4)
+		w.WriteString(spacing + term + " ::")
+		indent, spacing = indent+strings.Repeat(" ", len(term)+4), " "
 	}
 	originalBuilder, originalIndent := w.Builder, w.indent
 	w.Builder, w.indent = strings.Builder{}, indent
@@ -252,41 +496,54 @@ func (w *OrgWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
 	if len(details) > 0 && details[0] == '\n' {
 		w.WriteString(details)
 	} else {
-		w.WriteString(" " + details)
+		w.WriteString(spacing + details)
 	}
 }
 
+// WriteTable writes t aligned like org-table-align: cells are padded to the width of the widest (rendered) cell of their column
+// and separators are regenerated to match.
 func (w *OrgWriter) WriteTable(t Table) {
-	for _, row := range t.Rows {
+	rows, widths := make([][]string, len(t.Rows)), make([]int, len(t.ColumnInfos))
+	for i, row := range t.Rows {
+		for j, column := range row.Columns {
+			content := w.WriteNodesAsString(column.Children...)
+			if w.TableTabWidth > 0 {
+				content = expandTabs(content, w.TableTabWidth)
+			}
+			if content == "" {
+				content = " "
+			}
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(content); n > widths[j] {
+				widths[j] = n
+			}
+			rows[i] = append(rows[i], content)
+		}
+	}
+	for i, row := range t.Rows {
 		w.WriteString(w.indent)
 		if len(row.Columns) == 0 {
 			w.WriteString(`|`)
-			for i := 0; i < len(t.ColumnInfos); i++ {
-				w.WriteString(strings.Repeat("-", t.ColumnInfos[i].Len+2))
-				if i < len(t.ColumnInfos)-1 {
+			for j, width := range widths {
+				w.WriteString(strings.Repeat("-", width+2))
+				if j < len(widths)-1 {
 					w.WriteString("+")
 				}
 			}
 			w.WriteString(`|`)
-
 		} else {
 			w.WriteString(`|`)
-			for _, column := range row.Columns {
+			for j, column := range row.Columns {
 				w.WriteString(` `)
-				content := w.WriteNodesAsString(column.Children...)
-				if content == "" {
-					content = " "
-				}
-				n := column.Len - utf8.RuneCountInString(content)
-				if n < 0 {
-					n = 0
-				}
-				if column.Align == "center" {
+				content, n := rows[i][j], widths[j]-utf8.RuneCountInString(rows[i][j])
+				if column.ColumnInfo != nil && column.Align == "center" {
 					if n%2 != 0 {
 						w.WriteString(" ")
 					}
 					w.WriteString(strings.Repeat(" ", n/2) + content + strings.Repeat(" ", n/2))
-				} else if column.Align == "right" {
+				} else if column.ColumnInfo != nil && column.Align == "right" {
 					w.WriteString(strings.Repeat(" ", n) + content)
 				} else {
 					w.WriteString(content + strings.Repeat(" ", n))
@@ -298,17 +555,36 @@ func (w *OrgWriter) WriteTable(t Table) {
 	}
 }
 
+// expandTabs replaces the tabs in s with spaces up to the next multiple of width.
+func expandTabs(s string, width int) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	out, n := strings.Builder{}, 0
+	for _, r := range s {
+		if r == '\t' {
+			out.WriteString(strings.Repeat(" ", width-n%width))
+			n += width - n%width
+		} else {
+			out.WriteRune(r)
+			n++
+		}
+	}
+	return out.String()
+}
+
 func (w *OrgWriter) WriteHorizontalRule(hr HorizontalRule) {
-	w.WriteString(w.indent + "-----\n")
+	dashes := hr.Dashes
+	if dashes < 5 {
+		dashes = 5
+	}
+	w.WriteString(w.indent + strings.Repeat("-", dashes) + "\n")
 }
 
 func (w *OrgWriter) WriteText(t Text) { w.WriteString(t.Content) }
 
 func (w *OrgWriter) WriteEmphasis(e Emphasis) {
-	borders, ok := emphasisOrgBorders[e.Kind]
-	if !ok {
-		panic(fmt.Sprintf("bad emphasis %#v", e))
-	}
+	borders := orgEmphasisBorders(e.Kind)
 	w.WriteString(borders[0])
 	WriteNodes(w, e.Content...)
 	w.WriteString(borders[1])
@@ -329,24 +605,33 @@ func (w *OrgWriter) WriteLineBreak(l LineBreak) {
 }
 
 func (w *OrgWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
-	w.WriteString(`\\` + "\n" + w.indent)
+	if !w.CollapseLineBreaks {
+		w.WriteString(`\\`)
+	}
+	w.WriteString("\n" + w.indent)
 }
 
-func (w *OrgWriter) WriteTimestamp(t Timestamp) {
-	w.WriteString("<")
-	if t.IsDate {
-		w.WriteString(t.Time.Format(datestampFormat))
-	} else {
-		w.WriteString(t.Time.Format(timestampFormat))
-	}
-	if t.Interval != "" {
-		w.WriteString(" " + t.Interval)
+func (w *OrgWriter) WriteRadioTarget(t RadioTarget) {
+	w.WriteString("<<<" + t.Name + ">>>")
+}
+
+func (w *OrgWriter) WriteEntity(e Entity) {
+	w.WriteString(`\` + e.Name)
+	if e.Braces {
+		w.WriteString("{}")
 	}
-	w.WriteString(">")
+}
+
+func (w *OrgWriter) WriteTarget(t Target) {
+	w.WriteString("<<" + t.Name + ">>")
+}
+
+func (w *OrgWriter) WriteTimestamp(t Timestamp) {
+	w.WriteString(t.orgString())
 }
 
 func (w *OrgWriter) WriteFootnoteLink(l FootnoteLink) {
-	w.WriteString("[fn:" + l.Name)
+	w.WriteString("[fn:" + w.footnoteName(l.Name, l.Definition))
 	if l.Definition != nil {
 		w.WriteString(":")
 		WriteNodes(w, l.Definition.Children[0].(Paragraph).Children...)
@@ -365,5 +650,13 @@ func (w *OrgWriter) WriteRegularLink(l RegularLink) {
 }
 
 func (w *OrgWriter) WriteMacro(m Macro) {
-	w.WriteString(fmt.Sprintf("{{{%s(%s)}}}", m.Name, strings.Join(m.Parameters, ",")))
+	if m.Parameters == nil {
+		w.WriteString(fmt.Sprintf("{{{%s}}}", m.Name))
+		return
+	}
+	parameters := make([]string, len(m.Parameters))
+	for i, p := range m.Parameters {
+		parameters[i] = strings.Replace(p, ",", `\,`, -1)
+	}
+	w.WriteString(fmt.Sprintf("{{{%s(%s)}}}", m.Name, strings.Join(parameters, ",")))
 }
@@ -87,3 +87,250 @@ func diff(actual, expected string) string {
 	text, _ := difflib.GetUnifiedDiffString(diff)
 	return text
 }
+
+func TestWriteNodesAsString(t *testing.T) {
+	item := List{Kind: "unordered", Items: []Node{ListItem{Bullet: "-", Children: []Node{Paragraph{Children: []Node{Text{Content: "item"}}}}}}}
+	writers := map[string]func() Writer{
+		"org":        func() Writer { return NewOrgWriter() },
+		"html":       func() Writer { return NewHTMLWriter() },
+		"markdown":   func() Writer { return NewMarkdownWriter() },
+		"plain text": func() Writer { return NewPlainTextWriter() },
+	}
+	for name, newWriter := range writers {
+		w := newWriter()
+		w.(interface{ WriteString(string) (int, error) }).WriteString("parent\n")
+		if out := w.WriteNodesAsString(item); !strings.Contains(out, "item") || strings.Contains(out, "parent") {
+			t.Errorf("%s: expected output of nodes only, got %q", name, out)
+		}
+		if out := w.String(); out != "parent\n" {
+			t.Errorf("%s: expected output of writer to be unchanged, got %q", name, out)
+		}
+	}
+
+	w := NewOrgWriter()
+	w.NodeRenderHook = func(Writer, Node) (string, bool) { panic("hook") }
+	w.WriteString("parent\n")
+	func() {
+		defer func() { recover() }()
+		w.WriteNodesAsString(Paragraph{Children: []Node{Text{Content: "text"}, Mention{"x"}}})
+	}()
+	if out := w.String(); out != "parent\n" {
+		t.Errorf("expected output of writer to be restored after panic, got %q", out)
+	}
+}
+
+func TestDrawerRoundTrip(t *testing.T) {
+	input := "* Headline\n:PROPERTIES:\n:ID:       abc\n:CUSTOM_ID: custom\n:EMPTY:\n:END:\n" +
+		":LOGBOOK:\nCLOCK: [2024-01-01 Mon 10:00]--[2024-01-01 Mon 11:30] =>  1:30\n- Note taken on [2024-01-01 Mon 10:00]\n:END:\ntext\n"
+	if actual := String(New().Silent().Parse(strings.NewReader(input), "./").Nodes); actual != input {
+		t.Errorf("expected drawers to round trip\n%s", diff(actual, input))
+	}
+	unaligned := "* Headline\n:PROPERTIES:\n:ID: abc\n:CUSTOM_ID:\tcustom\n:END:\n"
+	d := New().Silent().Parse(strings.NewReader(unaligned), "./")
+	if actual := String(d.Nodes); actual != unaligned {
+		t.Errorf("expected property whitespace to round trip\n%s", diff(actual, unaligned))
+	}
+	w := NewOrgWriter()
+	w.AlignProperties = true
+	expected := "* Headline\n:PROPERTIES:\n:ID:       abc\n:CUSTOM_ID: custom\n:END:\n"
+	if actual, err := d.Write(w); err != nil || actual != expected {
+		t.Errorf("expected aligned properties: %v\n%s", err, diff(actual, expected))
+	}
+}
+
+func TestBlockCommaEscaping(t *testing.T) {
+	for _, name := range []string{"SRC text", "EXAMPLE", "EXPORT html"} {
+		input := fmt.Sprintf("#+BEGIN_%s\n,* a\n  ,#+END_SRC\n,,* b\n,,#+c\na ,* d\n#+END_%s\n", name, strings.Fields(name)[0])
+		d := New().Silent().Parse(strings.NewReader(input), "./")
+		if actual := String(d.Nodes); actual != input {
+			t.Errorf("%s: round trip failed\n%s", name, diff(actual, input))
+		}
+		b, ok := d.Nodes[0].(Block)
+		if !ok {
+			t.Fatalf("%s: expected Block, got %#v", name, d.Nodes[0])
+		}
+		expected := "* a\n  #+END_SRC\n,* b\n,#+c\na ,* d\n"
+		if actual := String(b.Children); actual != expected {
+			t.Errorf("%s: unexpected content\n%s", name, diff(actual, expected))
+		}
+	}
+}
+
+func TestRawBlockContainingOtherBlocks(t *testing.T) {
+	for outer, inner := range map[string]string{"SRC": "EXAMPLE", "EXAMPLE": "SRC", "EXPORT": "QUOTE"} {
+		content := fmt.Sprintf("#+BEGIN_%s go\nfoo\n#+END_%s\n#+end_%s\n", inner, inner, strings.ToLower(inner))
+		input := fmt.Sprintf("#+BEGIN_%s org\n%s#+end_%s\ntail\n", outer, content, strings.ToLower(outer))
+		d := New().Silent().Parse(strings.NewReader(input), "./")
+		if len(d.Nodes) != 2 {
+			t.Fatalf("%s: expected block and paragraph, got %#v", outer, d.Nodes)
+		}
+		b, ok := d.Nodes[0].(Block)
+		if !ok || b.Name != outer {
+			t.Fatalf("%s: expected Block, got %#v", outer, d.Nodes[0])
+		}
+		if actual := String(b.Children); actual != content {
+			t.Errorf("%s: unexpected content\n%s", outer, diff(actual, content))
+		}
+		if roundTrip := New().Silent().Parse(strings.NewReader(String(d.Nodes)), "./"); String(roundTrip.Nodes) != String(d.Nodes) {
+			t.Errorf("%s: round trip failed\n%s", outer, diff(String(roundTrip.Nodes), String(d.Nodes)))
+		}
+	}
+}
+
+func TestListItemSpacing(t *testing.T) {
+	input := "-   aligned item\n    continued\n    -  nested item\n       continued\n-   [X] done\n1.  ordered\n    #+BEGIN_SRC sh\n    echo a\n    #+END_SRC\n-   term :: details\n"
+	if actual := String(New().Silent().Parse(strings.NewReader(input), "./").Nodes); actual != input {
+		t.Errorf("list item spacing did not round trip\n%s", diff(actual, input))
+	}
+	normalized := "- aligned item\n  continued\n  - nested item\n    continued\n- [X] done\n1. ordered\n   #+BEGIN_SRC sh\n   echo a\n   #+END_SRC\n- term :: details\n"
+	w := NewOrgWriter()
+	w.NormalizeListItemSpacing = true
+	if actual, err := New().Silent().Parse(strings.NewReader(input), "./").Write(w); err != nil || actual != normalized {
+		t.Errorf("expected normalized list item spacing (%v)\n%s", err, diff(actual, normalized))
+	}
+}
+
+func TestNumberFootnotes(t *testing.T) {
+	input := "b[fn:second] a[fn:first] anonymous[fn::inline] named[fn:named:inline] again[fn:first] missing[fn:missing]\n\n" +
+		"[fn:first] first\n\n[fn:second] second\n\n[fn:unused] unused\n"
+	// the inline definition of named comes first in definition order, anonymous footnotes follow in order of reference
+	expected := "b[fn:3] a[fn:2] anonymous[fn:4:inline] named[fn:1:inline] again[fn:2] missing[fn:5]\n\n" +
+		"[fn:2] first\n\n[fn:3] second\n\n[fn:6] unused\n"
+	actual, err := RenderOrg(input, WithNumberFootnotes(), WithSilent())
+	if err != nil || actual != expected {
+		t.Errorf("expected numbered footnotes (%v)\n%s", err, diff(actual, expected))
+	}
+	if actual, _ := RenderOrg(input, WithSilent()); actual != input {
+		t.Errorf("expected footnote names to be kept by default\n%s", diff(actual, input))
+	}
+}
+
+func TestHorizontalRuleDashes(t *testing.T) {
+	input := "-----\n\n----------\n\n- item\n  -------\n"
+	if actual := String(New().Silent().Parse(strings.NewReader(input), "./").Nodes); actual != input {
+		t.Errorf("horizontal rules did not round trip\n%s", diff(actual, input))
+	}
+}
+
+func TestTableAlignment(t *testing.T) {
+	input := "| name | n |\n|-+-|\n| äöü | 1 |\n| x | 100 |\n|  | 2 |\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./")
+	table := d.Nodes[0].(Table)
+	table.Rows[3].Columns[0].Children = []Node{Text{"a longer cell", false}}
+	expected := "| name          |   n |\n|---------------+-----|\n| äöü           |   1 |\n| a longer cell | 100 |\n|               |   2 |\n"
+	if actual := String([]Node{table}); actual != expected {
+		t.Errorf("expected table to be aligned\n%s", diff(actual, expected))
+	}
+}
+
+func TestTableTabWidth(t *testing.T) {
+	input := "| a\tb | c |\n| abcdef | d |\n"
+	for width, expected := range map[int]string{
+		8: "| a       b | c |\n| abcdef    | d |\n",
+		4: "| a   b  | c |\n| abcdef | d |\n",
+	} {
+		w := NewOrgWriter()
+		w.TableTabWidth = width
+		actual, err := New().Silent().Parse(strings.NewReader(input), "./").Write(w)
+		if err != nil || actual != expected {
+			t.Errorf("tab width %d: expected table to be aligned (%v)\n%s", width, err, diff(actual, expected))
+		}
+	}
+}
+
+func TestCommentsAndKeywords(t *testing.T) {
+	input := "# #+TODO: A | B\n#+TODO: X | Y\n#not-a-keyword\n#\n* X a\n"
+	d := New().Silent().Parse(strings.NewReader(input), "./comments.org")
+	if actual := String(d.Nodes); actual != input {
+		t.Errorf("expected comments to round-trip:\n%s", diff(actual, input))
+	}
+	kinds := []string{}
+	for _, n := range d.Nodes {
+		kinds = append(kinds, fmt.Sprintf("%T", n))
+	}
+	if actual, expected := strings.Join(kinds, " "), "org.Comment org.Keyword org.Paragraph org.Comment org.Headline"; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	if todo := d.Get("TODO"); todo != "X | Y" {
+		t.Errorf("expected commented out #+TODO: to be ignored, got %q", todo)
+	}
+}
+
+func TestListBullets(t *testing.T) {
+	input := "+ a\n  * b\n    10. c\n        d\n    11. e\n+ f :: g\n\n3) x\n7) [@5] y\n8) z\n\ntext\n\nb. p\nc. q\n"
+	w := NewOrgWriter()
+	w.ListBullet, w.RenumberOrderedLists = "*", true
+	actual, err := New().Silent().Parse(strings.NewReader(input), "./bullets.org").Write(w)
+	expected := "+ a\n  * b\n    1. c\n       d\n    2. e\n+ f :: g\n\n1) x\n5) [@5] y\n6) z\n\ntext\n\na. p\nb. q\n"
+	if err != nil || actual != expected {
+		t.Errorf("%v\n%s", err, diff(actual, expected))
+	}
+	w = NewOrgWriter()
+	w.ListBullet = "-"
+	actual, _ = New().Silent().Parse(strings.NewReader(input), "./bullets.org").Write(w)
+	if expected := "- a\n  - b\n    10. c\n        d\n    11. e\n- f :: g\n\n3) x\n7) [@5] y\n8) z\n\ntext\n\nb. p\nc. q\n"; actual != expected {
+		t.Errorf("expected only unordered bullets to change:\n%s", diff(actual, expected))
+	}
+}
+
+func TestFillColumn(t *testing.T) {
+	tests := map[string]string{
+		"some plain text with a [[https://example.com/a/very/long/path/that/does/not/fit][long link]] and *bold text spans* that stay whole.\n": "" +
+			"some plain text with a\n[[https://example.com/a/very/long/path/that/does/not/fit][long link]]\nand *bold text spans* that stay whole.\n",
+		"short\nlines are\njoined into one\n":                                       "short lines are joined into one\n",
+		"a forced\\\\\nbreak and ~inline code with spaces~ plus =verbatim words=\n": "a forced\\\\\nbreak and ~inline code with spaces~ plus\n=verbatim words=\n",
+		"never start a line with a bullet like this one - or 1. which is a list\n":  "never start a line with a bullet like\nthis one - or 1. which is a list\n",
+		"- an item with text that gets wrapped at the fill column too\n":            "- an item with text that gets wrapped at\n  the fill column too\n",
+	}
+	for input, expected := range tests {
+		w := NewOrgWriter()
+		w.FillColumn = 40
+		actual, err := New().Silent().Parse(strings.NewReader(input), "./fill.org").Write(w)
+		if err != nil || actual != expected {
+			t.Errorf("%q: %v\n%s", input, err, diff(actual, expected))
+		}
+		if reparsed := New().Silent().Parse(strings.NewReader(actual), "./fill.org"); len(reparsed.Nodes) != 1 {
+			t.Errorf("%q: expected a single node after filling, got %#v", input, reparsed.Nodes)
+		}
+	}
+}
+
+func TestExplicitLineBreaks(t *testing.T) {
+	tests := map[string][]string{
+		"foo \\\\\n\nbar\n":             {"foo \\\\\n\nbar\n", "foo\n\nbar\n"},
+		"- a \\\\\n  b \\\\\n\n- c\n":   {"- a \\\\\n  b \\\\\n\n- c\n", "- a\n  b\n\n- c\n"},
+		"a \\\\  \nb\n":                 {"a \\\\\nb\n", "a\nb\n"},
+		"\\\\ at the start of a line\n": {"\\\\ at the start of a line\n", "\\\\ at the start of a line\n"},
+	}
+	for input, expected := range tests {
+		for i, collapse := range []bool{false, true} {
+			w := NewOrgWriter()
+			w.CollapseLineBreaks = collapse
+			if actual, err := New().Silent().Parse(strings.NewReader(input), "./breaks.org").Write(w); err != nil || actual != expected[i] {
+				t.Errorf("%q (collapse: %v): %v\n%s", input, collapse, err, diff(actual, expected[i]))
+			}
+		}
+	}
+	testHTMLWriterContains(t, map[string]string{"foo \\\\\n\nbar": "<p>foo <br>\n</p>"}, func(w *HTMLWriter) {})
+}
+
+func TestKeywordCase(t *testing.T) {
+	input := "#+title: Title\n#+My_Keyword: a: b\n#+attr_html: :class a:b\n#+caption: c\n[[./x.png]]\n"
+	for preserve, expected := range map[bool]string{
+		false: "#+TITLE: Title\n#+MY_KEYWORD: a: b\n#+CAPTION: c\n#+ATTR_HTML: :class a:b\n[[./x.png]]\n",
+		true:  "#+TITLE: Title\n#+My_Keyword: a: b\n#+CAPTION: c\n#+ATTR_HTML: :class a:b\n[[./x.png]]\n",
+	} {
+		w := NewOrgWriter()
+		w.PreserveKeywordCase = preserve
+		d := New().Silent().Parse(strings.NewReader(input), "./keywords.org")
+		if actual, err := d.Write(w); err != nil || actual != expected {
+			t.Errorf("preserve: %v: %v\n%s", preserve, err, diff(actual, expected))
+		}
+		if d.Get("title") != "Title" || d.Get("my_keyword") != "a: b" {
+			t.Errorf("expected case-insensitive keyword lookup: %q %q", d.Get("title"), d.Get("my_keyword"))
+		}
+	}
+	if k := (Keyword{"MY_KEYWORD", "a: b"}); k != New().Silent().Parse(strings.NewReader(input), "./keywords.org").Nodes[1] {
+		t.Errorf("expected the case of keywords to not be part of the node: %#v", k)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"math"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 type Paragraph struct{ Children []Node }
-type HorizontalRule struct{}
+type HorizontalRule struct {
+	Dashes int // Dashes is the number of dashes of the rule, e.g. 5 for -----.
+}
 
 var horizontalRuleRegexp = regexp.MustCompile(`^(\s*)-{5,}\s*$`)
 var plainTextRegexp = regexp.MustCompile(`^(\s*)(.*)`)
@@ -21,7 +24,7 @@ func lexText(line string) (token, bool) {
 
 func lexHorizontalRule(line string) (token, bool) {
 	if m := horizontalRuleRegexp.FindStringSubmatch(line); m != nil {
-		return token{"horizontalRule", len(m[1]), "", m}, true
+		return token{"horizontalRule", len(m[1]), strings.TrimSpace(m[0]), m}, true
 	}
 	return nilToken, false
 }
@@ -35,12 +38,20 @@ func (d *Document) parseParagraph(i int, parentStop stopFn) (int, Node) {
 		lvl := math.Max(float64(d.tokens[i].lvl-d.baseLvl), 0)
 		lines = append(lines, strings.Repeat(" ", int(lvl))+d.tokens[i].content)
 	}
-	consumed := i - start
-	return consumed, Paragraph{d.parseInline(strings.Join(lines, "\n"))}
+	consumed, content := i-start, strings.Join(lines, "\n")
+	if !strings.HasSuffix(strings.TrimRightFunc(content, unicode.IsSpace), `\\`) {
+		return consumed, Paragraph{d.parseInline(content)}
+	}
+	// explicit line breaks (\\) are terminated by a newline - a trailing one at the end of the paragraph as well
+	nodes := d.parseInline(content + "\n")
+	if _, ok := nodes[len(nodes)-1].(LineBreak); ok {
+		nodes = nodes[:len(nodes)-1]
+	}
+	return consumed, Paragraph{nodes}
 }
 
 func (d *Document) parseHorizontalRule(i int, parentStop stopFn) (int, Node) {
-	return 1, HorizontalRule{}
+	return 1, HorizontalRule{len(d.tokens[i].content)}
 }
 
 func (n Paragraph) String() string      { return orgWriter.WriteNodesAsString(n) }
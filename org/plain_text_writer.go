@@ -0,0 +1,348 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PlainTextWriter exports an org document as plain text without any markup - e.g. for search indexes and previews.
+// Org features without text content (e.g. drawers, keywords and export blocks) are dropped.
+type PlainTextWriter struct {
+	ExtendingWriter Writer
+	HeadlinePrefix  bool              // Prefix headlines with their level as # (e.g. ## for level 2 headlines).
+	LineWidth       int               // Soft wrap paragraphs at spaces so that lines are at most this many characters wide (including the indent). Disabled if 0.
+	CheckboxSymbols map[string]string // Write the checkboxes of list items as these symbols by status (X, space & -), e.g. UnicodeCheckboxSymbols. Defaults to DefaultCheckboxSymbols.
+
+	lineWriter
+	document  *Document
+	footnotes *footnotes
+}
+
+// DefaultCheckboxSymbols are the CheckboxSymbols of NewPlainTextWriter: checkboxes are written as is.
+var DefaultCheckboxSymbols = map[string]string{"X": "[X]", " ": "[ ]", "-": "[-]"}
+
+// UnicodeCheckboxSymbols are CheckboxSymbols that are more readable in terminals.
+var UnicodeCheckboxSymbols = map[string]string{"X": "✓", " ": "☐", "-": "◐"}
+
+func NewPlainTextWriter() *PlainTextWriter {
+	defaultConfig := New()
+	return &PlainTextWriter{
+		CheckboxSymbols: DefaultCheckboxSymbols,
+		document:        &Document{Configuration: defaultConfig},
+		footnotes:       &footnotes{mapping: map[string]int{}},
+	}
+}
+
+func (w *PlainTextWriter) WriterWithExtensions() Writer {
+	if w.ExtendingWriter != nil {
+		return w.ExtendingWriter
+	}
+	return w
+}
+
+func (w *PlainTextWriter) Before(d *Document) {
+	w.document = d
+}
+
+func (w *PlainTextWriter) After(d *Document) {
+	w.WriteFootnotes(d)
+}
+
+func (w *PlainTextWriter) WriteNodesAsString(nodes ...Node) string {
+	builder := w.Builder
+	w.Builder = strings.Builder{}
+	defer func() { w.Builder = builder }()
+	WriteNodes(w, nodes...)
+	return w.String()
+}
+
+func (w *PlainTextWriter) WriteHeadline(h Headline) {
+	if h.IsExcluded(w.document) {
+		return
+	}
+	w.startBlock()
+	if w.HeadlinePrefix {
+		w.WriteString(strings.Repeat("#", h.Lvl) + " ")
+	}
+	w.WriteString(strings.TrimSpace(w.WriteNodesAsString(h.Title...)) + "\n")
+	WriteNodes(w, h.Children...)
+}
+
+func (w *PlainTextWriter) WriteBlock(b Block) {
+	content, params := rawContent(b.Children), b.ParameterMap()
+	switch b.Name {
+	case "SRC":
+		if params[":exports"] != "results" && params[":exports"] != "none" {
+			w.writeRawBlock(content)
+		}
+	case "EXAMPLE":
+		w.writeRawBlock(content)
+	case "EXPORT", "COMMENT":
+	default:
+		WriteNodes(w, b.Children...)
+	}
+
+	if b.Result != nil && params[":exports"] != "code" && params[":exports"] != "none" && !hasResultsParameter(params, "silent") {
+		if _, ok := resultFile(params); !ok {
+			WriteNodes(w, b.Result)
+		}
+	}
+}
+
+func (w *PlainTextWriter) writeRawBlock(content string) {
+	if content = strings.TrimRight(content, "\n"); content != "" {
+		w.startBlock()
+		w.writeLines(content)
+	}
+}
+
+func (w *PlainTextWriter) WriteResult(r Result) { WriteNodes(w, r.Node) }
+
+func (w *PlainTextWriter) WriteInlineBlock(b InlineBlock) {
+	if b.Name == "src" {
+		w.WriteString(rawContent(b.Children))
+	}
+}
+
+// WriteInlineBabelCall writes the call itself for :exports code & both (see HTMLWriter.WriteInlineBabelCall).
+func (w *PlainTextWriter) WriteInlineBabelCall(c InlineBabelCall) {
+	switch c.ParameterMap()[":exports"] {
+	case "code", "both":
+		w.WriteString(c.String())
+	}
+}
+
+func (w *PlainTextWriter) WriteExample(e Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = rawContent([]Node{n})
+	}
+	w.writeRawBlock(strings.Join(lines, "\n"))
+}
+
+func (w *PlainTextWriter) WriteLatexBlock(b LatexBlock) {
+	w.writeRawBlock(b.Latex())
+}
+
+func (w *PlainTextWriter) WriteDrawer(d Drawer) {
+	WriteNodes(w, d.Children...)
+}
+
+func (w *PlainTextWriter) WriteKeyword(k Keyword) {}
+
+func (w *PlainTextWriter) WriteInclude(i Include) {
+	WriteNodes(w, i.Nodes()...)
+}
+
+func (w *PlainTextWriter) WriteComment(Comment)               {}
+func (w *PlainTextWriter) WritePropertyDrawer(PropertyDrawer) {}
+func (w *PlainTextWriter) WriteHorizontalRule(HorizontalRule) {}
+
+func (w *PlainTextWriter) WriteNodeWithMeta(n NodeWithMeta) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *PlainTextWriter) WriteNodeWithName(n NodeWithName) {
+	WriteNodes(w, n.Node)
+}
+
+func (w *PlainTextWriter) WriteFootnoteDefinition(f FootnoteDefinition) {
+	w.footnotes.updateDefinition(f)
+}
+
+// WriteFootnotes writes the definitions of all referenced footnotes as [n] definition - continuation lines are indented by 4 spaces.
+func (w *PlainTextWriter) WriteFootnotes(d *Document) {
+	if w.document.GetOption("f") == "nil" || len(w.footnotes.list) == 0 {
+		return
+	}
+	for i := 0; i < len(w.footnotes.list); i++ {
+		definition := w.footnotes.list[i]
+		if definition == nil && d.Footnotes != nil {
+			definition = d.Footnotes.Definitions[w.footnotes.names[i]]
+		}
+		if definition == nil {
+			d.Log.Printf("Missing footnote definition for [fn:%s] (#%d)", w.footnotes.names[i], i+1)
+			continue
+		}
+		builder, indent := w.Builder, w.indent
+		w.Builder, w.indent = strings.Builder{}, indent+"    "
+		WriteNodes(w, definition.Children...)
+		content := strings.TrimRight(strings.TrimPrefix(w.String(), w.indent), "\n")
+		w.Builder, w.indent = builder, indent
+		w.startBlock()
+		w.WriteString(fmt.Sprintf("[%d] %s\n", i+1, content))
+	}
+}
+
+func (w *PlainTextWriter) WriteParagraph(p Paragraph) {
+	if p, ok := firstParagraph([]Node{p}); ok {
+		w.startBlock()
+		content := w.WriteNodesAsString(p.Children...)
+		if w.LineWidth > 0 {
+			lines := []string{}
+			for _, line := range strings.Split(content, "\n") {
+				lines = append(lines, wrapLine(line, w.LineWidth-len(w.indent))...)
+			}
+			content = strings.Join(lines, "\n")
+		}
+		w.writeLines(content)
+	}
+}
+
+// wrapLine splits s at spaces into lines of at most width characters. Words longer than width get a line of their own.
+func wrapLine(s string, width int) []string {
+	lines, line := []string{}, ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines, line = append(lines, line), ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+func (w *PlainTextWriter) WriteList(l List) {
+	if w.indent == "" {
+		w.startBlock()
+	}
+	WriteNodes(w, l.Items...)
+}
+
+func (w *PlainTextWriter) WriteListItem(li ListItem) {
+	w.writeListItem(li.Bullet, li.Status, li.Children)
+}
+
+// WriteDescriptiveListItem writes descriptive list items as - term: details.
+func (w *PlainTextWriter) WriteDescriptiveListItem(di DescriptiveListItem) {
+	details := di.Details
+	if len(di.Term) != 0 {
+		term := append(append([]Node{}, di.Term...), Text{":", false})
+		if p, ok := firstParagraph(details); ok {
+			details = append([]Node{Paragraph{append(append(term, Text{" ", false}), p.Children...)}}, details[1:]...)
+		} else {
+			details = append([]Node{Paragraph{term}}, details...)
+		}
+	}
+	w.writeListItem(di.Bullet, di.Status, details)
+}
+
+func (w *PlainTextWriter) writeListItem(bullet, status string, children []Node) {
+	builder, indent := w.Builder, w.indent
+	w.Builder, w.indent = strings.Builder{}, indent+strings.Repeat(" ", len(bullet)+1)
+	WriteNodes(w, children...)
+	content := strings.TrimRight(strings.TrimPrefix(w.String(), w.indent), "\n")
+	w.Builder, w.indent = builder, indent
+	w.WriteString(w.indent + bullet)
+	if symbol, ok := w.CheckboxSymbols[status]; ok && status != "" {
+		w.WriteString(" " + symbol)
+	} else if status != "" {
+		w.WriteString(" [" + status + "]")
+	}
+	if content != "" {
+		w.WriteString(" " + content)
+	}
+	w.WriteString("\n")
+}
+
+// WriteTable writes the rows of t as tab separated columns - separators and special rows are dropped.
+func (w *PlainTextWriter) WriteTable(t Table) {
+	w.startBlock()
+	for _, row := range t.Rows {
+		if row.IsSpecial || len(row.Columns) == 0 {
+			continue
+		}
+		columns := make([]string, len(row.Columns))
+		for i, column := range row.Columns {
+			columns[i] = w.WriteNodesAsString(column.Children...)
+		}
+		w.WriteString(w.indent + strings.Join(columns, "\t") + "\n")
+	}
+}
+
+func (w *PlainTextWriter) WriteText(t Text) {
+	content := t.Content
+	if !t.IsRaw && w.document.GetOption("e") != "nil" {
+		content = specialStringReplacer.Replace(content)
+	}
+	w.WriteString(content)
+}
+
+func (w *PlainTextWriter) WriteEmphasis(e Emphasis) {
+	WriteNodes(w, e.Content...)
+}
+
+// WriteLatexFragment writes the content of \(...\), \[...\], $...$ & $$...$$ fragments - environments are written as is.
+func (w *PlainTextWriter) WriteLatexFragment(l LatexFragment) {
+	if strings.HasPrefix(l.OpeningPair, `\begin`) {
+		w.WriteString(l.OpeningPair + rawContent(l.Content) + l.ClosingPair)
+	} else {
+		w.WriteString(rawContent(l.Content))
+	}
+}
+
+func (w *PlainTextWriter) WriteStatisticToken(s StatisticToken) {
+	w.WriteString("[" + s.Content + "]")
+}
+
+// WriteLineBreak writes a space rather than a newline if paragraphs are soft wrapped (see PlainTextWriter.LineWidth).
+func (w *PlainTextWriter) WriteLineBreak(l LineBreak) {
+	if w.LineWidth > 0 {
+		w.WriteString(" ")
+	} else {
+		w.WriteString(strings.Repeat("\n", l.Count))
+	}
+}
+
+func (w *PlainTextWriter) WriteExplicitLineBreak(l ExplicitLineBreak) {
+	w.WriteString("\n")
+}
+
+func (w *PlainTextWriter) WriteRadioTarget(t RadioTarget) {
+	w.WriteString(t.Name)
+}
+
+func (w *PlainTextWriter) WriteEntity(e Entity) {
+	if w.document.GetOption("e") == "nil" {
+		w.WriteString(e.String())
+	} else {
+		w.WriteString(e.UTF8())
+	}
+}
+
+func (w *PlainTextWriter) WriteTarget(t Target) {}
+
+func (w *PlainTextWriter) WriteTimestamp(t Timestamp) {
+	if w.document.GetOption("<") == "nil" {
+		return
+	}
+	w.WriteString(t.orgString())
+}
+
+func (w *PlainTextWriter) WriteFootnoteLink(l FootnoteLink) {
+	if w.document.GetOption("f") == "nil" {
+		return
+	}
+	w.WriteString(fmt.Sprintf("[%d]", w.footnotes.add(l)+1))
+}
+
+func (w *PlainTextWriter) WriteRegularLink(l RegularLink) {
+	if url, expanded := w.document.ExpandLink(l); l.Description == nil && expanded {
+		w.WriteString(url)
+	} else if l.Description == nil {
+		w.WriteString(strings.TrimPrefix(l.URL, "file:"))
+	} else {
+		WriteNodes(w, l.Description...)
+	}
+}
+
+func (w *PlainTextWriter) WriteMacro(m Macro) {
+	if nodes, ok := w.document.ExpandMacro(m); ok {
+		WriteNodes(w, nodes...)
+	} else {
+		w.WriteString(m.String())
+	}
+}
@@ -0,0 +1,58 @@
+package org
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlainTextWriter(t *testing.T) {
+	testPlainTextWriter(t, map[string]string{
+		"* TODO [#A] Headline :tag:\n** Child\ntext": "Headline\n\nChild\n\ntext\n",
+
+		"/italic/ *bold* +strike+ ~code~ =verbatim= and 2*3_4": "italic bold strike code verbatim and 2*3_4\n",
+		"[[https://example.com][a link]] [[file:foo.png]]":     "a link foo.png\n",
+		"\\alpha and \\(x^2\\) and <<target>>here":             "α and x^2 and here\n",
+
+		"- a\n  - b\n  - [X] c\n- d\n\n  second paragraph\n": "- a\n  - b\n  - [X] c\n- d\n\n  second paragraph\n",
+		"1. one\n2. two\n":    "1. one\n2. two\n",
+		"- term :: details\n": "- term: details\n",
+
+		"| a | b |\n|---+---|\n| 1 | *x* |\n": "a\tb\n1\tx\n",
+
+		"#+BEGIN_SRC go\nfunc main() {}\n#+END_SRC\n":     "func main() {}\n",
+		"#+BEGIN_QUOTE\nquoted\n#+END_QUOTE\n":            "quoted\n",
+		"#+BEGIN_EXPORT html\n<b>raw</b>\n#+END_EXPORT\n": "",
+		"text\n:DRAWER:\ndrawn\n:END:\n# comment\n-----":  "text\n\ndrawn\n",
+
+		"a footnote[fn:1] and an inline one[fn::inline]\n\n[fn:1] the definition\n": "a footnote[1] and an inline one[2]\n\n[1] the definition\n\n[2] inline\n",
+	}, func(*PlainTextWriter) {})
+}
+
+func TestPlainTextWriterOptions(t *testing.T) {
+	testPlainTextWriter(t, map[string]string{
+		"* Headline\n** Child\n":                               "# Headline\n\n## Child\n",
+		"one two three\nfour five six seven\\\\\nhard break\n": "one two\nthree four\nfive six\nseven\nhard break\n",
+		"- a list item with a long text\n":                     "- a list\n  item\n  with a\n  long\n  text\n",
+		"averyveryverylongword short":                          "averyveryverylongword\nshort\n",
+	}, func(w *PlainTextWriter) { w.HeadlinePrefix, w.LineWidth = true, 10 })
+	checkboxes := "- [X] done\n- [ ] open\n- [-] partial\n"
+	testPlainTextWriter(t, map[string]string{checkboxes: checkboxes}, func(*PlainTextWriter) {})
+	testPlainTextWriter(t, map[string]string{
+		checkboxes: "- ✓ done\n- ☐ open\n- ◐ partial\n",
+	}, func(w *PlainTextWriter) { w.CheckboxSymbols = UnicodeCheckboxSymbols })
+}
+
+func testPlainTextWriter(t *testing.T, tests map[string]string, setup func(*PlainTextWriter)) {
+	for org, expected := range tests {
+		t.Run(org, func(t *testing.T) {
+			writer := NewPlainTextWriter()
+			setup(writer)
+			actual, err := New().Silent().Parse(strings.NewReader(org), "./test.org").Write(writer)
+			if err != nil {
+				t.Errorf("%s\n got error: %s", org, err)
+			} else if actual != expected {
+				t.Errorf("%s:\n%s'", org, diff(actual, expected))
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package org
+
+import (
+	"io/fs"
+	"log"
+	"strings"
+)
+
+// Option configures the parsing & writing done by RenderHTML and RenderOrg.
+// Options are the recommended way to configure go-org - the exported fields of
+// Configuration, HTMLWriter and OrgWriter are kept for compatibility.
+type Option func(*renderer)
+
+type renderer struct {
+	configuration    *Configuration
+	htmlWriter       *HTMLWriter
+	orgWriter        *OrgWriter
+	path             string
+	updateStatistics bool
+}
+
+// RenderHTML parses src and exports it as html.
+func RenderHTML(src string, options ...Option) (string, error) {
+	r := newRenderer(options)
+	return r.parse(src).Write(r.htmlWriter)
+}
+
+// RenderOrg parses src and exports it as pretty printed org.
+func RenderOrg(src string, options ...Option) (string, error) {
+	r := newRenderer(options)
+	return r.parse(src).Write(r.orgWriter)
+}
+
+func newRenderer(options []Option) *renderer {
+	r := &renderer{New(), NewHTMLWriter(), NewOrgWriter(), "./", false}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+func (r *renderer) parse(src string) *Document {
+	d := r.configuration.Parse(strings.NewReader(src), r.path)
+	if r.updateStatistics && d.Error == nil {
+		d.UpdateStatistics()
+	}
+	return d
+}
+
+// WithPath sets the path of the rendered document - used to resolve relative paths (e.g. #+INCLUDE).
+func WithPath(path string) Option {
+	return func(r *renderer) { r.path = path }
+}
+
+// WithConfiguration allows modifying the Configuration used for parsing.
+func WithConfiguration(f func(*Configuration)) Option {
+	return func(r *renderer) { f(r.configuration) }
+}
+
+// WithHTMLWriter allows modifying the HTMLWriter used by RenderHTML.
+func WithHTMLWriter(f func(*HTMLWriter)) Option {
+	return func(r *renderer) { f(r.htmlWriter) }
+}
+
+// WithOrgWriter allows modifying the OrgWriter used by RenderOrg.
+func WithOrgWriter(f func(*OrgWriter)) Option {
+	return func(r *renderer) { f(r.orgWriter) }
+}
+
+// WithLog sets the logger used for warnings during parsing & writing.
+func WithLog(l *log.Logger) Option {
+	return func(r *renderer) { r.configuration.Log = l }
+}
+
+// WithSilent disables all logging of warnings.
+func WithSilent() Option {
+	return func(r *renderer) { r.configuration.Silent() }
+}
+
+// WithAutoLink toggles converting text that looks like a url into links.
+func WithAutoLink(enabled bool) Option {
+	return func(r *renderer) { r.configuration.AutoLink = enabled }
+}
+
+// WithMaxEmphasisNewLines sets the maximum number of newlines inside an emphasis.
+func WithMaxEmphasisNewLines(n int) Option {
+	return func(r *renderer) { r.configuration.MaxEmphasisNewLines = n }
+}
+
+// WithEmphasisMarkers sets the characters parsed as emphasis (see Configuration.EmphasisMarkers), e.g. to treat + literally.
+func WithEmphasisMarkers(markers string) Option {
+	return func(r *renderer) { r.configuration.EmphasisMarkers = markers }
+}
+
+// WithDefaultSetting sets the default value of a buffer setting (e.g. OPTIONS or TODO).
+func WithDefaultSetting(key, value string) Option {
+	return func(r *renderer) { r.configuration.DefaultSettings[key] = value }
+}
+
+// WithReadFile sets the function used to read e.g. #+INCLUDE and #+SETUPFILE files.
+func WithReadFile(f func(filename string) ([]byte, error)) Option {
+	return func(r *renderer) { r.configuration.ReadFile = f }
+}
+
+// WithInlineParsers adds parsers for custom inline syntax.
+func WithInlineParsers(parsers ...InlineParser) Option {
+	return func(r *renderer) { r.configuration.InlineParsers = append(r.configuration.InlineParsers, parsers...) }
+}
+
+// WithUnknownKeywords sets the policy for unknown keywords: keep, drop or error.
+func WithUnknownKeywords(policy string) Option {
+	return func(r *renderer) { r.configuration.UnknownKeywords = policy }
+}
+
+// WithStripCharacters removes the given characters (e.g. InvisibleCharacters) from the input before parsing.
+func WithStripCharacters(chars string) Option {
+	return func(r *renderer) { r.configuration.StripCharacters = chars }
+}
+
+// WithNodeRenderHook sets the NodeRenderHook of both the HTMLWriter and the OrgWriter.
+func WithNodeRenderHook(f func(w Writer, n Node) (string, bool)) Option {
+	return func(r *renderer) { r.htmlWriter.NodeRenderHook, r.orgWriter.NodeRenderHook = f, f }
+}
+
+// WithTagsColumn sets the column headline tags are aligned to by RenderOrg.
+func WithTagsColumn(n int) Option {
+	return func(r *renderer) { r.orgWriter.TagsColumn = n }
+}
+
+// WithListBullet sets the bullet of unordered lists written by RenderOrg (see OrgWriter.ListBullet).
+func WithListBullet(bullet string) Option {
+	return func(r *renderer) { r.orgWriter.ListBullet = bullet }
+}
+
+// WithRenumberedOrderedLists numbers the items of ordered lists written by RenderOrg sequentially (see OrgWriter.RenumberOrderedLists).
+func WithRenumberedOrderedLists() Option {
+	return func(r *renderer) { r.orgWriter.RenumberOrderedLists = true }
+}
+
+// WithCollapsedLineBreaks makes RenderOrg write explicit line breaks (\\) as plain newlines (see OrgWriter.CollapseLineBreaks).
+func WithCollapsedLineBreaks() Option {
+	return func(r *renderer) { r.orgWriter.CollapseLineBreaks = true }
+}
+
+// WithPreservedKeywordCase makes RenderOrg write custom keywords in their original case (see OrgWriter.PreserveKeywordCase).
+func WithPreservedKeywordCase() Option {
+	return func(r *renderer) { r.orgWriter.PreserveKeywordCase = true }
+}
+
+// WithAlignedProperties aligns property values like org-property-format in RenderOrg (see OrgWriter.AlignProperties).
+func WithAlignedProperties() Option {
+	return func(r *renderer) { r.orgWriter.AlignProperties = true }
+}
+
+// WithFillColumn wraps paragraphs written by RenderOrg at the given column (see OrgWriter.FillColumn).
+func WithFillColumn(n int) Option {
+	return func(r *renderer) { r.orgWriter.FillColumn = n }
+}
+
+// WithTableTabWidth sets the tab width used to expand tabs in table cells by RenderOrg - 0 keeps tabs (see OrgWriter.TableTabWidth).
+func WithTableTabWidth(n int) Option {
+	return func(r *renderer) { r.orgWriter.TableTabWidth = n }
+}
+
+// WithNormalizeListItemSpacing makes RenderOrg write a single space after list bullets (see OrgWriter.NormalizeListItemSpacing).
+func WithNormalizeListItemSpacing() Option {
+	return func(r *renderer) { r.orgWriter.NormalizeListItemSpacing = true }
+}
+
+// WithNumberFootnotes makes RenderOrg rename footnotes to their number (see OrgWriter.NumberFootnotes).
+func WithNumberFootnotes() Option {
+	return func(r *renderer) { r.orgWriter.NumberFootnotes = true }
+}
+
+// WithHighlightCodeBlock sets the function used by RenderHTML to highlight src blocks.
+func WithHighlightCodeBlock(f func(source, lang string, inline bool, params map[string]string) string) Option {
+	return func(r *renderer) { r.htmlWriter.HighlightCodeBlock = f }
+}
+
+// WithBaseURL resolves relative links against base (see HTMLWriter.BaseURL).
+func WithBaseURL(base string) Option {
+	return func(r *renderer) { r.htmlWriter.BaseURL = base }
+}
+
+// WithPrettyRelativeLinks rewrites relative links to foo.org as ../foo/ (see HTMLWriter.PrettyRelativeLinks).
+func WithPrettyRelativeLinks() Option {
+	return func(r *renderer) { r.htmlWriter.PrettyRelativeLinks = true }
+}
+
+// WithLineNumberAllCode numbers the lines of all src & example blocks (see HTMLWriter.LineNumberAllCode).
+func WithLineNumberAllCode() Option {
+	return func(r *renderer) { r.htmlWriter.LineNumberAllCode = true }
+}
+
+// WithTableFigureCaptions renders table captions as figure captions (see HTMLWriter.TableFigureCaptions).
+func WithTableFigureCaptions() Option {
+	return func(r *renderer) { r.htmlWriter.TableFigureCaptions = true }
+}
+
+// WithPrettifyText replaces ASCII arrows with their typographic counterparts (see HTMLWriter.PrettifyText).
+func WithPrettifyText() Option {
+	return func(r *renderer) { r.htmlWriter.PrettifyText = true }
+}
+
+// WithFootnotesInDefinitionOrder numbers footnotes in order of their definitions (see HTMLWriter.FootnotesInDefinitionOrder).
+func WithFootnotesInDefinitionOrder() Option {
+	return func(r *renderer) { r.htmlWriter.FootnotesInDefinitionOrder = true }
+}
+
+// WithFoldSrcBlockLines folds src blocks with more than n lines into a collapsible <details> (see HTMLWriter.FoldSrcBlockLines).
+func WithFoldSrcBlockLines(n int) Option {
+	return func(r *renderer) { r.htmlWriter.FoldSrcBlockLines = n }
+}
+
+// WithUpdateStatistics fills in the statistics cookies of headlines & list items before writing (see Document.UpdateStatistics).
+func WithUpdateStatistics() Option {
+	return func(r *renderer) { r.updateStatistics = true }
+}
+
+// WithStatisticProgress renders a <progress> after statistics cookies of checkbox lists (see HTMLWriter.StatisticProgress).
+func WithStatisticProgress() Option {
+	return func(r *renderer) { r.htmlWriter.StatisticProgress = true }
+}
+
+// WithCheckboxInputs renders checkbox list items with a disabled <input type="checkbox"> (see HTMLWriter.CheckboxInputs).
+func WithCheckboxInputs() Option {
+	return func(r *renderer) { r.htmlWriter.CheckboxInputs = true }
+}
+
+// WithQuoteAttribution renders trailing attribution lines of quote blocks in the given tag (see HTMLWriter.QuoteAttribution).
+func WithQuoteAttribution(tag string) Option {
+	return func(r *renderer) { r.htmlWriter.QuoteAttribution = tag }
+}
+
+// WithNumberCaptions prefixes captions with Figure N: / Table N: (see HTMLWriter.NumberCaptions).
+func WithNumberCaptions() Option {
+	return func(r *renderer) { r.htmlWriter.NumberCaptions = true }
+}
+
+// WithDiagramLanguages renders src blocks of the given languages as <pre class="lang"> for client side rendering (see HTMLWriter.DiagramLanguages).
+func WithDiagramLanguages(languages ...string) Option {
+	return func(r *renderer) { r.htmlWriter.DiagramLanguages = languages }
+}
+
+// WithSlugHeadlineIDs uses unique slugs of headline titles as their ids (see HTMLWriter.SlugHeadlineIDs).
+func WithSlugHeadlineIDs() Option {
+	return func(r *renderer) { r.htmlWriter.SlugHeadlineIDs = true }
+}
+
+// WithHeadlineIDPrecedence sets the sources tried in order for headline ids: ID, CUSTOM_ID and slug (see HTMLWriter.HeadlineIDPrecedence).
+func WithHeadlineIDPrecedence(sources ...string) Option {
+	return func(r *renderer) { r.htmlWriter.HeadlineIDPrecedence = sources }
+}
+
+// WithSlugStyle sets the slug algorithm used by WithSlugHeadlineIDs: default or github (see HTMLWriter.SlugStyle).
+func WithSlugStyle(style string) Option {
+	return func(r *renderer) { r.htmlWriter.SlugStyle = style }
+}
+
+// WithTOCMaxLvl limits the table of contents to headlines up to level n (see HTMLWriter.TOCMaxLvl).
+func WithTOCMaxLvl(n int) Option {
+	return func(r *renderer) { r.htmlWriter.TOCMaxLvl = n }
+}
+
+// WithTOCMinLvl leaves out headlines below level n from the table of contents (see HTMLWriter.TOCMinLvl).
+func WithTOCMinLvl(n int) Option {
+	return func(r *renderer) { r.htmlWriter.TOCMinLvl = n }
+}
+
+// WithVerseLineBreaks renders verse blocks with a <br> per line like Org mode (see HTMLWriter.VerseLineBreaks).
+func WithVerseLineBreaks() Option {
+	return func(r *renderer) { r.htmlWriter.VerseLineBreaks = true }
+}
+
+// WithXHTML self-closes void elements and renders boolean attributes as attr="attr" (see HTMLWriter.XHTML).
+func WithXHTML() Option {
+	return func(r *renderer) { r.htmlWriter.XHTML = true }
+}
+
+// WithTagLinkFormat renders tags as links to the given url format, e.g. /tags/%s (see HTMLWriter.TagLinkFormat).
+func WithTagLinkFormat(format string) Option {
+	return func(r *renderer) { r.htmlWriter.TagLinkFormat = format }
+}
+
+// WithHeadlineOffset shifts the <hN> of headlines down by n levels (see HTMLWriter.HeadlineOffset).
+func WithHeadlineOffset(n int) Option {
+	return func(r *renderer) { r.htmlWriter.HeadlineOffset = n }
+}
+
+// WithInlineImages inlines local images read from fsys up to maxSize bytes as data: uris (see HTMLWriter.InlineImages).
+func WithInlineImages(fsys fs.FS, maxSize int64) Option {
+	return func(r *renderer) { r.htmlWriter.InlineImages, r.htmlWriter.InlineImageMaxSize = fsys, maxSize }
+}
+
+// WithHorizontalRuleClass renders horizontal rules with the given class and their number of dashes (see HTMLWriter.HorizontalRuleClass).
+func WithHorizontalRuleClass(class string) Option {
+	return func(r *renderer) { r.htmlWriter.HorizontalRuleClass = class }
+}
+
+// WithBlockRenderer renders blocks with the given name (e.g. ALERT for #+BEGIN_ALERT) using f (see HTMLWriter.BlockRenderers).
+func WithBlockRenderer(name string, f func(Block) string) Option {
+	return func(r *renderer) {
+		if r.htmlWriter.BlockRenderers == nil {
+			r.htmlWriter.BlockRenderers = map[string]func(Block) string{}
+		}
+		r.htmlWriter.BlockRenderers[strings.ToUpper(name)] = f
+	}
+}
+
+// WithTimestampFormat renders #+DATE: below the title, formatting timestamps with the given Go time layout (see HTMLWriter.TimestampFormat).
+func WithTimestampFormat(layout string) Option {
+	return func(r *renderer) { r.htmlWriter.TimestampFormat = layout }
+}
+
+// WithTimestampClasses adds a timestamp-active / timestamp-inactive class to timestamps (see HTMLWriter.TimestampClasses).
+func WithTimestampClasses() Option {
+	return func(r *renderer) { r.htmlWriter.TimestampClasses = true }
+}
+
+// WithHideInactiveTimestamps drops [inactive] timestamps from the html output (see HTMLWriter.HideInactiveTimestamps).
+func WithHideInactiveTimestamps() Option {
+	return func(r *renderer) { r.htmlWriter.HideInactiveTimestamps = true }
+}
+
+// WithTabReplacement replaces tabs in prose with the given html (see HTMLWriter.TabReplacement).
+func WithTabReplacement(replacement string) Option {
+	return func(r *renderer) { r.htmlWriter.TabReplacement = replacement }
+}
+
+// WithAttachmentResolver sets the function used to map attachment links to urls (see HTMLWriter.AttachmentResolver).
+func WithAttachmentResolver(f func(headlineID, path string) string) Option {
+	return func(r *renderer) { r.htmlWriter.AttachmentResolver = f }
+}
+
+// WithRenderCache memoizes the html of headline subtrees (see HTMLWriter.RenderCache).
+func WithRenderCache(c RenderCache) Option {
+	return func(r *renderer) { r.htmlWriter.RenderCache = c }
+}
+
+// WithTableSpanMarkers merges table cells consisting only of a marker into the cell to their left (colSpanMarker) or above (rowSpanMarker).
+func WithTableSpanMarkers(colSpanMarker, rowSpanMarker string) Option {
+	return func(r *renderer) {
+		r.htmlWriter.TableColSpanMarker, r.htmlWriter.TableRowSpanMarker = colSpanMarker, rowSpanMarker
+	}
+}
+
+// WithAriaLabels labels the table of contents and the footnotes with translated aria-labels (see HTMLWriter.AriaLabels).
+func WithAriaLabels() Option {
+	return func(r *renderer) { r.htmlWriter.AriaLabels = true }
+}
+
+// WithTranslations adds translations of fixed export strings for language (see Configuration.Translations).
+func WithTranslations(language string, translations map[string]string) Option {
+	return func(r *renderer) {
+		if r.configuration.Translations == nil {
+			r.configuration.Translations = map[string]map[string]string{}
+		}
+		r.configuration.Translations[language] = translations
+	}
+}
@@ -0,0 +1,143 @@
+package org
+
+import "sort"
+
+// SortHeadlines sorts sibling headlines (at every level) with less. The content before the first headline
+// of a sibling group keeps its place. The Outline is updated accordingly.
+// To allow method chaining, the document is returned.
+func (d *Document) SortHeadlines(less func(a, b Headline) bool) *Document {
+	var sortNodes func([]Node) []Node
+	sortNodes = func(nodes []Node) []Node {
+		sorted, positions, headlines := append([]Node{}, nodes...), []int{}, []Headline{}
+		for i, n := range nodes {
+			if h, ok := n.(Headline); ok {
+				h.Children = sortNodes(h.Children)
+				positions, headlines = append(positions, i), append(headlines, h)
+			}
+		}
+		sort.SliceStable(headlines, func(i, j int) bool { return less(headlines[i], headlines[j]) })
+		for i, p := range positions {
+			sorted[p] = headlines[i]
+		}
+		return sorted
+	}
+	d.Nodes = sortNodes(d.Nodes)
+	d.rebuildOutline()
+	return d
+}
+
+// PromoteSubtree decreases the level of h (identified by its Index) and all its descendants by one.
+// Like in Org mode, following siblings of h become its children. Level 1 headlines cannot be promoted - false is returned.
+func (d *Document) PromoteSubtree(h Headline) bool { return d.shiftSubtree(h, -1) }
+
+// DemoteSubtree increases the level of h (identified by its Index) and all its descendants by one.
+// Like in Org mode, h becomes a child of its preceding sibling (if any). False is returned if h is not part of the document.
+func (d *Document) DemoteSubtree(h Headline) bool { return d.shiftSubtree(h, 1) }
+
+// shiftSubtree changes the level of h and its descendants by delta and nests the headlines again the way the parser
+// does - i.e. the OrgWriter output of the document parses into the same structure.
+func (d *Document) shiftSubtree(h Headline, delta int) bool {
+	nodes := flattenHeadlines(d.Nodes)
+	start := -1
+	for i, n := range nodes {
+		if c, ok := n.(Headline); ok && c.Index == h.Index {
+			start = i
+			break
+		}
+	}
+	if start == -1 || nodes[start].(Headline).Lvl+delta < 1 {
+		return false
+	}
+	lvl := nodes[start].(Headline).Lvl
+	for i := start; i < len(nodes); i++ {
+		c, ok := nodes[i].(Headline)
+		if !ok {
+			continue
+		} else if i != start && c.Lvl <= lvl {
+			break
+		}
+		c.Lvl += delta
+		nodes[i] = c
+	}
+	_, d.Nodes = nestHeadlines(nodes, 0, 0)
+	d.rebuildOutline()
+	return true
+}
+
+// flattenHeadlines returns nodes with all headlines lifted out of the children of their parent headlines, in document order.
+func flattenHeadlines(nodes []Node) []Node {
+	flattened := []Node{}
+	for _, n := range nodes {
+		h, ok := n.(Headline)
+		if !ok {
+			flattened = append(flattened, n)
+			continue
+		}
+		content, headlines := []Node{}, []Node{}
+		for _, c := range h.Children {
+			if _, ok := c.(Headline); ok || len(headlines) != 0 {
+				headlines = append(headlines, c)
+			} else {
+				content = append(content, c)
+			}
+		}
+		h.Children = content
+		flattened = append(append(flattened, h), flattenHeadlines(headlines)...)
+	}
+	return flattened
+}
+
+// nestHeadlines is the inverse of flattenHeadlines: headlines become children of the closest preceding headline of a lower level.
+func nestHeadlines(nodes []Node, i, lvl int) (int, []Node) {
+	nested := []Node{}
+	for i < len(nodes) {
+		h, ok := nodes[i].(Headline)
+		if !ok {
+			nested, i = append(nested, nodes[i]), i+1
+			continue
+		} else if h.Lvl <= lvl {
+			break
+		}
+		consumed, children := nestHeadlines(nodes, i+1, h.Lvl)
+		h.Children = append(append([]Node{}, h.Children...), children...)
+		nested, i = append(nested, h), consumed
+	}
+	return i, nested
+}
+
+// rebuildOutline updates the Outline to the headline structure of the Nodes. Sections are kept (e.g. for IndexEntry.Section).
+func (d *Document) rebuildOutline() {
+	sections := map[int]*Section{}
+	var collect func(*Section)
+	collect = func(s *Section) {
+		if s.Headline != nil {
+			sections[s.Headline.Index] = s
+		}
+		for _, c := range s.Children {
+			collect(c)
+		}
+	}
+	collect(d.Outline.Section)
+	var rebuild func(*Section, []Node)
+	rebuild = func(parent *Section, nodes []Node) {
+		for _, n := range nodes {
+			if i, ok := n.(Include); ok {
+				rebuild(parent, i.Children)
+			}
+			h, ok := n.(Headline)
+			if !ok {
+				continue
+			}
+			s := sections[h.Index]
+			if s == nil {
+				s = &Section{Headline: &Headline{}}
+			}
+			*s.Headline, s.Parent, s.Children = h, parent, nil
+			parent.Children = append(parent.Children, s)
+			rebuild(s, h.Children)
+		}
+	}
+	d.Outline.Section.Children = nil
+	rebuild(d.Outline.Section, d.Nodes)
+	d.indexOutline()
+}
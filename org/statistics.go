@@ -0,0 +1,155 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateStatistics fills in the statistics cookies ([/], [%], [1/3], ...) of headlines and list items.
+// Cookies of list items count the checkboxes of their child items. Cookies of headlines count the
+// TODO states of their child headlines - or the checkboxes of their lists if there are none. The
+// COOKIE_DATA property of a headline selects between todo & checkbox and recursive counts all descendants.
+// Containers without children to count get [0/0] and [0%].
+func (d *Document) UpdateStatistics() *Document {
+	d.Nodes = d.updateStatistics(d.Nodes)
+	d.updateOutline()
+	return d
+}
+
+func (d *Document) updateStatistics(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	updated := make([]Node, len(nodes))
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case Headline:
+			n.Children = d.updateStatistics(n.Children)
+			done, total := d.headlineStatistics(n)
+			n.Title = fillStatistics(n.Title, done, total)
+			updated[i] = n
+		case List:
+			updated[i] = List{n.Kind, d.updateStatistics(n.Items)}
+		case ListItem:
+			n.Children = d.updateStatistics(n.Children)
+			done, total := countCheckboxes(n.Children)
+			n.Children = fillStatistics(n.Children, done, total)
+			updated[i] = n
+		case DescriptiveListItem:
+			n.Details = d.updateStatistics(n.Details)
+			done, total := countCheckboxes(n.Details)
+			n.Term, n.Details = fillStatistics(n.Term, done, total), fillStatistics(n.Details, done, total)
+			updated[i] = n
+		case NodeWithMeta:
+			updated[i] = NodeWithMeta{d.updateStatistics([]Node{n.Node})[0], n.Meta}
+		case NodeWithName:
+			updated[i] = NodeWithName{n.Name, d.updateStatistics([]Node{n.Node})[0]}
+		case Include:
+			n.Children = d.updateStatistics(n.Children)
+			updated[i] = n
+		default:
+			updated[i] = n
+		}
+	}
+	return updated
+}
+
+func (d *Document) headlineStatistics(h Headline) (done, total int) {
+	cookieData, _ := h.Properties.Get("COOKIE_DATA")
+	recursive := strings.Contains(cookieData, "recursive")
+	if !strings.Contains(cookieData, "checkbox") {
+		keywords := d.TodoKeywords()
+		count := func(c Headline) {
+			if i, ok := findTodoKeyword(keywords, c.Status); ok {
+				total++
+				if keywords[i].Done {
+					done++
+				}
+			}
+		}
+		for _, c := range h.Children {
+			if c, ok := c.(Headline); ok {
+				count(c)
+				if recursive {
+					walkNodes(c.Children, func(n Node) {
+						if n, ok := n.(Headline); ok {
+							count(n)
+						}
+					})
+				}
+			}
+		}
+		if total != 0 || strings.Contains(cookieData, "todo") {
+			return done, total
+		}
+	}
+	if recursive {
+		walkNodes(h.Children, func(n Node) {
+			if status := listItemStatus(n); status != "" {
+				total++
+				if status == "X" {
+					done++
+				}
+			}
+		})
+		return done, total
+	}
+	return countCheckboxes(h.Children)
+}
+
+// fillStatistics replaces the statistics cookies in nodes (and their paragraphs & emphasis) - nested lists are left alone.
+func fillStatistics(nodes []Node, done, total int) []Node {
+	if nodes == nil {
+		return nil
+	}
+	filled := make([]Node, len(nodes))
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case StatisticToken:
+			if strings.HasSuffix(n.Content, "%") {
+				percent := 0
+				if total != 0 {
+					percent = done * 100 / total
+				}
+				filled[i] = StatisticToken{fmt.Sprintf("%d%%", percent)}
+			} else {
+				filled[i] = StatisticToken{fmt.Sprintf("%d/%d", done, total)}
+			}
+		case Paragraph:
+			filled[i] = Paragraph{fillStatistics(n.Children, done, total)}
+		case Emphasis:
+			filled[i] = Emphasis{n.Kind, fillStatistics(n.Content, done, total)}
+		default:
+			filled[i] = n
+		}
+	}
+	return filled
+}
+
+// countCheckboxes counts the checked & total checkboxes of the items of the lists in nodes - nested lists are not counted.
+func countCheckboxes(nodes []Node) (done, total int) {
+	for _, n := range nodes {
+		if l, ok := n.(List); ok {
+			for _, item := range l.Items {
+				status := listItemStatus(item)
+				if status != "" {
+					total++
+				}
+				if status == "X" {
+					done++
+				}
+			}
+		}
+	}
+	return done, total
+}
+
+func listItemStatus(n Node) string {
+	switch n := n.(type) {
+	case ListItem:
+		return n.Status
+	case DescriptiveListItem:
+		return n.Status
+	}
+	return ""
+}
@@ -1,6 +1,7 @@
 package org
 
 import (
+	"encoding/csv"
 	"regexp"
 	"strconv"
 	"strings"
@@ -29,6 +30,12 @@ type ColumnInfo struct {
 	DisplayLen int
 }
 
+// CellSpan is the number of columns & rows a table cell spans. Cells covered by another cell span 0 columns & rows.
+type CellSpan struct {
+	ColSpan int
+	RowSpan int
+}
+
 var tableSeparatorRegexp = regexp.MustCompile(`^(\s*)(\|[+-|]*)\s*$`)
 var tableRowRegexp = regexp.MustCompile(`^(\s*)(\|.*)`)
 
@@ -49,7 +56,7 @@ func (d *Document) parseTable(i int, parentStop stopFn) (int, Node) {
 		if t := d.tokens[i]; t.kind == "tableRow" {
 			rawRow := strings.FieldsFunc(d.tokens[i].content, func(r rune) bool { return r == '|' })
 			for i := range rawRow {
-				rawRow[i] = strings.TrimSpace(rawRow[i])
+				rawRow[i] = strings.Trim(rawRow[i], " \t") // keep non-breaking spaces
 			}
 			rawRows = append(rawRows, rawRow)
 		} else if t.kind == "tableSeparator" {
@@ -134,4 +141,115 @@ func isSpecialRow(rawColumns []string) bool {
 	return isAlignRow
 }
 
+// Spans merges table cells based on markers: A cell consisting only of colSpanMarker (e.g. <) is merged into the cell to its left,
+// a cell consisting only of rowSpanMarker (e.g. ^) into the cell above it. An empty marker disables the respective spanning.
+// Merging is conservative: only rectangular areas are created and cells are never merged across separators.
+func (t Table) Spans(colSpanMarker, rowSpanMarker string) [][]CellSpan {
+	type cell struct{ row, column int }
+	spans, owners := make([][]CellSpan, len(t.Rows)), make([][]cell, len(t.Rows))
+	for i, row := range t.Rows {
+		spans[i], owners[i] = make([]CellSpan, len(row.Columns)), make([]cell, len(row.Columns))
+		for j, column := range row.Columns {
+			spans[i][j], owners[i][j] = CellSpan{1, 1}, cell{i, j}
+			if row.IsSpecial {
+				continue
+			}
+			content := strings.TrimSpace(String(column.Children))
+			if colSpanMarker != "" && content == colSpanMarker && j > 0 && owners[i][j-1].row == i {
+				o := owners[i][j-1]
+				spans[o.row][o.column].ColSpan++
+				spans[i][j], owners[i][j] = CellSpan{0, 0}, o
+			} else if rowSpanMarker != "" && content == rowSpanMarker && i > 0 && !t.Rows[i-1].IsSpecial && j < len(t.Rows[i-1].Columns) {
+				if o := owners[i-1][j]; spans[o.row][o.column].ColSpan == 1 {
+					spans[o.row][o.column].RowSpan++
+					spans[i][j], owners[i][j] = CellSpan{0, 0}, o
+				}
+			}
+		}
+	}
+	return spans
+}
+
+// RowCount returns the number of rows of t - separators and special rows (e.g. alignment cookies) are not counted.
+// The header (if any) is row 0.
+func (t Table) RowCount() int { return len(t.rows()) }
+
+// ColCount returns the number of columns of t - i.e. the number of columns of its widest row.
+func (t Table) ColCount() int { return len(t.ColumnInfos) }
+
+// Cell returns the content of the cell at row & col (see RowCount). Out-of-range cells (e.g. of ragged rows) are empty.
+func (t Table) Cell(row, col int) []Node {
+	rows := t.rows()
+	if row < 0 || row >= len(rows) || col < 0 || col >= len(rows[row].Columns) {
+		return nil
+	}
+	return rows[row].Columns[col].Children
+}
+
+// Records returns the trimmed plain text content of the cells of t by row (see RowCount) - emphasis markers are dropped
+// and links are replaced by their description.
+func (t Table) Records() [][]string {
+	rows := t.rows()
+	records := make([][]string, len(rows))
+	for i, row := range rows {
+		records[i] = make([]string, len(row.Columns))
+		for j, column := range row.Columns {
+			records[i][j] = strings.TrimSpace(plainText(column.Children))
+		}
+	}
+	return records
+}
+
+// CSV returns the Records of t as comma separated values - cells containing commas, quotes or newlines are quoted as per RFC 4180.
+func (t Table) CSV() string {
+	out := strings.Builder{}
+	w := csv.NewWriter(&out)
+	w.WriteAll(t.Records()) // writing to a strings.Builder does not fail
+	return out.String()
+}
+
+// Tables returns all tables of d in document order.
+func (d *Document) Tables() []Table {
+	tables := []Table{}
+	walkNodes(d.Nodes, func(n Node) {
+		if t, ok := n.(Table); ok {
+			tables = append(tables, t)
+		}
+	})
+	return tables
+}
+
+// NamedTable returns the table named via #+NAME: name - or the table results of the src block of that name.
+func (d *Document) NamedTable(name string) (Table, bool) {
+	n := d.NamedNodes[name]
+	if m, ok := n.(NodeWithMeta); ok {
+		n = m.Node
+	}
+	if b, ok := n.(Block); ok {
+		return b.ResultTable()
+	}
+	t, ok := n.(Table)
+	return t, ok
+}
+
+// ResultTable returns the #+RESULTS: of b if they are a table.
+func (b Block) ResultTable() (Table, bool) {
+	r, ok := b.Result.(Result)
+	if !ok {
+		return Table{}, false
+	}
+	t, ok := r.Node.(Table)
+	return t, ok
+}
+
+func (t Table) rows() []Row {
+	rows := []Row{}
+	for _, row := range t.Rows {
+		if len(row.Columns) != 0 && !row.IsSpecial {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
 func (n Table) String() string { return orgWriter.WriteNodesAsString(n) }
@@ -0,0 +1,69 @@
+package org
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nowebReferenceRegexp = regexp.MustCompile(`^(.*?)<<([^<>()\s]+)>>(.*)$`)
+
+// Tangle extracts the content of all src blocks with a :tangle header argument.
+// The returned map contains the concatenated content of all blocks for each target file.
+// Noweb references (<<name>>) to named src blocks are expanded for blocks with :noweb yes|tangle|no-export|strip-export.
+func (d *Document) Tangle() map[string][]byte {
+	files := map[string][]byte{}
+	walkNodes(d.Nodes, func(n Node) {
+		b, ok := n.(Block)
+		if !ok || b.Name != "SRC" {
+			return
+		}
+		params := b.ParameterMap()
+		file := params[":tangle"]
+		switch file {
+		case "", "no":
+			return
+		case "yes":
+			file = strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path)) + "." + params[":lang"]
+		}
+		files[file] = append(files[file], d.tangleBlock(b, map[string]bool{})...)
+	})
+	return files
+}
+
+func (d *Document) tangleBlock(b Block, visited map[string]bool) string {
+	content := String(b.Children)
+	switch b.ParameterMap()[":noweb"] {
+	case "yes", "tangle", "no-export", "strip-export":
+	default:
+		return content
+	}
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines {
+		m := nowebReferenceRegexp.FindStringSubmatch(strings.TrimSuffix(line, "\n"))
+		if m == nil {
+			continue
+		}
+		prefix, name, suffix := m[1], m[2], m[3]
+		referenced, ok := d.namedBlock(name)
+		if !ok || visited[name] {
+			d.Log.Printf("Bad noweb reference <<%s>>", name)
+			continue
+		}
+		visited[name] = true
+		expanded := strings.TrimSuffix(d.tangleBlock(referenced, visited), "\n")
+		visited[name] = false
+		expanded = prefix + strings.ReplaceAll(expanded, "\n", suffix+"\n"+prefix) + suffix
+		lines[i] = expanded + line[len(strings.TrimSuffix(line, "\n")):]
+	}
+	return strings.Join(lines, "")
+}
+
+func (d *Document) namedBlock(name string) (Block, bool) {
+	n := d.NamedNodes[name]
+	if m, ok := n.(NodeWithMeta); ok {
+		n = m.Node
+	}
+	b, ok := n.(Block)
+	return b, ok && b.Name == "SRC"
+}
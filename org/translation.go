@@ -0,0 +1,40 @@
+package org
+
+import "strings"
+
+// DefaultTranslations contains translations of the fixed strings emitted by writers (e.g. "Footnotes") by #+LANGUAGE.
+// Strings without a translation fall back to English. See Configuration.Translations to add (or override) translations.
+var DefaultTranslations = map[string]map[string]string{
+	"de": {
+		"Figure":            "Abbildung",
+		"Footnotes":         "Fußnoten",
+		"Index":             "Stichwortverzeichnis",
+		"Table":             "Tabelle",
+		"Table of Contents": "Inhaltsverzeichnis",
+	},
+	"fr": {
+		"Figure":            "Figure",
+		"Footnotes":         "Notes de bas de page",
+		"Index":             "Index",
+		"Table":             "Tableau",
+		"Table of Contents": "Table des matières",
+	},
+}
+
+// Translate returns the translation of the fixed string s for the #+LANGUAGE of the document.
+// Regional variants (e.g. fr-CA) fall back to their base language, unknown languages and strings to s itself.
+func (d *Document) Translate(s string) string {
+	language := strings.ToLower(d.Get("LANGUAGE"))
+	languages := []string{language}
+	if i := strings.IndexAny(language, "-_"); i != -1 {
+		languages = append(languages, language[:i])
+	}
+	for _, l := range languages {
+		if t, ok := d.Translations[l][s]; ok {
+			return t
+		} else if t, ok := DefaultTranslations[l][s]; ok {
+			return t
+		}
+	}
+	return s
+}
@@ -1,10 +1,23 @@
 package org
 
 import (
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+const zeroWidthSpace = "\u200b"
+
+var orgEscapeReplacer = strings.NewReplacer(
+	"*", zeroWidthSpace+"*", "/", zeroWidthSpace+"/", "+", zeroWidthSpace+"+",
+	"=", zeroWidthSpace+"=", "~", zeroWidthSpace+"~",
+	"_", zeroWidthSpace+"_"+zeroWidthSpace, "^", "^"+zeroWidthSpace,
+	"[", "["+zeroWidthSpace, "{", "{"+zeroWidthSpace, "<", "<"+zeroWidthSpace,
+	"\\", "\\"+zeroWidthSpace, "$", "$"+zeroWidthSpace, "@", "@"+zeroWidthSpace, ":", ":"+zeroWidthSpace,
+)
+
+var orgEscapeLineStartRegexp = regexp.MustCompile(`(?m)^(\s*)([#|:+-]|[0-9a-zA-Z]+[.)])`)
+
 func isSecondBlankLine(d *Document, i int) bool {
 	if i-1 <= 0 {
 		return false
@@ -68,3 +81,10 @@ func ParseRanges(s string) [][2]int {
 	}
 	return ranges
 }
+
+// EscapeOrg escapes s so that it is parsed as literal text rather than Org mode markup when used inside a paragraph.
+// Following the Org mode convention, markup is broken up by inserting zero width spaces (U+200B).
+func EscapeOrg(s string) string {
+	s = orgEscapeReplacer.Replace(s)
+	return orgEscapeLineStartRegexp.ReplaceAllString(s, "$1"+zeroWidthSpace+"$2")
+}
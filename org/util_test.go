@@ -2,6 +2,7 @@ package org
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +37,39 @@ func TestParseRanges(t *testing.T) {
 	}
 
 }
+
+var escapeOrgTests = []string{
+	"*bold* /italic/ _underline_ +strike+ =verbatim= ~code~",
+	"[[https://example.com][link]] and https://example.com",
+	"[fn:1] a_{sub} a^{sup} $x$ \\(y\\) {{{macro(a)}}} <2020-01-01 Wed> @@html:<b>@@",
+	"* not a headline\n#+TITLE: not a keyword\n- not a list\n1. not a list\n| not | a table |\n: not an example\n-----",
+}
+
+func TestEscapeOrg(t *testing.T) {
+	for _, input := range escapeOrgTests {
+		t.Run(input, func(t *testing.T) {
+			d := New().Silent().Parse(strings.NewReader(EscapeOrg(input)), "./escapeOrgTests.org")
+			if len(d.Nodes) != 1 {
+				t.Fatalf("expected a single paragraph, got %v", d.Nodes)
+			}
+			p, ok := d.Nodes[0].(Paragraph)
+			if !ok {
+				t.Fatalf("expected a single paragraph, got %#v", d.Nodes[0])
+			}
+			actual := ""
+			for _, n := range p.Children {
+				switch n := n.(type) {
+				case Text:
+					actual += n.Content
+				case LineBreak:
+					actual += strings.Repeat("\n", n.Count)
+				default:
+					t.Fatalf("expected only text, got %#v", n)
+				}
+			}
+			if actual := strings.ReplaceAll(actual, "\u200b", ""); actual != input {
+				t.Errorf("%s:\n%s", input, diff(actual, input))
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package org
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Issue is a problem found by Document.Validate.
+type Issue struct {
+	Kind     string    // Kind is one of broken-link, missing-footnote-definition or unreferenced-footnote-definition.
+	Message  string    // Message describes the problem, e.g. "broken link: [[#foo]]".
+	Node     Node      // Node is the offending link, footnote reference or footnote definition.
+	Headline *Headline // Headline is the innermost headline containing Node - nil for nodes before the first headline.
+	Line     int       // Line is the (1-based) line of Node in the document - 0 if it is unknown, e.g. for nodes of included files.
+}
+
+// Validate reports broken internal links ([[#custom-id]], [[*Headline]], [[name]] & [[id:...]]),
+// footnote references without a definition and footnote definitions without a reference.
+// Issues are returned in document order - the document is not modified.
+func (d *Document) Validate() []Issue {
+	v := &validator{document: d, links: newLinkResolver(d), references: map[string]bool{}}
+	walkNodes(d.Nodes, func(n Node) {
+		if l, ok := n.(FootnoteLink); ok {
+			v.references[l.Name] = true
+		}
+	})
+	v.validate(d.Nodes, nil)
+	return v.issues
+}
+
+type validator struct {
+	document   *Document
+	links      *linkResolver
+	references map[string]bool
+	issues     []Issue
+	line       int // line is the index of the line of the last issue - see addIssue.
+}
+
+func (v *validator) validate(nodes []Node, h *Headline) {
+	for _, n := range nodes {
+		if child, ok := n.(Headline); ok {
+			v.validate(child.Title, &child)
+			v.validate(child.Children, &child)
+			continue
+		}
+		walkNodes([]Node{n}, func(n Node) {
+			switch n := n.(type) {
+			case RegularLink:
+				if t, internal := v.links.resolve(n); internal && t.isEmpty() {
+					v.addIssue("broken-link", fmt.Sprintf("broken link: %s", n), "[["+n.URL+"]", n, h)
+				}
+			case FootnoteLink:
+				if n.Name != "" && n.Definition == nil && v.document.Footnotes.Definitions[n.Name] == nil {
+					v.addIssue("missing-footnote-definition", fmt.Sprintf("missing footnote definition: %s", n), "[fn:"+n.Name, n, h)
+				}
+			case FootnoteDefinition:
+				if !v.references[n.Name] {
+					v.addIssue("unreferenced-footnote-definition", fmt.Sprintf("unreferenced footnote definition: [fn:%s]", n.Name), "[fn:"+n.Name+"]", n, h)
+				}
+			}
+		})
+	}
+}
+
+// addIssue adds an issue for n. Nodes do not know their position - the line of n is the first line containing
+// source (e.g. [[#foo]), searched from the line of the last issue as issues are added in document order.
+func (v *validator) addIssue(kind, message, source string, n Node, h *Headline) {
+	line, lines := 0, v.document.lines
+	for i := v.line; i < len(lines) && line == 0; i++ {
+		if strings.Contains(lines[i], source) {
+			line, v.line = i+1, i
+		}
+	}
+	v.issues = append(v.issues, Issue{kind, message, n, h, line})
+}
@@ -0,0 +1,81 @@
+package org
+
+// Walk calls visit for n and its descendants in a pre-order traversal of the tree - including the definitions of inline footnotes.
+// The children of a node are only visited if visit returns true for it.
+func Walk(n Node, visit func(Node) bool) {
+	if n == nil || !visit(n) {
+		return
+	}
+	walk := func(nodes []Node) {
+		for _, n := range nodes {
+			Walk(n, visit)
+		}
+	}
+	switch n := n.(type) {
+	case Headline:
+		walk(n.Title)
+		walk(n.Children)
+	case Block:
+		walk(n.Children)
+		Walk(n.Result, visit)
+	case Result:
+		Walk(n.Node, visit)
+	case Drawer:
+		walk(n.Children)
+	case List:
+		walk(n.Items)
+	case ListItem:
+		walk(n.Children)
+	case DescriptiveListItem:
+		walk(n.Term)
+		walk(n.Details)
+	case Table:
+		for _, row := range n.Rows {
+			for _, column := range row.Columns {
+				walk(column.Children)
+			}
+		}
+	case Paragraph:
+		walk(n.Children)
+	case Emphasis:
+		walk(n.Content)
+	case NodeWithMeta:
+		Walk(n.Node, visit)
+	case NodeWithName:
+		Walk(n.Node, visit)
+	case Include:
+		walk(n.Children)
+	case FootnoteDefinition:
+		walk(n.Children)
+	case RegularLink:
+		walk(n.Description)
+	case FootnoteLink:
+		if n.Definition != nil && n.Definition.Inline {
+			Walk(*n.Definition, visit)
+		}
+	}
+}
+
+// Walk calls Walk for each top-level node of d, e.g. to collect the URLs of all links:
+//
+//	d.Walk(func(n Node) bool {
+//		if l, ok := n.(RegularLink); ok {
+//			urls = append(urls, l.URL)
+//		}
+//		return true
+//	})
+func (d *Document) Walk(visit func(Node) bool) {
+	for _, n := range d.Nodes {
+		Walk(n, visit)
+	}
+}
+
+// walkNodes calls f for each node in a pre-order traversal of the tree.
+func walkNodes(nodes []Node, f func(Node)) {
+	for _, n := range nodes {
+		Walk(n, func(n Node) bool {
+			f(n)
+			return true
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package org
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultWordsPerMinute is the reading speed used by ReadingTime for non-positive wpm.
+const DefaultWordsPerMinute = 200
+
+// WordCount returns the number of words of the exported prose of d: headline titles, paragraphs, lists, tables,
+// footnotes etc. Excluded headlines (see Headline.IsExcluded), SRC, EXAMPLE & EXPORT blocks, results, drawers
+// and keywords are not counted. Words are separated by whitespace - tokens without letters or digits (e.g. -) are ignored.
+func (d *Document) WordCount() int {
+	text := strings.Builder{}
+	var visit func(Node) bool
+	visit = func(n Node) bool {
+		switch n := n.(type) {
+		case Text:
+			text.WriteString(n.Content)
+			return true
+		case Emphasis:
+			return true
+		case RegularLink:
+			text.WriteString(" ")
+			return true
+		case Headline:
+			text.WriteString(" ")
+			return !n.IsExcluded(d)
+		case Table:
+			for _, row := range n.Rows {
+				for _, column := range row.Columns {
+					text.WriteString(" ")
+					for _, c := range column.Children {
+						Walk(c, visit)
+					}
+				}
+			}
+			return false
+		case Block:
+			text.WriteString(" ")
+			return n.Name != "SRC" && n.Name != "EXAMPLE" && n.Name != "EXPORT"
+		case Result, Drawer, PropertyDrawer, Keyword, Comment, Example:
+			return false
+		}
+		text.WriteString(" ")
+		return true
+	}
+	d.Walk(visit)
+	count := 0
+	for _, word := range strings.Fields(text.String()) {
+		if strings.IndexFunc(word, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) != -1 {
+			count++
+		}
+	}
+	return count
+}
+
+// ReadingTime returns the time it takes to read the WordCount of d at wpm words per minute (DefaultWordsPerMinute if wpm <= 0).
+func (d *Document) ReadingTime(wpm int) time.Duration {
+	if wpm <= 0 {
+		wpm = DefaultWordsPerMinute
+	}
+	return time.Duration(d.WordCount()) * time.Minute / time.Duration(wpm)
+}
@@ -1,6 +1,9 @@
 package org
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Writer is the interface that is used to export a parsed document into a new format. See Document.Write().
 type Writer interface {
@@ -9,6 +12,8 @@ type Writer interface {
 	String() string   // String is called at the very end to retrieve the final output.
 
 	WriterWithExtensions() Writer
+	// WriteNodesAsString writes nodes into a fresh output buffer and returns the result. All other state of the writer
+	// (e.g. indentation, footnotes, ids) is shared - the original buffer is restored afterwards, even if writing panics.
 	WriteNodesAsString(...Node) string
 
 	WriteKeyword(Keyword)
@@ -20,7 +25,9 @@ type Writer interface {
 	WriteBlock(Block)
 	WriteResult(Result)
 	WriteInlineBlock(InlineBlock)
+	WriteInlineBabelCall(InlineBabelCall)
 	WriteExample(Example)
+	WriteLatexBlock(LatexBlock)
 	WriteDrawer(Drawer)
 	WritePropertyDrawer(PropertyDrawer)
 	WriteList(List)
@@ -38,10 +45,18 @@ type Writer interface {
 	WriteRegularLink(RegularLink)
 	WriteMacro(Macro)
 	WriteTimestamp(Timestamp)
+	WriteRadioTarget(RadioTarget)
+	WriteTarget(Target)
+	WriteEntity(Entity)
 	WriteFootnoteLink(FootnoteLink)
 	WriteFootnoteDefinition(FootnoteDefinition)
 }
 
+// CustomNodeWriter is implemented by writers that can render nodes unknown to WriteNodes - e.g. nodes returned by custom InlineParsers.
+type CustomNodeWriter interface {
+	WriteCustomNode(Node) bool // WriteCustomNode returns false if the node could not be written.
+}
+
 func WriteNodes(w Writer, nodes ...Node) {
 	w = w.WriterWithExtensions()
 	for _, n := range nodes {
@@ -64,8 +79,12 @@ func WriteNodes(w Writer, nodes ...Node) {
 			w.WriteResult(n)
 		case InlineBlock:
 			w.WriteInlineBlock(n)
+		case InlineBabelCall:
+			w.WriteInlineBabelCall(n)
 		case Example:
 			w.WriteExample(n)
+		case LatexBlock:
+			w.WriteLatexBlock(n)
 		case Drawer:
 			w.WriteDrawer(n)
 		case PropertyDrawer:
@@ -100,14 +119,56 @@ func WriteNodes(w Writer, nodes ...Node) {
 			w.WriteMacro(n)
 		case Timestamp:
 			w.WriteTimestamp(n)
+		case RadioTarget:
+			w.WriteRadioTarget(n)
+		case Target:
+			w.WriteTarget(n)
+		case Entity:
+			w.WriteEntity(n)
 		case FootnoteLink:
 			w.WriteFootnoteLink(n)
 		case FootnoteDefinition:
 			w.WriteFootnoteDefinition(n)
 		default:
-			if n != nil {
+			if cw, ok := w.(CustomNodeWriter); n != nil && (!ok || !cw.WriteCustomNode(n)) {
 				panic(fmt.Sprintf("bad node %T %#v", n, n))
 			}
 		}
 	}
 }
+
+// lineWriter is the output of writers that write blocks of indented lines (see MarkdownWriter & PlainTextWriter).
+type lineWriter struct {
+	strings.Builder
+	indent string
+}
+
+// startBlock separates the block about to be written from the previous one with a blank line.
+func (w *lineWriter) startBlock() {
+	if out := w.String(); out != "" && !strings.HasSuffix(out, "\n\n") {
+		w.WriteString(strings.TrimRight(w.indent, " ") + "\n")
+	}
+}
+
+// writeLines writes each line of content prefixed with the current indent.
+func (w *lineWriter) writeLines(content string) {
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		w.WriteString(strings.TrimRight(w.indent+line, " ") + "\n")
+	}
+}
+
+// rawContent returns the content of nodes as is (i.e. without escaping) - e.g. the content of src blocks.
+func rawContent(nodes []Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case Text:
+			b.WriteString(n.Content)
+		case LineBreak:
+			b.WriteString(strings.Repeat("\n", n.Count))
+		default:
+			b.WriteString(n.String())
+		}
+	}
+	return b.String()
+}
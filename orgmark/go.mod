@@ -0,0 +1,13 @@
+module github.com/niklasfasching/go-org/orgmark
+
+go 1.18
+
+require (
+	github.com/niklasfasching/go-org v1.6.5
+	github.com/yuin/goldmark v1.4.13
+)
+
+require golang.org/x/net v0.0.0-20201224014010-6772e930b67b // indirect
+
+// orgmark is developed alongside go-org - its own module keeps goldmark out of the dependencies of go-org.
+replace github.com/niklasfasching/go-org => ../
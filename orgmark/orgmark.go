@@ -0,0 +1,316 @@
+// Package orgmark converts parsed org documents into goldmark (markdown) ASTs.
+// This allows rendering org & markdown content with the same goldmark pipeline (renderers, extensions, ...).
+package orgmark
+
+import (
+	"strings"
+
+	"github.com/niklasfasching/go-org/org"
+	gast "github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+type converter struct {
+	document *org.Document
+	source   []byte
+}
+
+var tableAlignments = map[string]east.Alignment{
+	"":       east.AlignNone,
+	"left":   east.AlignLeft,
+	"right":  east.AlignRight,
+	"center": east.AlignCenter,
+}
+
+// Convert converts the nodes of d into a goldmark document.
+// Goldmark nodes reference their content via segments of a source - the returned source
+// has to be passed along with the document, e.g. to goldmark's renderer.Render(w, source, document).
+// Strikethrough, tables, task lists and definition lists use the nodes of the goldmark extension package.
+func Convert(d *org.Document) (gast.Node, []byte) {
+	c := &converter{document: d}
+	document := gast.NewDocument()
+	c.convertBlocks(document, d.Nodes)
+	return document, c.source
+}
+
+func (c *converter) convertBlocks(parent gast.Node, nodes []org.Node) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case org.Headline:
+			if n.IsExcluded(c.document) {
+				continue
+			}
+			heading := gast.NewHeading(n.Lvl)
+			c.convertInlines(heading, n.Title)
+			parent.AppendChild(parent, heading)
+			c.convertBlocks(parent, n.Children)
+		case org.Paragraph:
+			if len(n.Children) == 0 {
+				continue // blank lines
+			}
+			paragraph := gast.NewParagraph()
+			c.convertInlines(paragraph, n.Children)
+			parent.AppendChild(parent, paragraph)
+		case org.List:
+			parent.AppendChild(parent, c.convertList(n))
+		case org.Block:
+			parent.AppendChild(parent, c.convertBlock(n))
+		case org.Example:
+			block, lines := gast.NewCodeBlock(), make([]string, len(n.Children))
+			for i, line := range n.Children {
+				lines[i] = textContent([]org.Node{line}) + "\n"
+			}
+			c.appendLines(block, strings.Join(lines, ""))
+			parent.AppendChild(parent, block)
+		case org.Table:
+			parent.AppendChild(parent, c.convertTable(n))
+		case org.HorizontalRule:
+			parent.AppendChild(parent, gast.NewThematicBreak())
+		case org.Drawer:
+			c.convertBlocks(parent, n.Children)
+		case org.NodeWithMeta:
+			c.convertBlocks(parent, []org.Node{n.Node})
+		case org.NodeWithName:
+			c.convertBlocks(parent, []org.Node{n.Node})
+		case org.Result:
+			c.convertBlocks(parent, []org.Node{n.Node})
+		case org.Include:
+			c.convertBlocks(parent, n.Nodes())
+		case org.Keyword, org.Comment, org.PropertyDrawer, org.FootnoteDefinition:
+		default:
+			paragraph := gast.NewParagraph()
+			c.convertInlines(paragraph, []org.Node{n})
+			parent.AppendChild(parent, paragraph)
+		}
+	}
+}
+
+func (c *converter) convertBlock(b org.Block) gast.Node {
+	switch b.Name {
+	case "SRC":
+		var info *gast.Text
+		if lang := b.ParameterMap()[":lang"]; lang != "" {
+			info = c.text(lang)
+		}
+		block := gast.NewFencedCodeBlock(info)
+		c.appendLines(block, textContent(b.Children))
+		return block
+	case "EXAMPLE":
+		block := gast.NewCodeBlock()
+		c.appendLines(block, textContent(b.Children))
+		return block
+	case "QUOTE":
+		quote := gast.NewBlockquote()
+		c.convertBlocks(quote, b.Children)
+		return quote
+	case "EXPORT":
+		block := gast.NewHTMLBlock(gast.HTMLBlockType7)
+		if len(b.Parameters) != 0 && strings.ToLower(b.Parameters[0]) == "html" {
+			c.appendLines(block, textContent(b.Children))
+		}
+		return block
+	default:
+		paragraph := gast.NewParagraph()
+		c.convertInlines(paragraph, b.Children)
+		return paragraph
+	}
+}
+
+func (c *converter) convertList(l org.List) gast.Node {
+	if l.Kind == "descriptive" {
+		list := east.NewDefinitionList(0, nil)
+		for _, item := range l.Items {
+			if item, ok := item.(org.DescriptiveListItem); ok {
+				term, description := east.NewDefinitionTerm(), east.NewDefinitionDescription()
+				description.IsTight = true
+				c.convertInlines(term, item.Term)
+				c.convertListItemChildren(description, item.Details)
+				list.AppendChild(list, term)
+				list.AppendChild(list, description)
+			}
+		}
+		return list
+	}
+	marker := byte('-')
+	if l.Kind == "ordered" {
+		marker = '.'
+	}
+	list := gast.NewList(marker)
+	list.IsTight = true
+	if l.Kind == "ordered" {
+		list.Start = 1
+	}
+	for _, item := range l.Items {
+		if item, ok := item.(org.ListItem); ok {
+			listItem := gast.NewListItem(len(item.Bullet) + 1)
+			c.convertListItemChildren(listItem, item.Children)
+			if textBlock := listItem.FirstChild(); item.Status != "" && textBlock != nil {
+				checkBox := east.NewTaskCheckBox(item.Status == "X")
+				if textBlock.FirstChild() != nil {
+					textBlock.InsertBefore(textBlock, textBlock.FirstChild(), checkBox)
+				} else {
+					textBlock.AppendChild(textBlock, checkBox)
+				}
+			}
+			list.AppendChild(list, listItem)
+		}
+	}
+	return list
+}
+
+// convertListItemChildren converts paragraphs into text blocks - i.e. paragraphs of tight lists.
+func (c *converter) convertListItemChildren(parent gast.Node, nodes []org.Node) {
+	for _, n := range nodes {
+		if p, ok := n.(org.Paragraph); ok && len(p.Children) == 0 {
+			continue
+		} else if ok {
+			textBlock := gast.NewTextBlock()
+			c.convertInlines(textBlock, p.Children)
+			parent.AppendChild(parent, textBlock)
+		} else {
+			c.convertBlocks(parent, []org.Node{n})
+		}
+	}
+}
+
+func (c *converter) convertTable(t org.Table) gast.Node {
+	table := east.NewTable()
+	for _, info := range t.ColumnInfos {
+		table.Alignments = append(table.Alignments, tableAlignments[info.Align])
+	}
+	hasHeader := len(t.SeparatorIndices) != 0 && t.SeparatorIndices[0] > 0
+	for i, row := range t.Rows {
+		if row.IsSpecial || len(row.Columns) == 0 {
+			continue
+		}
+		tableRow := east.NewTableRow(table.Alignments)
+		for _, column := range row.Columns {
+			cell := east.NewTableCell()
+			if column.ColumnInfo != nil {
+				cell.Alignment = tableAlignments[column.Align]
+			}
+			c.convertInlines(cell, column.Children)
+			tableRow.AppendChild(tableRow, cell)
+		}
+		if hasHeader && i < t.SeparatorIndices[0] {
+			table.AppendChild(table, east.NewTableHeader(tableRow))
+		} else {
+			table.AppendChild(table, tableRow)
+		}
+	}
+	return table
+}
+
+func (c *converter) convertInlines(parent gast.Node, nodes []org.Node) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case org.Text:
+			parent.AppendChild(parent, c.text(n.Content))
+		case org.LineBreak:
+			t := c.text("")
+			t.SetSoftLineBreak(true)
+			parent.AppendChild(parent, t)
+		case org.ExplicitLineBreak:
+			t := c.text("")
+			t.SetHardLineBreak(true)
+			parent.AppendChild(parent, t)
+		case org.Emphasis:
+			switch n.Kind {
+			case "*", "/":
+				emphasis := gast.NewEmphasis(map[string]int{"*": 2, "/": 1}[n.Kind])
+				c.convertInlines(emphasis, n.Content)
+				parent.AppendChild(parent, emphasis)
+			case "+":
+				strikethrough := east.NewStrikethrough()
+				c.convertInlines(strikethrough, n.Content)
+				parent.AppendChild(parent, strikethrough)
+			case "~", "=":
+				parent.AppendChild(parent, c.codeSpan(textContent(n.Content)))
+			default:
+				c.convertInlines(parent, n.Content)
+			}
+		case org.InlineBlock:
+			if n.Name == "src" {
+				parent.AppendChild(parent, c.codeSpan(textContent(n.Children)))
+			} else if n.Name == "export" && len(n.Parameters) != 0 && n.Parameters[0] == "html" {
+				raw := gast.NewRawHTML()
+				raw.Segments.Append(c.segment(textContent(n.Children)))
+				parent.AppendChild(parent, raw)
+			}
+		case org.RegularLink:
+			parent.AppendChild(parent, c.convertLink(n))
+		case org.RadioTarget:
+			parent.AppendChild(parent, c.text(n.Name))
+		case org.Target:
+		case org.Entity:
+			parent.AppendChild(parent, c.text(n.UTF8()))
+		case org.FootnoteLink:
+		default:
+			parent.AppendChild(parent, c.text(n.String()))
+		}
+	}
+}
+
+func (c *converter) convertLink(l org.RegularLink) gast.Node {
+	link := gast.NewLink()
+	link.Destination = []byte(l.URL)
+	if l.Protocol == "file" {
+		link.Destination = []byte(strings.TrimPrefix(l.URL, "file:"))
+	}
+	if l.Kind() == "image" && l.Description == nil {
+		image := gast.NewImage(link)
+		image.AppendChild(image, c.text(string(link.Destination)))
+		return image
+	}
+	if l.Description == nil {
+		link.AppendChild(link, c.text(string(link.Destination)))
+	} else {
+		c.convertInlines(link, l.Description)
+	}
+	return link
+}
+
+func (c *converter) codeSpan(content string) gast.Node {
+	codeSpan := gast.NewCodeSpan()
+	codeSpan.AppendChild(codeSpan, gast.NewRawTextSegment(c.segment(content)))
+	return codeSpan
+}
+
+func (c *converter) text(content string) *gast.Text {
+	return gast.NewTextSegment(c.segment(content))
+}
+
+func (c *converter) segment(content string) text.Segment {
+	start := len(c.source)
+	c.source = append(c.source, content...)
+	return text.NewSegment(start, len(c.source))
+}
+
+func (c *converter) appendLines(n gast.Node, content string) {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	lines := n.Lines()
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if line != "" {
+			lines.Append(c.segment(line))
+		}
+	}
+}
+
+// textContent returns the raw text of nodes - e.g. the content of src blocks.
+func textContent(nodes []org.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case org.Text:
+			b.WriteString(n.Content)
+		case org.LineBreak:
+			b.WriteString(strings.Repeat("\n", n.Count))
+		default:
+			b.WriteString(n.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package orgmark
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/niklasfasching/go-org/org"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+func TestConvert(t *testing.T) {
+	input := `* A *headline*
+a paragraph with /emphasis/, +strikethrough+, =code= and a [[https://example.com][link]]
+spanning two lines.
+
+** Lists
+- [X] done
+- [ ] todo
+
+1. one
+2. two
+
+- term :: description
+
+#+BEGIN_SRC go
+fmt.Println("hello")
+#+END_SRC
+
+: first line
+: second line
+
+| a | b |
+|---+---|
+| 1 | 2 |
+`
+	expected := `<h1>A <strong>headline</strong></h1>
+<p>a paragraph with <em>emphasis</em>, <del>strikethrough</del>, <code>code</code> and a <a href="https://example.com">link</a>
+spanning two lines.</p>
+<h2>Lists</h2>
+<ul>
+<li><input checked="" disabled="" type="checkbox"> done</li>
+<li><input disabled="" type="checkbox"> todo</li>
+</ul>
+<ol>
+<li>one</li>
+<li>two</li>
+</ol>
+<dl>
+<dt>term</dt>
+<dd>description</dd>
+</dl>
+<pre><code class="language-go">fmt.Println(&quot;hello&quot;)
+</code></pre>
+<pre><code>first line
+second line
+</code></pre>
+<table>
+<thead>
+<tr>
+<th style="text-align:right">a</th>
+<th style="text-align:right">b</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td style="text-align:right">1</td>
+<td style="text-align:right">2</td>
+</tr>
+</tbody>
+</table>
+`
+	d := org.New().Silent().Parse(strings.NewReader(input), "./test.org")
+	document, source := Convert(d)
+	out := &bytes.Buffer{}
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM, extension.DefinitionList))
+	if err := md.Renderer().Render(out, source, document); err != nil {
+		t.Fatal(err)
+	}
+	if actual := out.String(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}